@@ -0,0 +1,266 @@
+package cinema
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// AnimationOptions configures the export of animated images.
+type AnimationOptions struct {
+	// Quality goes from 1 (smallest file) to 100 (best quality). It defaults
+	// to 75. GIFs always use the best 256 colors for the whole animation and
+	// ignore it.
+	Quality int
+	// Loop is the number of times the animation is played, 0 means forever.
+	Loop int
+	// FPS is the framerate of the animation. It defaults to the framerate of
+	// the Video, see SetFPS. Animations rarely need more than 15 frames per
+	// second, and fewer frames give much smaller files.
+	FPS int
+	// Width scales the animation to the given width in pixels, keeping the
+	// aspect ratio. 0 keeps the size of the Video.
+	Width int
+	// Dither is how GIFs approximate colors that are not in their palette.
+	// It defaults to DitherSierra.
+	Dither Dither
+}
+
+// Dither is an algorithm that approximates colors missing from the palette of
+// a GIF by mixing neighboring pixels of the available colors.
+type Dither string
+
+const (
+	// DitherSierra spreads the color error to the neighboring pixels, which
+	// gives smooth gradients.
+	DitherSierra Dither = "sierra2_4a"
+	// DitherFloydSteinberg spreads the color error like DitherSierra but with
+	// more visible patterns.
+	DitherFloydSteinberg Dither = "floyd_steinberg"
+	// DitherBayer uses a fixed pattern, which compresses better than error
+	// diffusion and does not flicker between frames.
+	DitherBayer Dither = "bayer"
+	// DitherNone uses the nearest color of the palette, which gives the
+	// smallest files but visible banding in gradients.
+	DitherNone Dither = "none"
+)
+
+// RenderGIF renders the Video as an animated GIF without audio. GIFs are
+// limited to 256 colors, so a palette with the best colors for the animation
+// is computed in a first pass and used to encode the frames in a second pass,
+// which looks much better than ffmpeg's generic palette. An error is returned
+// if an option is out of range.
+func (v *Video) RenderGIF(output string, opts AnimationOptions) error {
+	switch opts.Dither {
+	case "":
+		opts.Dither = DitherSierra
+	case DitherSierra, DitherFloydSteinberg, DitherBayer, DitherNone:
+	default:
+		return errors.New("cinema.Video.RenderGIF: unknown dither " +
+			string(opts.Dither))
+	}
+	clip, err := v.animationClip("cinema.Video.RenderGIF", opts)
+	if err != nil {
+		return err
+	}
+	cleanup, err := clip.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderGIF: " + err.Error())
+	}
+	defer cleanup()
+	opts = opts.withDefaults()
+
+	dir, err := ioutil.TempDir("", "cinema-gif-")
+	if err != nil {
+		return errors.New("cinema.Video.RenderGIF: unable to create " +
+			"temporary directory: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+	palette := filepath.Join(dir, "palette.png")
+
+	inputArgs, graph := clip.filterGraph(clip.chain())
+	trim := clip.trimArgs(clip.outputTime(clip.start), clip.outputTime(clip.end))
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, clip.threadGlobalArgs()...)
+	line = append(line, clip.input()...)
+	line = append(line, inputArgs...)
+
+	// Only the colors of the frames that change are counted, so that a
+	// static background does not take up the whole palette.
+	pass1 := append([]string(nil), line...)
+	pass1 = append(pass1, trim...)
+	pass1 = append(pass1,
+		"-filter_complex", graph+";[vout]palettegen=stats_mode=diff[palette]",
+		"-map", "[palette]",
+		"-frames:v", "1",
+		palette,
+	)
+	if err := clip.runFFmpeg(pass1); err != nil {
+		return ffmpegFailed("cinema.Video.RenderGIF", err)
+	}
+
+	paletteInput := "[" + strconv.Itoa(1+countInputs(inputArgs)) + ":v]"
+	use := "paletteuse=dither=" + string(opts.Dither) + ":diff_mode=rectangle"
+	pass2 := append(line, "-i", palette)
+	pass2 = append(pass2, trim...)
+	pass2 = append(pass2,
+		"-filter_complex", graph+";[vout]"+paletteInput+use+"[gif]",
+		"-map", "[gif]",
+		"-an",
+		"-loop", strconv.Itoa(gifLoop(opts.Loop)),
+		"-f", "gif",
+		output,
+	)
+	if err := clip.runFFmpeg(pass2); err != nil {
+		return ffmpegFailed("cinema.Video.RenderGIF", err)
+	}
+	return nil
+}
+
+// RenderAnimatedWebP renders the Video as an animated WebP image without
+// audio. Animated WebP files are much smaller than GIFs and supported by all
+// modern browsers, which makes them a good fit for preview loops.
+func (v *Video) RenderAnimatedWebP(output string, opts AnimationOptions) error {
+	clip, err := v.animationClip("cinema.Video.RenderAnimatedWebP", opts)
+	if err != nil {
+		return err
+	}
+	cleanup, err := clip.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderAnimatedWebP: " + err.Error())
+	}
+	defer cleanup()
+	opts = opts.withDefaults()
+	err = clip.runFFmpeg(clip.commandLine(output,
+		"-an",
+		"-c:v", "libwebp",
+		"-quality", strconv.Itoa(opts.Quality),
+		"-loop", strconv.Itoa(opts.Loop),
+		"-f", "webp",
+	))
+	if err != nil {
+		return ffmpegFailed("cinema.Video.RenderAnimatedWebP", err)
+	}
+	return nil
+}
+
+// RenderAVIF renders the Video as an animated AVIF image without audio. AVIF
+// gives even smaller files than WebP but takes longer to encode.
+func (v *Video) RenderAVIF(output string, opts AnimationOptions) error {
+	clip, err := v.animationClip("cinema.Video.RenderAVIF", opts)
+	if err != nil {
+		return err
+	}
+	cleanup, err := clip.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderAVIF: " + err.Error())
+	}
+	defer cleanup()
+	opts = opts.withDefaults()
+	// libaom's CRF goes from 0 (best) to 63 (worst).
+	crf := 63 - (opts.Quality*63+50)/100
+	err = clip.runFFmpeg(clip.commandLine(output,
+		"-an",
+		"-c:v", "libaom-av1",
+		"-crf", strconv.Itoa(crf),
+		"-b:v", "0",
+		"-pix_fmt", "yuv420p",
+		"-loop", strconv.Itoa(opts.Loop),
+		"-f", "avif",
+	))
+	if err != nil {
+		return ffmpegFailed("cinema.Video.RenderAVIF", err)
+	}
+	return nil
+}
+
+// animationClip returns a copy of the Video with the framerate and size of
+// opts, for the render op.
+func (v *Video) animationClip(op string, opts AnimationOptions) (*Video, error) {
+	if opts.FPS < 0 || opts.Width < 0 {
+		return nil, errors.New(op + ": framerate and width must not be " +
+			"negative")
+	}
+	if err := v.checkTrim(op); err != nil {
+		return nil, err
+	}
+	clip := v.snapshot()
+	if opts.FPS > 0 {
+		clip.fps = opts.FPS
+		clip.fpsRate = ""
+	}
+	if opts.Width > 0 {
+		clip.SetSize(opts.Width, -1)
+	}
+	return &clip, nil
+}
+
+// gifLoop returns the -loop option of the gif muxer for an animation that is
+// played loop times, 0 meaning forever. The gif muxer counts the repeats after
+// the first play and uses -1 for playing once.
+func gifLoop(loop int) int {
+	if loop == 0 {
+		return 0
+	}
+	if loop == 1 {
+		return -1
+	}
+	return loop - 1
+}
+
+func (opts AnimationOptions) withDefaults() AnimationOptions {
+	if opts.Quality <= 0 {
+		opts.Quality = 75
+	}
+	if opts.Quality > 100 {
+		opts.Quality = 100
+	}
+	if opts.Loop < 0 {
+		opts.Loop = 0
+	}
+	return opts
+}
+
+// APNGOptions configures RenderAPNG.
+type APNGOptions struct {
+	// FPS is the framerate of the animation. It defaults to the framerate of
+	// the Video, see SetFPS.
+	FPS int
+	// Loop is the number of times the animation is played, 0 means forever.
+	Loop int
+}
+
+// RenderAPNG renders the Video as an animated PNG without audio. APNG is
+// lossless and keeps the alpha channel of the input, which makes it a good fit
+// for UI assets and stickers.
+func (v *Video) RenderAPNG(output string, opts APNGOptions) error {
+	video := v.snapshot()
+	if opts.FPS > 0 {
+		video.fps = opts.FPS
+		video.fpsRate = ""
+	}
+	if err := video.checkTrim("cinema.Video.RenderAPNG"); err != nil {
+		return err
+	}
+	if opts.Loop < 0 {
+		opts.Loop = 0
+	}
+	cleanup, err := video.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderAPNG: " + err.Error())
+	}
+	defer cleanup()
+	err = video.runFFmpeg(video.commandLine(output,
+		"-an",
+		"-c:v", "apng",
+		"-pix_fmt", "rgba",
+		"-plays", strconv.Itoa(opts.Loop),
+		"-f", "apng",
+	))
+	if err != nil {
+		return ffmpegFailed("cinema.Video.RenderAPNG", err)
+	}
+	return nil
+}
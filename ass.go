@@ -0,0 +1,337 @@
+package cinema
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ASSStyle is a named style of an ASSScript. Zero values select the defaults.
+type ASSStyle struct {
+	// Name identifies the style in events. It defaults to "Default".
+	Name string
+	// FontName is the name of the font family. It defaults to "Arial".
+	FontName string
+	// FontSize is the text height in pixels of the script resolution. It
+	// defaults to a twentieth of the script height.
+	FontSize int
+	// PrimaryColor is the color of the text, as "#RRGGBB" or "#RRGGBBAA"
+	// with an alpha of 00 for transparent. In karaoke events it is the color
+	// of the words that have been sung. It defaults to white.
+	PrimaryColor string
+	// SecondaryColor is the color of the words of karaoke events that have
+	// not been sung yet. It defaults to the primary color with half its
+	// opacity.
+	SecondaryColor string
+	// OutlineColor is the color of the outline. It defaults to black and is
+	// not used with Box.
+	OutlineColor string
+	// BackColor is the color of the shadow, and of the box with Box. It
+	// defaults to half transparent black.
+	BackColor string
+	Bold      bool
+	Italic    bool
+	// Outline is the width of the outline and Shadow the distance of the
+	// shadow in pixels. Outline defaults to a tenth of the font size, use a
+	// negative value for no outline.
+	Outline float64
+	Shadow  float64
+	// Box draws a box in BackColor behind the text instead of the outline,
+	// Outline is then the padding of the box.
+	Box bool
+	// Alignment is the position of the text, as on a numeric keypad: 1 is
+	// bottom left, 2 bottom center, 5 the center and 9 top right. It
+	// defaults to 2, the usual place of captions.
+	Alignment int
+	// MarginLeft, MarginRight and MarginVertical are the distances of the
+	// text to the edges of the frame in pixels. MarginVertical defaults to
+	// a twentieth of the script height.
+	MarginLeft, MarginRight, MarginVertical int
+}
+
+// ASSEvent is a caption of an ASSScript.
+type ASSEvent struct {
+	// Start and End are the times the caption is shown, relative to the
+	// start of the output.
+	Start, End time.Duration
+	// Style is the name of the style, empty for "Default".
+	Style string
+	// Text is the caption. Line breaks start a new line, braces are shown
+	// as they are. It is ignored if there are Words.
+	Text string
+	// Words make a karaoke caption that highlights each word while it is
+	// spoken, see ASSStyle.PrimaryColor.
+	Words []ASSWord
+	// Positioned places the anchor of the text, see ASSStyle.Alignment, at
+	// X and Y instead of at the margins.
+	Positioned bool
+	X, Y       int
+}
+
+// ASSWord is a word of a karaoke caption. Start and End are the times it is
+// spoken, relative to the start of the output.
+type ASSWord struct {
+	Text       string
+	Start, End time.Duration
+}
+
+// ASSScript builds styled subtitles in the Advanced SubStation Alpha format,
+// e.g. the word by word captions of social media clips. Burn them onto a
+// video with BurnASS or save them with WriteFile.
+type ASSScript struct {
+	width, height int
+	styles        []ASSStyle
+	events        []ASSEvent
+}
+
+// NewASSScript returns an empty script for a video of width x height pixels.
+// All sizes and positions of the script are in pixels of this resolution;
+// they are scaled if the video has a different size.
+func NewASSScript(width, height int) *ASSScript {
+	return &ASSScript{width: width, height: height}
+}
+
+// AddStyle adds a style to the script, or replaces the one with the same
+// name. An error is returned if the name contains a comma or if a color or
+// the alignment is invalid.
+func (s *ASSScript) AddStyle(style ASSStyle) error {
+	style.Name = orDefault(style.Name, "Default")
+	if style.Alignment == 0 {
+		style.Alignment = 2
+	}
+	if strings.Contains(style.Name, ",") {
+		return errors.New("cinema.ASSScript.AddStyle: style names must not " +
+			"contain commas")
+	}
+	if style.Alignment < 1 || style.Alignment > 9 {
+		return errors.New("cinema.ASSScript.AddStyle: alignment must be " +
+			"between 1 and 9")
+	}
+	for _, c := range []string{style.PrimaryColor, style.SecondaryColor,
+		style.OutlineColor, style.BackColor} {
+		if _, err := assColor(c, ""); err != nil {
+			return errors.New("cinema.ASSScript.AddStyle: " + err.Error())
+		}
+	}
+	for i := range s.styles {
+		if s.styles[i].Name == style.Name {
+			s.styles[i] = style
+			return nil
+		}
+	}
+	s.styles = append(s.styles, style)
+	return nil
+}
+
+// AddEvent adds a caption to the script. An error is returned if its times
+// are invalid or its style was not added. The style "Default" does not need
+// to be added.
+func (s *ASSScript) AddEvent(event ASSEvent) error {
+	if event.Start < 0 || event.End <= event.Start {
+		return errors.New("cinema.ASSScript.AddEvent: invalid time range " +
+			event.Start.String() + " to " + event.End.String())
+	}
+	for _, w := range event.Words {
+		if w.Start < event.Start || w.End < w.Start || w.End > event.End {
+			return errors.New("cinema.ASSScript.AddEvent: word " + w.Text +
+				" is not inside the event")
+		}
+	}
+	event.Style = orDefault(event.Style, "Default")
+	if event.Style != "Default" && s.style(event.Style) == nil {
+		return errors.New("cinema.ASSScript.AddEvent: unknown style " +
+			event.Style)
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+// style returns the style with the name, nil if there is none.
+func (s *ASSScript) style(name string) *ASSStyle {
+	for i := range s.styles {
+		if s.styles[i].Name == name {
+			return &s.styles[i]
+		}
+	}
+	return nil
+}
+
+// String returns the script in ASS format.
+func (s *ASSScript) String() string {
+	styles := s.styles
+	if s.style("Default") == nil {
+		styles = append([]ASSStyle{{Name: "Default", Alignment: 2}}, styles...)
+	}
+	var b strings.Builder
+	b.WriteString("[Script Info]\nScriptType: v4.00+\nWrapStyle: 0\n" +
+		"ScaledBorderAndShadow: yes\n" +
+		"PlayResX: " + strconv.Itoa(s.width) + "\n" +
+		"PlayResY: " + strconv.Itoa(s.height) + "\n\n")
+	b.WriteString("[V4+ Styles]\nFormat: Name, Fontname, Fontsize, " +
+		"PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, " +
+		"Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, " +
+		"BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, " +
+		"Encoding\n")
+	for _, style := range styles {
+		b.WriteString(s.styleLine(style) + "\n")
+	}
+	b.WriteString("\n[Events]\nFormat: Layer, Start, End, Style, Name, " +
+		"MarginL, MarginR, MarginV, Effect, Text\n")
+	for _, e := range s.events {
+		b.WriteString("Dialogue: 0," + assTime(e.Start) + "," + assTime(e.End) +
+			"," + e.Style + ",,0,0,0,," + eventText(e) + "\n")
+	}
+	return b.String()
+}
+
+// styleLine returns the Style line of the style, with the defaults filled in.
+func (s *ASSScript) styleLine(style ASSStyle) string {
+	size := style.FontSize
+	if size <= 0 {
+		size = s.height / 20
+	}
+	outline := style.Outline
+	if outline == 0 {
+		outline = float64(size) / 10
+	}
+	if outline < 0 {
+		outline = 0
+	}
+	marginV := style.MarginVertical
+	if marginV == 0 {
+		marginV = s.height / 20
+	}
+	borderStyle := "1"
+	if style.Box {
+		borderStyle = "3"
+	}
+	// The colors were checked by AddStyle.
+	primary, _ := assColor(style.PrimaryColor, "#FFFFFF")
+	secondary, _ := assColor(style.SecondaryColor, "")
+	if secondary == "" {
+		secondary = "&H80" + primary[4:]
+	}
+	outlineColor, _ := assColor(style.OutlineColor, "#000000")
+	back, _ := assColor(style.BackColor, "#00000080")
+	if style.Box {
+		// Renderers fill the box of border style 3 with the outline
+		// color.
+		outlineColor = back
+	}
+	return "Style: " + style.Name + "," +
+		orDefault(style.FontName, "Arial") + "," + strconv.Itoa(size) + "," +
+		primary + "," + secondary + "," + outlineColor + "," + back + "," +
+		assBool(style.Bold) + "," + assBool(style.Italic) + ",0,0,100,100,0,0," +
+		borderStyle + "," + formatFloat(outline) + "," +
+		formatFloat(style.Shadow) + "," + strconv.Itoa(style.Alignment) + "," +
+		strconv.Itoa(style.MarginLeft) + "," + strconv.Itoa(style.MarginRight) +
+		"," + strconv.Itoa(marginV) + ",1"
+}
+
+// eventText returns the text of the Dialogue line of the event.
+func eventText(e ASSEvent) string {
+	var text string
+	if e.Positioned {
+		text = `{\pos(` + strconv.Itoa(e.X) + "," + strconv.Itoa(e.Y) + ")}"
+	}
+	if len(e.Words) == 0 {
+		return text + escapeASS(e.Text)
+	}
+	// \k durations are in centiseconds and count from the end of the
+	// previous word, gaps get an empty syllable.
+	at := e.Start
+	for i, w := range e.Words {
+		if w.Start > at {
+			text += `{\k` + strconv.Itoa(centiseconds(w.Start-at)) + "}"
+		}
+		if i > 0 {
+			text += " "
+		}
+		text += `{\k` + strconv.Itoa(centiseconds(w.End-w.Start)) + "}" +
+			escapeASS(w.Text)
+		at = w.End
+	}
+	return text
+}
+
+// WriteFile writes the script to the file at path, which should have the
+// extension .ass.
+func (s *ASSScript) WriteFile(path string) error {
+	if err := ioutil.WriteFile(path, []byte(s.String()), 0644); err != nil {
+		return errors.New("cinema.ASSScript.WriteFile: " + err.Error())
+	}
+	return nil
+}
+
+// BurnASS draws the captions of the script onto the video, like
+// BurnSubtitles. The script is written to a temporary file while ffmpeg runs,
+// so later changes to it are not applied. An error is returned if the name of
+// the file cannot be reserved.
+func (v *Video) BurnASS(s *ASSScript) error {
+	script, err := newScriptFile("cinema-subtitles-*.ass", s.String())
+	if err != nil {
+		return errors.New("cinema.Video.BurnASS: unable to create " +
+			"subtitle file: " + err.Error())
+	}
+	v.record("BurnASS", s)
+	f := subtitlesFilter(script.path)
+	f.script = script
+	v.filters = append(v.filters, f)
+	return nil
+}
+
+// assColor converts a color in "#RRGGBB" or "#RRGGBBAA" format to the
+// "&HAABBGGRR" format of ASS, whose alpha is inverted. An empty color is
+// replaced by def; if def is empty too, the empty string is returned.
+func assColor(color, def string) (string, error) {
+	color = orDefault(color, def)
+	if color == "" {
+		return "", nil
+	}
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) == 6 {
+		hex += "FF"
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || !strings.HasPrefix(color, "#") || err != nil {
+		return "", errors.New("invalid color " + color)
+	}
+	r, g, b, a := n>>24, n>>16&0xff, n>>8&0xff, n&0xff
+	return fmt.Sprintf("&H%02X%02X%02X%02X", 255-a, b, g, r), nil
+}
+
+// assTime formats t as H:MM:SS.cc.
+func assTime(t time.Duration) string {
+	cs := centiseconds(t)
+	two := func(n int) string {
+		return string([]byte{byte('0' + n/10), byte('0' + n%10)})
+	}
+	return strconv.Itoa(cs/360000) + ":" + two(cs/6000%60) + ":" +
+		two(cs/100%60) + "." + two(cs%100)
+}
+
+// centiseconds returns t in hundredths of a second, rounded.
+func centiseconds(t time.Duration) int {
+	return int((t + 5*time.Millisecond) / (10 * time.Millisecond))
+}
+
+// assBool formats b as an ASS style flag.
+func assBool(b bool) string {
+	if b {
+		return "-1"
+	}
+	return "0"
+}
+
+// escapeASS escapes the braces and line breaks of caption text.
+func escapeASS(s string) string {
+	return strings.NewReplacer(
+		"{", `\{`,
+		"}", `\}`,
+		"\r\n", `\N`,
+		"\n", `\N`,
+	).Replace(s)
+}
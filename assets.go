@@ -0,0 +1,160 @@
+package cinema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// AssetSpec configures GenerateAssets. Zero values select the defaults.
+type AssetSpec struct {
+	// Name is the base name of all created files. It defaults to "video".
+	Name string
+	// PosterTime is the time of the poster frame, relative to the start of
+	// the output video.
+	PosterTime time.Duration
+	// ThumbnailInterval is the time between two seek thumbnails. It defaults
+	// to 10 seconds.
+	ThumbnailInterval time.Duration
+	// ThumbnailWidth is the width of the seek thumbnails in pixels. It
+	// defaults to 160.
+	ThumbnailWidth int
+	// PreviewStart is the start of the preview loop, relative to the start
+	// of the output video.
+	PreviewStart time.Duration
+	// PreviewLength is the length of the preview loop. It defaults to 3
+	// seconds.
+	PreviewLength time.Duration
+	// PreviewWidth is the width of the preview loop in pixels. It defaults
+	// to 480.
+	PreviewWidth int
+}
+
+// GenerateAssets creates the standard set of files for publishing the Video on
+// the web in dir, using a single ffmpeg process that decodes the input only
+// once. With the default name "video" the files are:
+//
+//	video.mp4                H.264/AAC for maximum compatibility
+//	video.webm               VP9/Opus for smaller downloads
+//	video-poster.jpg         the poster frame
+//	video-thumb-0001.jpg...  seek thumbnails
+//	video-preview.mp4        a short, silent, downscaled loop
+func (v *Video) GenerateAssets(dir string, spec AssetSpec) error {
+	if err := v.checkTrim("cinema.Video.GenerateAssets"); err != nil {
+		return err
+	}
+	if spec.Name == "" {
+		spec.Name = "video"
+	}
+	if spec.ThumbnailInterval <= 0 {
+		spec.ThumbnailInterval = 10 * time.Second
+	}
+	if spec.ThumbnailWidth <= 0 {
+		spec.ThumbnailWidth = 160
+	}
+	if spec.PreviewLength <= 0 {
+		spec.PreviewLength = 3 * time.Second
+	}
+	if spec.PreviewWidth <= 0 {
+		spec.PreviewWidth = 480
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.New("cinema.Video.GenerateAssets: unable to create " +
+			"output directory: " + err.Error())
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.GenerateAssets: " + err.Error())
+	}
+	defer cleanup()
+	path := func(suffix string) string {
+		return filepath.Join(dir, spec.Name+suffix)
+	}
+
+	// All outputs share the decoded and filtered video. The timestamps at
+	// the end of the filter chain are those of the untrimmed output.
+	start, end := v.outputTime(v.start), v.outputTime(v.end)
+	poster := start + spec.PosterTime
+	if poster > end {
+		poster = end
+	}
+	previewStart := start + spec.PreviewStart
+	previewEnd := previewStart + spec.PreviewLength
+	if previewEnd > end {
+		previewEnd = end
+	}
+
+	inputArgs, graph := v.filterGraph(v.chain())
+	graph += ";[vout]split=5[mp4][webm][poster][thumbs][preview]" +
+		";[poster]select=" + filterValue("gte(t,"+formatFloat(poster.Seconds())+")") +
+		"[posterout]" +
+		";[thumbs]fps=1/" + formatFloat(spec.ThumbnailInterval.Seconds()) +
+		",scale=" + strconv.Itoa(spec.ThumbnailWidth) + ":-2[thumbsout]" +
+		";[preview]scale=" + strconv.Itoa(spec.PreviewWidth) + ":-2[previewout]"
+
+	trim := func(from, to time.Duration) []string {
+		return []string{
+			"-ss", formatFloat(from.Seconds()),
+			"-t", formatFloat((to - from).Seconds()),
+		}
+	}
+
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, v.threadGlobalArgs()...)
+	// The outputs are filtered and encoded on the CPU.
+	line = append(line, v.inputWith(v.seekArgs())...)
+	line = append(line, inputArgs...)
+	line = append(line, "-filter_complex", graph)
+
+	line = append(line, "-map", "[mp4]")
+	line = append(line, v.audioMapArgs("0:a?")...)
+	line = append(line, trim(start, end)...)
+	line = append(line, v.audioArgs()...)
+	line = append(line,
+		"-c:v", "libx264", "-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-movflags", "+faststart",
+		path(".mp4"),
+	)
+
+	line = append(line, "-map", "[webm]")
+	line = append(line, v.audioMapArgs("0:a?")...)
+	line = append(line, trim(start, end)...)
+	line = append(line, v.audioArgs()...)
+	line = append(line,
+		"-c:v", "libvpx-vp9", "-crf", "32", "-b:v", "0",
+		"-c:a", "libopus",
+		path(".webm"),
+	)
+
+	line = append(line,
+		"-map", "[posterout]",
+		"-frames:v", "1",
+		"-q:v", "2",
+		path("-poster.jpg"),
+	)
+
+	line = append(line, "-map", "[thumbsout]")
+	line = append(line, trim(start, end)...)
+	line = append(line,
+		"-vsync", "vfr",
+		"-q:v", "5",
+		path("-thumb-%04d.jpg"),
+	)
+
+	line = append(line, "-map", "[previewout]")
+	line = append(line, trim(previewStart, previewEnd)...)
+	line = append(line,
+		"-an",
+		"-c:v", "libx264", "-pix_fmt", "yuv420p",
+		"-movflags", "+faststart",
+		path("-preview.mp4"),
+	)
+
+	if err := v.runFFmpeg(line); err != nil {
+		return ffmpegFailed("cinema.Video.GenerateAssets", err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package cinema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// CompressorOptions configures Compress. Zero values select the defaults.
+type CompressorOptions struct {
+	// Threshold in dB above which the signal is compressed, from -60 to 0.
+	// It defaults to -18.
+	Threshold float64
+	// Ratio by which the signal above the threshold is reduced, from 1 to
+	// 20. It defaults to 2.
+	Ratio float64
+	// Attack is how quickly the compression starts when the signal rises
+	// above the threshold, from 0.01ms to 2s. It defaults to 20ms.
+	Attack time.Duration
+	// Release is how quickly the compression stops when the signal falls
+	// below the threshold, from 0.01ms to 9s. It defaults to 250ms.
+	Release time.Duration
+	// Makeup is the gain in dB applied after compression, from 0 to 36.
+	Makeup float64
+	// Knee is the softness of the transition at the threshold as a linear
+	// factor, from 1 (a hard knee) to 8. It defaults to 2.828, which spans
+	// about 9 dB.
+	Knee float64
+}
+
+// DeEsserOptions configures DeEss. Zero values select the defaults.
+type DeEsserOptions struct {
+	// Intensity of the sibilance reduction, from 0 to 1. It defaults to 0.5.
+	Intensity float64
+	// MaxReduction limits how much the sibilance is reduced, from 0 to 1. It
+	// defaults to 0.5.
+	MaxReduction float64
+	// Frequency above which sibilance is detected, relative to the Nyquist
+	// frequency, from 0 to 1. It defaults to 0.5.
+	Frequency float64
+}
+
+// Compress applies dynamic range compression to the audio, e.g. to even out
+// the loudness of speech. An error is returned if an option is out of range.
+func (v *Video) Compress(opts CompressorOptions) error {
+	if opts.Threshold == 0 {
+		opts.Threshold = -18
+	}
+	if opts.Ratio == 0 {
+		opts.Ratio = 2
+	}
+	if opts.Attack == 0 {
+		opts.Attack = 20 * time.Millisecond
+	}
+	if opts.Release == 0 {
+		opts.Release = 250 * time.Millisecond
+	}
+	if opts.Knee == 0 {
+		opts.Knee = 2.828
+	}
+	attack := float64(opts.Attack) / float64(time.Millisecond)
+	release := float64(opts.Release) / float64(time.Millisecond)
+	switch {
+	case opts.Threshold < -60 || opts.Threshold > 0:
+		return errors.New("cinema.Video.Compress: threshold must be between " +
+			"-60 and 0 dB")
+	case opts.Ratio < 1 || opts.Ratio > 20:
+		return errors.New("cinema.Video.Compress: ratio must be between 1 " +
+			"and 20")
+	case attack < 0.01 || attack > 2000:
+		return errors.New("cinema.Video.Compress: attack must be between " +
+			"0.01ms and 2s")
+	case release < 0.01 || release > 9000:
+		return errors.New("cinema.Video.Compress: release must be between " +
+			"0.01ms and 9s")
+	case opts.Makeup < 0 || opts.Makeup > 36:
+		return errors.New("cinema.Video.Compress: makeup must be between 0 " +
+			"and 36 dB")
+	case opts.Knee < 1 || opts.Knee > 8:
+		return errors.New("cinema.Video.Compress: knee must be between 1 " +
+			"and 8")
+	}
+	v.record("Compress", opts)
+	v.audioFilters = append(v.audioFilters, fmt.Sprintf(
+		"acompressor=threshold=%s:ratio=%s:attack=%s:release=%s:makeup=%s:knee=%s",
+		formatFloat(dbToLinear(opts.Threshold)),
+		formatFloat(opts.Ratio),
+		formatFloat(attack),
+		formatFloat(release),
+		formatFloat(dbToLinear(opts.Makeup)),
+		formatFloat(opts.Knee),
+	))
+	return nil
+}
+
+// Equalize boosts or cuts the audio around the given frequency in Hz by gain
+// dB. q is the quality factor that controls the width of the affected band,
+// higher values affect a narrower band. An error is returned if a parameter is
+// out of range.
+func (v *Video) Equalize(frequency, q, gain float64) error {
+	switch {
+	case frequency <= 0:
+		return errors.New("cinema.Video.Equalize: frequency must be positive")
+	case q <= 0 || q > 1000:
+		return errors.New("cinema.Video.Equalize: q must be greater than 0 " +
+			"and at most 1000")
+	case gain < -900 || gain > 900:
+		return errors.New("cinema.Video.Equalize: gain must be between -900 " +
+			"and 900 dB")
+	}
+	v.record("Equalize", frequency, q, gain)
+	v.audioFilters = append(v.audioFilters, fmt.Sprintf(
+		"equalizer=f=%s:t=q:w=%s:g=%s",
+		formatFloat(frequency), formatFloat(q), formatFloat(gain),
+	))
+	return nil
+}
+
+// HighPass removes audio frequencies below the given frequency in Hz, e.g.
+// rumble and handling noise. An error is returned if frequency is not
+// positive.
+func (v *Video) HighPass(frequency float64) error {
+	if frequency <= 0 {
+		return errors.New("cinema.Video.HighPass: frequency must be positive")
+	}
+	v.record("HighPass", frequency)
+	v.audioFilters = append(v.audioFilters, "highpass=f="+formatFloat(frequency))
+	return nil
+}
+
+// LowPass removes audio frequencies above the given frequency in Hz, e.g.
+// hiss. An error is returned if frequency is not positive.
+func (v *Video) LowPass(frequency float64) error {
+	if frequency <= 0 {
+		return errors.New("cinema.Video.LowPass: frequency must be positive")
+	}
+	v.record("LowPass", frequency)
+	v.audioFilters = append(v.audioFilters, "lowpass=f="+formatFloat(frequency))
+	return nil
+}
+
+// DeEss reduces harsh sibilance ("s" and "sh" sounds) in speech. An error is
+// returned if an option is out of range.
+func (v *Video) DeEss(opts DeEsserOptions) error {
+	if opts.Intensity == 0 {
+		opts.Intensity = 0.5
+	}
+	if opts.MaxReduction == 0 {
+		opts.MaxReduction = 0.5
+	}
+	if opts.Frequency == 0 {
+		opts.Frequency = 0.5
+	}
+	if opts.Intensity < 0 || opts.Intensity > 1 ||
+		opts.MaxReduction < 0 || opts.MaxReduction > 1 ||
+		opts.Frequency < 0 || opts.Frequency > 1 {
+		return errors.New("cinema.Video.DeEss: intensity, max reduction and " +
+			"frequency must be between 0 and 1")
+	}
+	v.record("DeEss", opts)
+	v.audioFilters = append(v.audioFilters, fmt.Sprintf(
+		"deesser=i=%s:m=%s:f=%s",
+		formatFloat(opts.Intensity),
+		formatFloat(opts.MaxReduction),
+		formatFloat(opts.Frequency),
+	))
+	return nil
+}
+
+// audioArgs returns the ffmpeg output options for the audio filters.
+func (v *Video) audioArgs() []string {
+	return audioFilterArgs(v.audioChain())
+}
+
+// audioChain returns the complete audio filter chain in render order.
+func (v *Video) audioChain() []string {
+	filters := append(v.sanitizeAudioFilters(), v.reverseAudioFilters()...)
+	filters = append(filters, v.audioFilters...)
+	filters = append(filters, v.rampAudioFilters()...)
+	filters = append(filters, v.tempoFilters(v.speed)...)
+	return append(filters, v.fadeFilters("afade")...)
+}
+
+// audioFilterArgs returns the ffmpeg output options for the audio filters.
+func audioFilterArgs(filters []string) []string {
+	if len(filters) == 0 {
+		return nil
+	}
+	return []string{"-af", strings.Join(filters, ",")}
+}
+
+// dbToLinear converts a gain in decibels to a linear factor.
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// DenoiseOptions configures DenoiseAudio.
+type DenoiseOptions struct {
+	// NoiseFloor is the level of the noise in dB, from -80 to -20. It
+	// defaults to -50. Only used without Model.
+	NoiseFloor float64
+	// Model is the path to an RNNoise model file. If set, the neural network
+	// based arnndn filter is used instead of the FFT based afftdn, which
+	// works better for speech.
+	Model string
+}
+
+// DenoiseAudio reduces background noise like hiss and hum in the audio, which
+// is common in screen recordings and interviews. strength goes from 0 (no
+// reduction) to 1 (maximum reduction). An error is returned if a parameter is
+// out of range.
+func (v *Video) DenoiseAudio(strength float64, opts DenoiseOptions) error {
+	if strength < 0 || strength > 1 {
+		return errors.New("cinema.Video.DenoiseAudio: strength must be " +
+			"between 0 and 1")
+	}
+	if opts.NoiseFloor == 0 {
+		opts.NoiseFloor = -50
+	}
+	if opts.NoiseFloor < -80 || opts.NoiseFloor > -20 {
+		return errors.New("cinema.Video.DenoiseAudio: noise floor must be " +
+			"between -80 and -20 dB")
+	}
+	v.record("DenoiseAudio", strength, opts)
+	if opts.Model != "" {
+		v.audioFilters = append(v.audioFilters,
+			"arnndn=m="+filterValue(opts.Model)+":mix="+formatFloat(strength),
+		)
+	} else {
+		// afftdn reduces the noise by 0.01 to 97 dB.
+		reduction := math.Max(0.01, strength*97)
+		v.audioFilters = append(v.audioFilters, fmt.Sprintf(
+			"afftdn=nr=%s:nf=%s",
+			formatFloat(reduction), formatFloat(opts.NoiseFloor),
+		))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+)
+
+// OpusMode tunes the Opus encoder for the kind of audio, see OpusOptions.
+type OpusMode int
+
+const (
+	// OpusMusic keeps the full frequency range, for music and mixed content.
+	OpusMusic OpusMode = iota
+	// OpusVoice favors speech intelligibility, for podcasts, interviews and
+	// screen recordings. It gives good results at much lower bitrates.
+	OpusVoice
+)
+
+// VBRMode is how an encoder varies the bitrate over time.
+type VBRMode int
+
+const (
+	// VBROn spends more bits on complex passages and fewer on simple ones,
+	// which gives the best quality for the file size.
+	VBROn VBRMode = iota
+	// VBRConstrained varies the bitrate but keeps it close to the target,
+	// for streaming over links with limited bandwidth.
+	VBRConstrained
+	// VBROff encodes with a constant bitrate.
+	VBROff
+)
+
+// OpusOptions configures RenderOpus. Zero values select the defaults.
+type OpusOptions struct {
+	// Mode tunes the encoder for music or speech. It defaults to OpusMusic.
+	Mode OpusMode
+	// Bitrate is the target bitrate in bits per second, from 6000 to
+	// 510000. It defaults to 128000 for music and 32000 for voice.
+	Bitrate int
+	// VBR is how the bitrate varies. It defaults to VBROn.
+	VBR VBRMode
+}
+
+// RenderOpus renders the audio of the Video as Opus, e.g. into an .opus or
+// .ogg file. Opus gives better quality than MP3 and AAC at the same bitrate.
+// The audio filters of the Video are applied. An error is returned if the
+// Video has no audio or an option is out of range.
+func (v *Video) RenderOpus(output string, opts OpusOptions) error {
+	application, bitrate := "audio", 128000
+	switch opts.Mode {
+	case OpusMusic:
+	case OpusVoice:
+		application, bitrate = "voip", 32000
+	default:
+		return errors.New("cinema.Video.RenderOpus: unknown mode " +
+			strconv.Itoa(int(opts.Mode)))
+	}
+	if opts.Bitrate == 0 {
+		opts.Bitrate = bitrate
+	}
+	if opts.Bitrate < 6000 || opts.Bitrate > 510000 {
+		return errors.New("cinema.Video.RenderOpus: bitrate must be between " +
+			"6000 and 510000")
+	}
+	vbr, ok := map[VBRMode]string{
+		VBROn:          "on",
+		VBRConstrained: "constrained",
+		VBROff:         "off",
+	}[opts.VBR]
+	if !ok {
+		return errors.New("cinema.Video.RenderOpus: unknown VBR mode " +
+			strconv.Itoa(int(opts.VBR)))
+	}
+	return v.renderAudio("cinema.Video.RenderOpus", output,
+		"-c:a", "libopus",
+		"-application", application,
+		"-b:a", strconv.Itoa(opts.Bitrate),
+		"-vbr", vbr,
+	)
+}
+
+// FLACOptions configures RenderFLAC. Zero values select the defaults.
+type FLACOptions struct {
+	// CompressionLevel goes from 1 (fastest) to 12 (smallest file). All
+	// levels are lossless, higher levels only take longer to encode. It
+	// defaults to 5.
+	CompressionLevel int
+}
+
+// RenderFLAC renders the audio of the Video as lossless FLAC, for archiving
+// or further editing. The audio filters of the Video are applied. An error is
+// returned if the Video has no audio or an option is out of range.
+func (v *Video) RenderFLAC(output string, opts FLACOptions) error {
+	if opts.CompressionLevel == 0 {
+		opts.CompressionLevel = 5
+	}
+	if opts.CompressionLevel < 1 || opts.CompressionLevel > 12 {
+		return errors.New("cinema.Video.RenderFLAC: compression level must " +
+			"be between 1 and 12")
+	}
+	return v.renderAudio("cinema.Video.RenderFLAC", output,
+		"-c:a", "flac",
+		"-compression_level", strconv.Itoa(opts.CompressionLevel),
+	)
+}
+
+// renderAudio renders only the audio of the trimmed Video to output, encoded
+// with the given codec options, for the render op.
+func (v *Video) renderAudio(op, output string, codecArgs ...string) error {
+	if v.audioChannels == 0 {
+		return errors.New(op + ": the video has no audio")
+	}
+	if err := v.checkTrim(op); err != nil {
+		return err
+	}
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, v.threadGlobalArgs()...)
+	line = append(line, v.input()...)
+	line = append(line, v.trimArgs(v.outputTime(v.start), v.outputTime(v.end))...)
+	line = append(line, "-vn", "-sn", "-map", "0:a:0")
+	line = append(line, v.audioArgs()...)
+	line = append(line, v.resamplerArgs()...)
+	line = append(line, v.threadOutputArgs()...)
+	line = append(line, v.metadataPolicyArgs(output)...)
+	line = append(line, codecArgs...)
+	line = append(line, output)
+	if err := v.runFFmpeg(line); err != nil {
+		return ffmpegFailed(op, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package cinema
+
+import (
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"time"
+)
+
+// CheckAVSync estimates how much later the first audio stream of the input
+// starts than the first video stream, from the timestamps of their first
+// packets. A negative offset means that the audio starts first.
+//
+// Players align the streams by their timestamps, so a large offset is
+// audible as lip sync error, or as silence or a frozen picture at the start
+// when the streams are cut apart. Offsets above about 45 milliseconds are
+// noticeable. The check only reads the start of the file, drift within the
+// file is not detected.
+func (v *Video) CheckAVSync() (offset time.Duration, err error) {
+	if v.audioChannels == 0 {
+		return 0, errors.New("cinema.Video.CheckAVSync: the video has no audio")
+	}
+	video, err := streamStart(v.filepath, "v:0")
+	if err != nil {
+		return 0, errors.New("cinema.Video.CheckAVSync: unable to read video " +
+			"timestamps: " + err.Error())
+	}
+	audio, err := streamStart(v.filepath, "a:0")
+	if err != nil {
+		return 0, errors.New("cinema.Video.CheckAVSync: unable to read audio " +
+			"timestamps: " + err.Error())
+	}
+	return audio - video, nil
+}
+
+// streamStart returns the earliest presentation time of the first packets of
+// the stream of the file at path selected by the ffprobe stream specifier
+// stream. Packets are in decoding order, so the first packet is not always
+// the first one shown. If the packets have no timestamps, the start time of
+// the stream is returned.
+func streamStart(path, stream string) (time.Duration, error) {
+	cmd := exec.Command(
+		"ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-select_streams", stream,
+		"-read_intervals", "%+#16",
+		"-show_entries", "packet=pts_time,dts_time:stream=start_time",
+		path,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, errors.New("ffprobe failed: " + err.Error())
+	}
+	var desc struct {
+		Packets []struct {
+			PTSTime json.Number `json:"pts_time"`
+			DTSTime json.Number `json:"dts_time"`
+		} `json:"packets"`
+		Streams []struct {
+			StartTime json.Number `json:"start_time"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return 0, errors.New("unable to parse JSON output from ffprobe: " +
+			err.Error())
+	}
+
+	found := false
+	var start time.Duration
+	for _, p := range desc.Packets {
+		if !hasProbeTime(p.PTSTime) && !hasProbeTime(p.DTSTime) {
+			continue
+		}
+		t, err := probeTime(p.PTSTime, p.DTSTime)
+		if err != nil {
+			return 0, errors.New("ffprobe returned invalid timestamp: " +
+				err.Error())
+		}
+		if !found || t < start {
+			start = t
+			found = true
+		}
+	}
+	if found {
+		return start, nil
+	}
+	if len(desc.Streams) == 0 || !hasProbeTime(desc.Streams[0].StartTime) {
+		return 0, errors.New("the stream has no timestamps")
+	}
+	start, err = probeTime(desc.Streams[0].StartTime)
+	if err != nil {
+		return 0, errors.New("ffprobe returned invalid start time: " +
+			err.Error())
+	}
+	return start, nil
+}
+
+// hasProbeTime reports whether ffprobe reported the time secs.
+func hasProbeTime(secs json.Number) bool {
+	return secs != "" && secs != "N/A"
+}
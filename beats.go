@@ -0,0 +1,173 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// beatSampleRate is the sample rate DetectBeats analyzes the audio at, and
+// beatHop the number of samples per analysis frame, about 23 ms.
+const (
+	beatSampleRate = 11025
+	beatHop        = 256
+)
+
+// Beats is the result of DetectBeats.
+type Beats struct {
+	// Tempo is the tempo in beats per minute, 0 if none was found.
+	Tempo float64
+	// Beats are the times of the beats, on a grid of constant tempo.
+	Beats []time.Duration
+	// Onsets are the times where notes or drum hits start. They follow the
+	// music more closely than the beats but include off-beat notes.
+	Onsets []time.Duration
+}
+
+// DetectBeats analyzes the audio of the trimmed video, e.g. a soundtrack, and
+// returns the times of its beats and note onsets, so that edits can be cut
+// on the beat. The times are times of the input, like those of Trim. The
+// tempo is assumed to be constant between 60 and 200 beats per minute; music
+// with tempo changes gets a grid of the average tempo, its onsets are still
+// correct. An error is returned if the video has no audio or ffmpeg fails.
+func (v *Video) DetectBeats() (*Beats, error) {
+	if v.audioChannels == 0 {
+		return nil, errors.New("cinema.Video.DetectBeats: the video has no " +
+			"audio")
+	}
+	if err := v.checkTrim("cinema.Video.DetectBeats"); err != nil {
+		return nil, err
+	}
+
+	// The onset strength is the rise of the loudness from one frame to the
+	// next.
+	var (
+		flux      []float64
+		energy    float64
+		prevLevel = -1.0
+		n         int
+	)
+	args := append(v.audioArgs(), "-ar", strconv.Itoa(beatSampleRate))
+	err := v.decodeAudio(args, func(s float32) {
+		energy += float64(s) * float64(s)
+		n++
+		if n < beatHop {
+			return
+		}
+		level := math.Log1p(1000 * energy / beatHop)
+		if prevLevel >= 0 {
+			flux = append(flux, math.Max(0, level-prevLevel))
+		} else {
+			flux = append(flux, 0)
+		}
+		prevLevel = level
+		energy, n = 0, 0
+	})
+	if err != nil {
+		return nil, ffmpegFailed("cinema.Video.DetectBeats", err)
+	}
+
+	frameTime := func(i float64) time.Duration {
+		t := time.Duration(i * beatHop / beatSampleRate * float64(time.Second))
+		return v.inputTime(v.outputTime(v.start) + t)
+	}
+	result := &Beats{}
+	for _, i := range pickOnsets(flux) {
+		result.Onsets = append(result.Onsets, frameTime(float64(i)))
+	}
+	period, phase := beatGrid(flux)
+	if period > 0 {
+		result.Tempo = math.Round(60*beatSampleRate/(period*beatHop)*10) / 10
+		for i := phase; i < float64(len(flux)); i += period {
+			result.Beats = append(result.Beats, frameTime(i))
+		}
+	}
+	return result, nil
+}
+
+// pickOnsets returns the frames where the onset strength has a peak that
+// stands out from its surroundings, at least 100 ms apart.
+func pickOnsets(flux []float64) []int {
+	const (
+		window  = 20 // frames before and after for the local mean
+		spacing = 100 * time.Millisecond
+	)
+	minGap := int(spacing.Seconds() * beatSampleRate / beatHop)
+	var max float64
+	for _, f := range flux {
+		max = math.Max(max, f)
+	}
+	var onsets []int
+	last := -minGap
+	for i := 1; i+1 < len(flux); i++ {
+		if flux[i] < flux[i-1] || flux[i] < flux[i+1] || i-last < minGap {
+			continue
+		}
+		var sum float64
+		count := 0
+		for j := i - window; j <= i+window; j++ {
+			if j >= 0 && j < len(flux) {
+				sum += flux[j]
+				count++
+			}
+		}
+		if flux[i] > 1.5*sum/float64(count)+0.05*max {
+			onsets = append(onsets, i)
+			last = i
+		}
+	}
+	return onsets
+}
+
+// beatGrid finds the beat period in frames with the autocorrelation of the
+// onset strength, preferring tempos around 120 beats per minute, and the
+// offset of the grid of that period that hits the strongest onsets. The
+// period is 0 if no tempo was found.
+func beatGrid(flux []float64) (period, phase float64) {
+	framesPerMinute := 60.0 * beatSampleRate / beatHop
+	minLag := int(framesPerMinute / 200)
+	maxLag := int(framesPerMinute/60) + 1
+	if len(flux) < 2*maxLag {
+		return 0, 0
+	}
+	score := func(lag int) float64 {
+		var sum float64
+		for i := lag; i < len(flux); i++ {
+			sum += flux[i] * flux[i-lag]
+		}
+		return sum / float64(len(flux)-lag)
+	}
+	best, bestScore := 0, 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		// Weight the tempos with a log-normal curve around 120 BPM.
+		octaves := math.Log2(framesPerMinute / float64(lag) / 120)
+		weighted := score(lag) * math.Exp(-octaves*octaves/2)
+		if weighted > bestScore {
+			best, bestScore = lag, weighted
+		}
+	}
+	if best == 0 {
+		return 0, 0
+	}
+	// Refine the period together with the offset of the grid: the grid that
+	// hits the strongest onsets wins. Small errors of the period add up over
+	// the length of the audio, so the steps are fine.
+	bestSum := -1.0
+	for p := float64(best) - 1; p <= float64(best)+1; p += 0.01 {
+		for offset := 0.0; offset < p; offset++ {
+			var sum float64
+			count := 0
+			for i := offset; i < float64(len(flux)); i += p {
+				sum += flux[int(i+0.5)%len(flux)]
+				count++
+			}
+			// The mean does not favor shorter periods, which have more
+			// beats.
+			if sum /= float64(count); sum > bestSum {
+				period, phase, bestSum = p, offset, sum
+			}
+		}
+	}
+	return period, phase
+}
@@ -0,0 +1,49 @@
+package cinema
+
+import (
+	"errors"
+	"os"
+)
+
+// RenderCaptioned renders two versions of the Video at once: clean without
+// captions and captioned with the subtitles in the file at subtitles burned
+// in, like BurnSubtitles. The input is decoded and the other operations are
+// applied only once, which takes about half the time of two renders. The
+// subtitle times are relative to the start of the output. Both versions are
+// encoded on the CPU even if SetHardware was used.
+func (v *Video) RenderCaptioned(clean, captioned, subtitles string) error {
+	if _, err := os.Stat(subtitles); err != nil {
+		return errors.New("cinema.Video.RenderCaptioned: unable to load " +
+			"subtitles: " + err.Error())
+	}
+	if err := v.checkTrim("cinema.Video.RenderCaptioned"); err != nil {
+		return err
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderCaptioned: " + err.Error())
+	}
+	defer cleanup()
+
+	// The filter chain of the Video is applied once and its result is split
+	// into the two versions, which needs the frames on the CPU.
+	clip := v.snapshot()
+	clip.hardware = NoHardware
+	inputArgs, graph := clip.filterGraph(clip.chain())
+	// The subtitles see timestamps that start at the start of the output.
+	start := formatFloat(clip.outputTime(clip.start).Seconds())
+	graph += ";[vout]split[clean][sub];[sub]setpts=PTS-" + start + "/TB," +
+		subtitlesFilter(subtitles).expr + ",setpts=PTS+" + start + "/TB[captioned]"
+
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, clip.threadGlobalArgs()...)
+	line = append(line, clip.input()...)
+	line = append(line, inputArgs...)
+	line = append(line, "-filter_complex", graph)
+	line = append(line, clip.branchOutputArgs("[clean]", clean)...)
+	line = append(line, clip.branchOutputArgs("[captioned]", captioned)...)
+	if err := clip.runFFmpeg(line); err != nil {
+		return ffmpegFailed("cinema.Video.RenderCaptioned", err)
+	}
+	return nil
+}
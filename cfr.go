@@ -0,0 +1,80 @@
+package cinema
+
+import (
+	"math"
+	"strconv"
+)
+
+// standardRates are the frame rates ForceCFR snaps to, as fractions.
+var standardRates = []string{
+	"24000/1001", "24", "25", "30000/1001", "30",
+	"48", "50", "60000/1001", "60", "120",
+}
+
+// ForceCFR makes the output have a constant frame rate that matches the
+// input. Recordings from phones and screen recorders often have a variable
+// frame rate (see IsVFR), which breaks many editors and makes audio drift out
+// of sync in them.
+//
+// The output frame rate is the average frame rate of the input, rounded to
+// the nearest common rate like 30000/1001 (29.97) if it is close to one.
+// Unlike SetFPS, this keeps fractional rates exact. Frames are duplicated or
+// dropped where the input timing is uneven. Calling SetFPS afterwards
+// replaces the frame rate but keeps it constant.
+func (v *Video) ForceCFR() {
+	v.record("ForceCFR")
+	v.forceCFR()
+}
+
+// forceCFR sets the output frame rate to the constant rate closest to the
+// average frame rate of the input.
+func (v *Video) forceCFR() {
+	v.cfr = true
+	v.fpsRate = cfrRate(v.frameRate)
+	if v.fpsRate == "" {
+		return
+	}
+	v.fps = int(math.Round(parseRate(v.fpsRate)))
+}
+
+// fpsFilter returns the filter that sets the output frame rate.
+func (v *Video) fpsFilter() string {
+	rate := v.fpsRate
+	if rate == "" {
+		rate = strconv.Itoa(v.fps)
+	}
+	if !v.cfr {
+		return "fps=fps=" + rate
+	}
+	// Round timestamps to the nearest frame so that uneven input frames are
+	// not shifted by up to a frame.
+	return "fps=fps=" + rate + ":round=near"
+}
+
+// cfrArgs returns the output options that keep the muxer from dropping the
+// duplicated frames again, which some muxers do for variable frame rates.
+func (v *Video) cfrArgs() []string {
+	if !v.cfr {
+		return nil
+	}
+	return []string{"-vsync", "cfr"}
+}
+
+// cfrRate returns the constant frame rate to use for a video with the average
+// frame rate avg, or the empty string if avg is unknown.
+func cfrRate(avg string) string {
+	rate := parseRate(avg)
+	if rate <= 0 {
+		return ""
+	}
+	best, diff := "", rate*0.01
+	for _, r := range standardRates {
+		if d := math.Abs(parseRate(r) - rate); d < diff {
+			best, diff = r, d
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return formatFloat(math.Round(rate*1000) / 1000)
+}
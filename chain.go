@@ -0,0 +1,145 @@
+package cinema
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// Chain runs several stages of operations at the same time, each one in its
+// own ffmpeg process, and streams the output of each stage into the next one
+// through a pipe instead of writing large intermediate files to disk. Create
+// it with NewChain, add stages with Then and run it with Render.
+//
+// Between the stages the video is passed uncompressed so no quality is lost.
+type Chain struct {
+	stages []*Video
+}
+
+// NewChain creates a Chain whose first stage is first.
+func NewChain(first *Video) *Chain {
+	return &Chain{stages: []*Video{first}}
+}
+
+// Then adds a stage to the chain and returns it. The input of the new stage is
+// the output of the previous stage, i.e. its size is the previous stage's
+// output size and its timeline starts at 0 at the previous stage's start.
+// Apply the operations of the stage to the returned Video.
+//
+// Operations applied to the previous stage after calling Then are not
+// reflected in the new stage's size and duration.
+func (c *Chain) Then() *Video {
+	prev := c.stages[len(c.stages)-1]
+	duration := prev.outputTime(prev.end) - prev.outputTime(prev.start)
+	next := &Video{
+		filepath:    "pipe:0",
+		inputFormat: "nut",
+		width:       prev.OutputWidth(),
+		height:      prev.OutputHeight(),
+		fps:         prev.fps,
+		fpsRate:     prev.fpsRate,
+		end:         duration,
+		duration:    duration,
+
+		audioChannels: prev.audioChannels,
+		channelLayout: prev.channelLayout,
+		sampleRate:    prev.sampleRate,
+	}
+	c.stages = append(c.stages, next)
+	return next
+}
+
+// Stages returns the Videos of all stages, in order.
+func (c *Chain) Stages() []*Video {
+	return append([]*Video(nil), c.stages...)
+}
+
+// CommandLines returns the command lines of all stages, in order, that Render
+// runs to create output. The standard output of each command is connected to
+// the standard input of the next one.
+func (c *Chain) CommandLines(output string) [][]string {
+	var lines [][]string
+	for i, v := range c.stages {
+		if i == len(c.stages)-1 {
+			lines = append(lines, v.commandLine(output))
+		} else {
+			lines = append(lines, v.commandLine("pipe:1",
+				"-c:v", "rawvideo",
+				"-c:a", "pcm_s16le",
+				"-f", "nut",
+			))
+		}
+	}
+	return lines
+}
+
+// Render runs all stages and writes the output of the last one to output.
+func (c *Chain) Render(output string) error {
+	for _, v := range c.stages {
+		if err := v.checkTrim("cinema.Chain.Render"); err != nil {
+			return err
+		}
+		cleanup, err := v.prepareRender()
+		if err != nil {
+			return errors.New("cinema.Chain.Render: " + err.Error())
+		}
+		defer cleanup()
+	}
+	var cmds []*exec.Cmd
+	// ends are this process' copies of the pipe ends, they are closed once
+	// the stages are started so that a stage notices when its neighbor dies.
+	var ends []*os.File
+	closeEnds := func() {
+		for _, f := range ends {
+			f.Close()
+		}
+		ends = nil
+	}
+	for i, line := range c.CommandLines(output) {
+		cmd := c.stages[i].command(line)
+		if i > 0 {
+			r, w, err := os.Pipe()
+			if err != nil {
+				closeEnds()
+				return errors.New("cinema.Chain.Render: unable to connect " +
+					"stages: " + err.Error())
+			}
+			cmds[i-1].Stdout = w
+			cmd.Stdin = r
+			ends = append(ends, r, w)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	for i, cmd := range cmds {
+		if err := startProcess(cmd, c.stages[i].memoryLimit); err != nil {
+			closeEnds()
+			for _, started := range cmds[:i] {
+				killProcess(started)
+				waitProcess(started)
+			}
+			return errors.New("cinema.Chain.Render: unable to start stage " +
+				strconv.Itoa(i+1) + ": " + err.Error())
+		}
+	}
+	closeEnds()
+
+	// The first failing stage is reported since its failure usually makes
+	// the other stages fail as well.
+	errs := make([]error, len(cmds))
+	for i, cmd := range cmds {
+		errs[i] = waitProcess(cmd)
+	}
+	for i, err := range errs {
+		if m, ok := err.(*MemoryError); ok {
+			m.Op = "cinema.Chain.Render"
+			return m
+		}
+		if err != nil {
+			return errors.New("cinema.Chain.Render: stage " +
+				strconv.Itoa(i+1) + " failed: " + err.Error())
+		}
+	}
+	return nil
+}
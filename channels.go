@@ -0,0 +1,164 @@
+package cinema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// channelNames lists the channels of common channel layouts in the order
+// ffmpeg's channelsplit filter outputs them.
+var channelNames = map[string][]string{
+	"mono":      {"FC"},
+	"stereo":    {"FL", "FR"},
+	"2.1":       {"FL", "FR", "LFE"},
+	"3.0":       {"FL", "FR", "FC"},
+	"quad":      {"FL", "FR", "BL", "BR"},
+	"4.0":       {"FL", "FR", "FC", "BC"},
+	"5.0":       {"FL", "FR", "FC", "BL", "BR"},
+	"5.0(side)": {"FL", "FR", "FC", "SL", "SR"},
+	"5.1":       {"FL", "FR", "FC", "LFE", "BL", "BR"},
+	"5.1(side)": {"FL", "FR", "FC", "LFE", "SL", "SR"},
+	"6.1":       {"FL", "FR", "FC", "LFE", "BC", "SL", "SR"},
+	"7.1":       {"FL", "FR", "FC", "LFE", "BL", "BR", "SL", "SR"},
+}
+
+// SplitAudioChannels writes each channel of the first audio stream, or of the
+// first one selected with SelectAudioStreams, into its own mono WAV file in
+// dir. The trim settings of the Video apply, all other operations are
+// ignored. For common channel layouts the files are named after the channels,
+// e.g. FL.wav and FR.wav for stereo, otherwise they are numbered, e.g.
+// channel-1.wav. The names of the created files are returned.
+func (v *Video) SplitAudioChannels(dir string) ([]string, error) {
+	if v.audioChannels == 0 {
+		return nil, errors.New("cinema.Video.SplitAudioChannels: the video " +
+			"has no audio")
+	}
+	if err := v.checkTrim("cinema.Video.SplitAudioChannels"); err != nil {
+		return nil, err
+	}
+	stream, layout, channels := "[0:a:0]", v.channelLayout, v.audioChannels
+	if v.streams.audio != nil {
+		index := v.streams.audio[0]
+		stream = "[0:a:" + strconv.Itoa(index) + "]"
+		if s, ok := v.audioStreamInfo(index); ok {
+			layout, channels = s.ChannelLayout, s.Channels
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.New("cinema.Video.SplitAudioChannels: unable to " +
+			"create output directory: " + err.Error())
+	}
+
+	names, known := channelNames[layout]
+	if !known {
+		names = nil
+		for i := 1; i <= channels; i++ {
+			names = append(names, "channel-"+strconv.Itoa(i))
+		}
+	}
+
+	var graph string
+	var pads []string
+	for i := range names {
+		pads = append(pads, "[c"+strconv.Itoa(i)+"]")
+	}
+	if known {
+		graph = stream + "channelsplit=channel_layout=" +
+			filterValue(layout) + strings.Join(pads, "")
+	} else {
+		// channelsplit needs a known layout, pick the channels one by one.
+		var splits []string
+		for i := range names {
+			splits = append(splits, "[s"+strconv.Itoa(i)+"]")
+		}
+		graph = stream + "asplit=" + strconv.Itoa(len(names)) +
+			strings.Join(splits, "")
+		for i := range names {
+			graph += ";" + splits[i] + "pan=mono|c0=c" + strconv.Itoa(i) +
+				pads[i]
+		}
+	}
+
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, v.threadGlobalArgs()...)
+	line = append(line, v.input()...)
+	line = append(line, "-filter_complex", graph)
+	var outputs []string
+	for i, name := range names {
+		// Output options only apply to the next output, so the trim is
+		// repeated for every file.
+		output := filepath.Join(dir, name+".wav")
+		line = append(line, v.trimArgs(v.start, v.end)...)
+		line = append(line, "-map", pads[i], output)
+		outputs = append(outputs, output)
+	}
+
+	if err := v.runFFmpeg(line); err != nil {
+		return nil, ffmpegFailed("cinema.Video.SplitAudioChannels", err)
+	}
+	return outputs, nil
+}
+
+// MergeAudioChannels combines mono audio files into a single multichannel
+// track with the given channel layout, e.g. "stereo" or "5.1", and writes it
+// to output. The inputs are assigned to the channels of the layout in order,
+// e.g. for stereo the first input becomes the front left and the second one
+// the front right channel. This is the reverse of SplitAudioChannels.
+func MergeAudioChannels(inputs []string, layout string, output string) error {
+	if len(inputs) == 0 {
+		return errors.New("cinema.MergeAudioChannels: no inputs given")
+	}
+	if names, ok := channelNames[layout]; ok && len(names) != len(inputs) {
+		return errors.New("cinema.MergeAudioChannels: channel layout " +
+			layout + " needs " + strconv.Itoa(len(names)) + " inputs but " +
+			strconv.Itoa(len(inputs)) + " were given")
+	}
+
+	line := []string{"ffmpeg", "-y"}
+	var pads string
+	for i, input := range inputs {
+		line = append(line, "-i", input)
+		pads += "[" + strconv.Itoa(i) + ":a:0]"
+	}
+	graph := pads + "join=inputs=" + strconv.Itoa(len(inputs)) +
+		":channel_layout=" + filterValue(layout)
+	if names, ok := channelNames[layout]; ok {
+		var mapping []string
+		for i, name := range names {
+			mapping = append(mapping, strconv.Itoa(i)+".0-"+name)
+		}
+		graph += ":map=" + strings.Join(mapping, "|")
+	}
+	line = append(line,
+		"-filter_complex", graph+"[a]",
+		"-map", "[a]",
+		output,
+	)
+
+	if err := run(line); err != nil {
+		return errors.New("cinema.MergeAudioChannels: ffmpeg failed: " +
+			err.Error())
+	}
+	return nil
+}
+
+// audioStreamInfo returns the description of the audio stream with the given
+// index among the audio streams of the input.
+func (v *Video) audioStreamInfo(index int) (StreamInfo, bool) {
+	if v.probe == nil {
+		return StreamInfo{}, false
+	}
+	for _, s := range v.probe.Streams {
+		if s.Type != "audio" {
+			continue
+		}
+		if index == 0 {
+			return s, true
+		}
+		index--
+	}
+	return StreamInfo{}, false
+}
@@ -0,0 +1,245 @@
+package cinema
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SilenceOptions configures DetectSilences.
+type SilenceOptions struct {
+	// Noise is the volume in dB below which audio counts as silence. It
+	// defaults to -30.
+	Noise float64
+	// MinDuration is the minimum length of a silence. It defaults to 2
+	// seconds.
+	MinDuration time.Duration
+}
+
+// Silence is a silent section of the input.
+type Silence struct {
+	// Start and End are the times in the input video.
+	Start, End time.Duration
+}
+
+var silenceLine = regexp.MustCompile(`silence_(start|end): (-?[0-9.]+)`)
+
+// DetectSilences returns the silent sections of the audio of the trimmed
+// Video, in order.
+func (v *Video) DetectSilences(opts SilenceOptions) ([]Silence, error) {
+	if opts.Noise == 0 {
+		opts.Noise = -30
+	}
+	if opts.MinDuration <= 0 {
+		opts.MinDuration = 2 * time.Second
+	}
+	// The trim is applied to the input, so the timestamps in the log are
+	// those of the input.
+	line := []string{
+		"ffmpeg",
+		"-ss", formatFloat(v.start.Seconds()),
+		"-t", formatFloat((v.end - v.start).Seconds()),
+	}
+	line = append(line, v.copytsArgs()...)
+	cmd := command(append(line,
+		"-i", v.filepath,
+		"-vn",
+		"-af", "silencedetect=noise="+formatFloat(opts.Noise)+"dB"+
+			":d="+formatFloat(opts.MinDuration.Seconds()),
+		"-f", "null",
+		"-",
+	))
+	var log bytes.Buffer
+	cmd.Stderr = &log
+	if err := startProcess(cmd, 0); err != nil {
+		return nil, errors.New("cinema.Video.DetectSilences: unable to start " +
+			"ffmpeg: " + err.Error())
+	}
+	if err := waitProcess(cmd); err != nil {
+		return nil, errors.New("cinema.Video.DetectSilences: ffmpeg failed: " +
+			err.Error())
+	}
+
+	var silences []Silence
+	for _, m := range silenceLine.FindAllStringSubmatch(log.String(), -1) {
+		secs, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		t := time.Duration(secs * float64(time.Second))
+		if m[1] == "start" {
+			silences = append(silences, Silence{Start: t, End: v.end})
+		} else if len(silences) > 0 {
+			silences[len(silences)-1].End = t
+		}
+	}
+	return silences, nil
+}
+
+// ChaptersFromSilences splits the output into chapters at the silences of
+// its audio, e.g. the pauses between the talks of a long conference
+// recording, so players can jump between them. Each chapter boundary is the
+// middle of a silence. The chapters are titled "Chapter 1", "Chapter 2" and so
+// on.
+//
+// See ChaptersFromMarkers for how the chapters are written.
+func (v *Video) ChaptersFromSilences(opts SilenceOptions) error {
+	silences, err := v.DetectSilences(opts)
+	if err != nil {
+		return errors.New("cinema.Video.ChaptersFromSilences: " + err.Error())
+	}
+	var markers []time.Duration
+	for _, s := range silences {
+		markers = append(markers, s.Start+(s.End-s.Start)/2)
+	}
+	if err := v.ChaptersFromMarkers(markers, nil); err != nil {
+		return errors.New("cinema.Video.ChaptersFromSilences: " + err.Error())
+	}
+	return nil
+}
+
+// ChaptersFromMarkers writes chapters into the output that start at the
+// trimmed start and at each of the markers, which are times in the input
+// video. titles are the titles of the chapters, in order; chapters without a
+// title are named "Chapter 1", "Chapter 2" and so on.
+//
+// The chapters replace those of the input in the output of Render and
+// StartRender. They are converted to output times when rendering, so they
+// stay correct when the Video is trimmed afterwards. Markers outside of the
+// trimmed range are skipped. ffmpeg reads the chapters from a temporary file,
+// which is written for each render and removed afterwards, so the command
+// line returned by CommandLine does not include the chapters.
+func (v *Video) ChaptersFromMarkers(markers []time.Duration, titles []string) error {
+	for i := 1; i < len(markers); i++ {
+		if markers[i] < markers[i-1] {
+			return errors.New("cinema.Video.ChaptersFromMarkers: markers " +
+				"must be in order")
+		}
+	}
+	v.record("ChaptersFromMarkers", markers, titles)
+	v.writeChapters = true
+
+	// The first chapter starts wherever the output starts.
+	starts := append([]time.Duration{0}, markers...)
+	v.outputChapters = nil
+	for i, start := range starts {
+		end := v.duration
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		title := "Chapter " + strconv.Itoa(i+1)
+		if i < len(titles) && titles[i] != "" {
+			title = titles[i]
+		}
+		v.outputChapters = append(v.outputChapters, Chapter{
+			Title: title,
+			Start: start,
+			End:   end,
+		})
+	}
+	return nil
+}
+
+// chapterArgs returns the input and output arguments that write the chapters
+// set with ChaptersFromMarkers or SetChapters. index is the index of the chapter input.
+func (v *Video) chapterArgs(index int) (inputArgs, outputArgs []string) {
+	if v.chapterFile == "" {
+		return nil, nil
+	}
+	return []string{"-f", "ffmetadata", "-i", v.chapterFile},
+		[]string{"-map_chapters", strconv.Itoa(index)}
+}
+
+// writeChapterFile writes the chapters set with ChaptersFromMarkers or
+// SetChapters in output times to a new temporary chapter file, which is
+// removed by removeChapterFile.
+func (v *Video) writeChapterFile() error {
+	if !v.writeChapters {
+		return nil
+	}
+	start, end := v.outputTime(v.start), v.outputTime(v.end)
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for _, c := range v.outputChapters {
+		from, to := v.outputTime(c.Start)-start, v.outputTime(c.End)-start
+		if from < 0 {
+			from = 0
+		}
+		if to > end-start {
+			to = end - start
+		}
+		if from >= to {
+			continue
+		}
+		b.WriteString("[CHAPTER]\nTIMEBASE=1/1000\n")
+		b.WriteString("START=" + strconv.FormatInt(from.Milliseconds(), 10) + "\n")
+		b.WriteString("END=" + strconv.FormatInt(to.Milliseconds(), 10) + "\n")
+		b.WriteString("title=" + escapeMetadata(c.Title) + "\n")
+	}
+	f, err := ioutil.TempFile("", "cinema-chapters-*.txt")
+	if err != nil {
+		return errors.New("unable to create chapter file: " + err.Error())
+	}
+	_, err = f.WriteString(b.String())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return errors.New("unable to write chapter file: " + err.Error())
+	}
+	v.chapterFile = f.Name()
+	return nil
+}
+
+// removeChapterFile removes the chapter file written by writeChapterFile.
+func (v *Video) removeChapterFile() {
+	if v.chapterFile != "" {
+		os.Remove(v.chapterFile)
+		v.chapterFile = ""
+	}
+}
+
+// prepareRender creates the files the command line of the Video refers to.
+// Each render gets its own chapter and transforms files, so renders of copies
+// of the Video can run at the same time; call cleanup once ffmpeg exited to
+// remove them and the filter scripts.
+func (v *Video) prepareRender() (cleanup func(), err error) {
+	if err := v.createPreviewDir(); err != nil {
+		return nil, err
+	}
+	removeScripts, err := v.writeScripts()
+	if err != nil {
+		return nil, err
+	}
+	cleanup = func() {
+		v.removeTransformsFile()
+		v.removeChapterFile()
+		removeScripts()
+	}
+	if err := v.detectMotion(); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if err := v.writeChapterFile(); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return cleanup, nil
+}
+
+// escapeMetadata escapes the special characters of ffmetadata files.
+func escapeMetadata(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"=", `\=`,
+		";", `\;`,
+		"#", `\#`,
+		"\n", "\\\n",
+	).Replace(s)
+}
@@ -0,0 +1,125 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chapter is a named section of a video.
+type Chapter struct {
+	Title string
+	// Start and End are the times in the input video.
+	Start, End time.Duration
+}
+
+// Chapters returns the chapters of the input video, as reported by ffprobe,
+// in order. The times are times in the input, untouched by Trim. The result
+// is a copy. Chapters set with SetChapters or ChaptersFromMarkers are not
+// included.
+func (v *Video) Chapters() []Chapter {
+	return append([]Chapter(nil), v.chapters...)
+}
+
+// SetChapters writes the chapters into the output, e.g. to keep long videos
+// navigable after editing or to add chapters to a video without any. The
+// times of the chapters are times in the input video, like those returned by
+// Chapters; chapters without a title are named "Chapter 1", "Chapter 2" and
+// so on. To keep the chapters of the input through a trim, pass the result
+// of Chapters.
+//
+// The chapters replace those of the input and those set before with
+// ChaptersFromMarkers. See ChaptersFromMarkers for how they are written. An
+// error is returned if a chapter does not end after it starts or the
+// chapters are not in order.
+func (v *Video) SetChapters(chapters []Chapter) error {
+	for i, c := range chapters {
+		if c.Start < 0 || c.End <= c.Start {
+			return errors.New("cinema.Video.SetChapters: chapter " +
+				strconv.Itoa(i+1) + " does not end after it starts")
+		}
+		if i > 0 && c.Start < chapters[i-1].Start {
+			return errors.New("cinema.Video.SetChapters: chapters must be " +
+				"in order")
+		}
+	}
+	v.record("SetChapters", chapters)
+	v.writeChapters = true
+	v.outputChapters = nil
+	for i, c := range chapters {
+		if c.Title == "" {
+			c.Title = "Chapter " + strconv.Itoa(i+1)
+		}
+		v.outputChapters = append(v.outputChapters, c)
+	}
+	return nil
+}
+
+// SplitByChapters renders one output file per chapter of the input video. All
+// other operations apply to every file. Chapters outside of the trimmed range
+// (see Trim) are skipped and chapters that are partially outside of it are
+// shortened.
+//
+// The output file names are created from pattern by replacing {n} with the
+// chapter number, starting at 1 and zero padded to the same width for all
+// chapters, and {title} with the chapter title. Characters that are not
+// allowed in file names are replaced in the title. The chapter title is also
+// written into the title metadata of each file. The names of the created files
+// are returned. If some files could not be rendered, the error is an
+// *OutputError, see ContinueOnError.
+func (v *Video) SplitByChapters(pattern string) ([]string, error) {
+	if len(v.chapters) == 0 {
+		return nil, errors.New("cinema.Video.SplitByChapters: the video " +
+			"has no chapters")
+	}
+
+	digits := len(strconv.Itoa(len(v.chapters)))
+	var outputs []string
+	var clips []Video
+	for i, c := range v.chapters {
+		start, end := c.Start, c.End
+		if start < v.start {
+			start = v.start
+		}
+		if end > v.end {
+			end = v.end
+		}
+		if start >= end {
+			continue
+		}
+
+		n := strconv.Itoa(i + 1)
+		n = strings.Repeat("0", digits-len(n)) + n
+		title := c.Title
+		if title == "" {
+			title = "Chapter " + n
+		}
+		output := strings.NewReplacer(
+			"{n}", n,
+			"{title}", sanitizeFileName(title),
+		).Replace(pattern)
+
+		chapter := v.snapshot()
+		chapter.start, chapter.end = start, end
+		chapter.outputArgs = append(chapter.outputArgs,
+			metadataArgs(output, title, nil)...)
+		outputs = append(outputs, output)
+		clips = append(clips, chapter)
+	}
+	return v.renderOutputs("cinema.Video.SplitByChapters", outputs, func(i int) error {
+		return clips[i].Render(outputs[i])
+	})
+}
+
+// sanitizeFileName replaces characters that are not allowed in file names on
+// common operating systems.
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.Trim(name, " .")
+}
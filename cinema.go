@@ -1,13 +1,12 @@
 package cinema
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"strconv"
-	"strings"
 	"time"
 )
 
@@ -16,77 +15,255 @@ import (
 // transformation functions to generate the desired output. Then call Render to
 // generate the final output video file.
 type Video struct {
-	filepath string
-	width    int
-	height   int
-	fps      int
-	start    time.Duration
-	end      time.Duration
-	duration time.Duration
-	filters  []string
+	filepath       string
+	width          int
+	height         int
+	fps            int
+	fpsRate        string
+	cfr            bool
+	start          time.Duration
+	end            time.Duration
+	duration       time.Duration
+	chapters       []Chapter
+	filters        []filter
+	audioFilters   []string
+	ramp           []SpeedKeyframe
+	canonical      bool
+	stageOrder     []Stage
+	guides         Guides
+	timecode       *TimecodeOptions
+	forensic       ForensicWatermarker
+	recipient      string
+	outputArgs     []string
+	follow         *FollowOptions
+	inputFormat    string
+	hardware       Hardware
+	hwDevice       string
+	trimMode       TrimMode
+	memoryLimit    int64
+	subtitles      []subtitleTrack
+	subtitleOffset time.Duration
+	subtitleScale  float64
+	outputChapters []Chapter
+	writeChapters  bool
+	chapterFile    string
+	timeFormat     TimeFormat
+	timePrecision  time.Duration
+	preview        *previewStream
+	threads        *ThreadOptions
+	sanitize       bool
+	resampler      *ResamplerOptions
+	logLevel       LogLevel
+	keepGoing      bool
+	metadata       *MetadataPolicy
+	imageQuality   int
+	encoder        encoderSettings
+	source         *readerSource
+	strictness     Strictness
+	reversed       *ReverseOptions
+	history        []Operation
+
+	// formatName is the container format as reported by ffprobe, e.g.
+	// "mov,mp4,m4a,3gp,3g2,mj2".
+	formatName string
+	// formatTags is the container metadata of the input.
+	formatTags map[string]string
+	// bitrate is the overall bit rate of the input in bits per second, 0 if
+	// unknown.
+	bitrate int
+	// startTime is the first timestamp of the input, see StartTimeOffset.
+	startTime time.Duration
+	// videoCodec, videoProfile, videoLevel and pixelFormat describe the
+	// first video stream. videoLevel is as reported by ffprobe, e.g. 41 for
+	// H.264 level 4.1.
+	videoCodec   string
+	videoProfile string
+	videoLevel   int
+	pixelFormat  string
+	// audioCodec, audioChannels, channelLayout and sampleRate describe the
+	// first audio stream, audioChannels is 0 if there is no audio.
+	audioCodec    string
+	audioChannels int
+	channelLayout string
+	sampleRate    int
+	// frameRate is the average frame rate of the first video stream as a
+	// fraction, e.g. "30000/1001". vfr reports whether the frame rate is
+	// variable.
+	frameRate string
+	vfr       bool
+	// videoStreams and audioStreams are the numbers of video and audio
+	// streams of the input.
+	videoStreams int
+	audioStreams int
+
+	// changePitch disables pitch preservation for speed changes.
+	changePitch bool
+	// speed is the playback speed set with SetSpeed, 0 if unchanged.
+	speed float64
+	// fadeIn and fadeOut are the durations set with FadeIn and FadeOut.
+	fadeIn, fadeOut time.Duration
+	// stabilizer is set by Stabilize, nil if the video is not stabilized.
+	stabilizer *stabilizer
+	// transformsFile is the camera motion found by detectMotion, set only
+	// while the Video renders.
+	transformsFile string
+	// color holds the color adjustments, see SetBrightness.
+	color colorSettings
+	// streams are the streams of the input that are written to the output,
+	// see SelectVideoStream.
+	streams streamSelection
+	// seekMode is set with SetSeekMode, keyframes caches the key frames
+	// SeekKeyframe moves the start to.
+	seekMode  SeekMode
+	keyframes *keyframeIndex
+	// probe is what ffprobe reported about the input, see Probe.
+	probe *ProbeResult
 }
 
 // Load gives you a Video that can be operated on. Load does not open the file
 // or load it into memory. Apply operations to the Video and call Render to
 // generate the output video file.
 func Load(path string) (*Video, error) {
+	return load(context.Background(), "cinema.Load", path)
+}
+
+// load implements Load and LoadContext, op is the name of the function for
+// error messages.
+func load(ctx context.Context, op, path string) (*Video, error) {
+	if err := checkFFprobe(op); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.New(op + ": unable to load file: " + err.Error())
+	}
+
+	cmd := exec.CommandContext(ctx, "ffprobe", probeArgs(path)...)
+	out, err := cmd.Output()
+
+	if err != nil {
+		return nil, errors.New(op + ": ffprobe failed: " + err.Error())
+	}
+	return describe(op, path, out, func() (time.Duration, error) {
+		return packetDuration(ctx, path)
+	})
+}
+
+// checkFFprobe returns an error for op if ffprobe is not installed.
+func checkFFprobe(op string) error {
 	if _, err := exec.LookPath("ffprobe"); err != nil {
-		return nil, errors.New("cinema.Load: ffprobe was not found in your PATH " +
+		return errors.New(op + ": ffprobe was not found in your PATH " +
 			"environment variable, make sure to install ffmpeg " +
 			"(https://ffmpeg.org/) and add ffmpeg, ffplay and ffprobe to your " +
 			"PATH")
 	}
+	return nil
+}
 
-	if _, err := os.Stat(path); err != nil {
-		return nil, errors.New("cinema.Load: unable to load file: " + err.Error())
-	}
-
-	cmd := exec.Command(
-		"ffprobe",
+// probeArgs returns the arguments of ffprobe that describe the input path
+// for describe.
+func probeArgs(path string) []string {
+	return []string{
 		"-v", "quiet",
 		"-print_format", "json",
 		"-show_format",
 		"-show_streams",
+		"-show_chapters",
 		path,
-	)
-	out, err := cmd.Output()
-
-	if err != nil {
-		return nil, errors.New("cinema.Load: ffprobe failed: " + err.Error())
 	}
+}
 
+// describe creates the Video for the input path from the output of ffprobe.
+// measureDuration is called for inputs that do not store their duration.
+func describe(op, path string, out []byte, measureDuration func() (time.Duration, error)) (*Video, error) {
 	type description struct {
 		Streams []struct {
-			Width  int `json:"width"`
-			Height int `json:"height"`
-			Tags   struct {
+			CodecType     string      `json:"codec_type"`
+			CodecName     string      `json:"codec_name"`
+			Profile       string      `json:"profile"`
+			Level         int         `json:"level"`
+			PixelFormat   string      `json:"pix_fmt"`
+			Width         int         `json:"width"`
+			Height        int         `json:"height"`
+			Channels      int         `json:"channels"`
+			ChannelLayout string      `json:"channel_layout"`
+			SampleRate    json.Number `json:"sample_rate"`
+			DurationSec   json.Number `json:"duration"`
+			RFrameRate    string      `json:"r_frame_rate"`
+			AvgFrameRate  string      `json:"avg_frame_rate"`
+			Tags          struct {
 				// Rotation is optional -> use a pointer.
 				Rotation *json.Number `json:"rotate"`
+				// Duration is set by the Matroska muxer, e.g.
+				// "00:01:02.345000000".
+				Duration string `json:"DURATION"`
 			} `json:"tags"`
 		} `json:"streams"`
 		Format struct {
-			DurationSec json.Number `json:"duration"`
+			FormatName  string            `json:"format_name"`
+			DurationSec json.Number       `json:"duration"`
+			StartSec    json.Number       `json:"start_time"`
+			BitRate     json.Number       `json:"bit_rate"`
+			Tags        map[string]string `json:"tags"`
 		} `json:"format"`
+		Chapters []struct {
+			StartSec json.Number `json:"start_time"`
+			EndSec   json.Number `json:"end_time"`
+			Tags     struct {
+				Title string `json:"title"`
+			} `json:"tags"`
+		} `json:"chapters"`
 	}
 	var desc description
 	if err := json.Unmarshal(out, &desc); err != nil {
-		return nil, errors.New("cinema.Load: unable to parse JSON output " +
+		return nil, errors.New(op + ": unable to parse JSON output " +
 			"from ffprobe: " + err.Error())
 	}
 	if len(desc.Streams) == 0 {
-		return nil, errors.New("cinema.Load: ffprobe does not contain stream " +
+		return nil, errors.New(op + ": ffprobe does not contain stream " +
 			"data, make sure the file " + path + " contains a valid video.")
 	}
+	probe, err := parseProbe(out)
+	if err != nil {
+		return nil, errors.New(op + ": " + err.Error())
+	}
 
-	secs, err := desc.Format.DurationSec.Float64()
+	// Some files, e.g. raw streams or recordings that were not finalized, do
+	// not store their duration in the container. Fall back to the durations
+	// of the streams and finally to reading all packets of the file.
+	duration, err := probeTime(desc.Format.DurationSec)
 	if err != nil {
-		return nil, errors.New("cinema.Load: ffprobe returned invalid duration: " +
+		return nil, errors.New(op + ": ffprobe returned invalid duration: " +
 			err.Error())
 	}
+	if duration <= 0 {
+		for _, s := range desc.Streams {
+			d, err := probeTime(s.DurationSec)
+			if err != nil {
+				return nil, errors.New(op + ": ffprobe returned " +
+					"invalid stream duration: " + err.Error())
+			}
+			if d <= 0 {
+				d = parseClock(s.Tags.Duration)
+			}
+			if d > duration {
+				duration = d
+			}
+		}
+	}
+	if duration <= 0 {
+		duration, err = measureDuration()
+		if err != nil {
+			return nil, errors.New(op + ": unable to determine " +
+				"duration: " + err.Error())
+		}
+	}
 
-	// Round seconds (floating point value) up to time.Duration. seconds will
-	// be >= 0 so adding 0.5 rounds to the right integer Duration value.
-	duration := time.Duration(secs*float64(time.Second) + 0.5)
+	startTime, err := probeTime(desc.Format.StartSec)
+	if err != nil {
+		return nil, errors.New(op + ": ffprobe returned invalid start " +
+			"time: " + err.Error())
+	}
 
 	width := desc.Streams[0].Width
 	height := desc.Streams[0].Height
@@ -96,7 +273,7 @@ func Load(path string) (*Video, error) {
 		// coordinates while cropping etc. works on the rotated dimensions.
 		rotation, err := desc.Streams[0].Tags.Rotation.Int64()
 		if err != nil {
-			return nil, errors.New("cinema.Load: ffprobe returned invalid " +
+			return nil, errors.New(op + ": ffprobe returned invalid " +
 				"rotation: " + err.Error())
 		}
 		flipCount := rotation / 90
@@ -105,6 +282,86 @@ func Load(path string) (*Video, error) {
 		}
 	}
 
+	var bitrate int64
+	if desc.Format.BitRate != "" {
+		bitrate, err = desc.Format.BitRate.Int64()
+		if err != nil {
+			return nil, errors.New(op + ": ffprobe returned invalid " +
+				"bit rate: " + err.Error())
+		}
+	}
+
+	var videoCodec, videoProfile, pixelFormat, frameRate string
+	var videoLevel int
+	var vfr bool
+	for _, s := range desc.Streams {
+		if s.CodecType == "video" {
+			videoCodec = s.CodecName
+			videoProfile = s.Profile
+			videoLevel = s.Level
+			pixelFormat = s.PixelFormat
+			frameRate = s.AvgFrameRate
+			vfr = isVFR(s.RFrameRate, s.AvgFrameRate)
+			break
+		}
+	}
+
+	var channels, sampleRate int
+	var channelLayout, audioCodec string
+	for _, s := range desc.Streams {
+		if s.CodecType == "audio" {
+			audioCodec = s.CodecName
+			channels = s.Channels
+			channelLayout = s.ChannelLayout
+			if s.SampleRate != "" {
+				rate, err := s.SampleRate.Int64()
+				if err != nil {
+					return nil, errors.New(op + ": ffprobe returned " +
+						"invalid sample rate: " + err.Error())
+				}
+				sampleRate = int(rate)
+			}
+			break
+		}
+	}
+
+	var videoStreams, audioStreams int
+	for _, s := range desc.Streams {
+		switch s.CodecType {
+		case "video":
+			videoStreams++
+		case "audio":
+			audioStreams++
+		}
+	}
+
+	var chapters []Chapter
+	for _, c := range desc.Chapters {
+		start, err := c.StartSec.Float64()
+		if err != nil {
+			return nil, errors.New(op + ": ffprobe returned invalid " +
+				"chapter start: " + err.Error())
+		}
+		end, err := c.EndSec.Float64()
+		if err != nil {
+			return nil, errors.New(op + ": ffprobe returned invalid " +
+				"chapter end: " + err.Error())
+		}
+		// Like all times of the Video, chapter times are relative to the
+		// start of the input.
+		chapter := Chapter{
+			Title: c.Tags.Title,
+			Start: time.Duration(start*float64(time.Second)+0.5) - startTime,
+			End:   time.Duration(end*float64(time.Second)+0.5) - startTime,
+		}
+		if chapter.Start < 0 {
+			chapter.Start = 0
+		}
+		if chapter.End > chapter.Start {
+			chapters = append(chapters, chapter)
+		}
+	}
+
 	return &Video{
 		filepath: path,
 		width:    width,
@@ -113,43 +370,155 @@ func Load(path string) (*Video, error) {
 		start:    0,
 		end:      duration,
 		duration: duration,
+		chapters: chapters,
+
+		formatName:    desc.Format.FormatName,
+		formatTags:    desc.Format.Tags,
+		bitrate:       int(bitrate),
+		startTime:     startTime,
+		videoCodec:    videoCodec,
+		videoProfile:  videoProfile,
+		videoLevel:    videoLevel,
+		pixelFormat:   pixelFormat,
+		audioCodec:    audioCodec,
+		audioChannels: channels,
+		channelLayout: channelLayout,
+		sampleRate:    sampleRate,
+		frameRate:     frameRate,
+		vfr:           vfr,
+		videoStreams:  videoStreams,
+		audioStreams:  audioStreams,
+		probe:         probe,
 	}, nil
 }
 
 // Render applies all operations to the Video and creates an output video file
 // of the given name.
 func (v *Video) Render(output string) error {
-	line := v.CommandLine(output)
-	cmd := exec.Command(line[0], line[1:]...)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-
-	err := cmd.Run()
+	if err := v.checkTrim("cinema.Video.Render"); err != nil {
+		return err
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.Render: " + err.Error())
+	}
+	defer cleanup()
+	err = v.runFFmpeg(v.CommandLine(output))
 	if err != nil {
-		return errors.New("cinema.Video.Render: ffmpeg failed: " + err.Error())
+		return ffmpegFailed("cinema.Video.Render", err)
 	}
 	return nil
 }
 
+// run executes the command line, forwarding its output to the standard output
+// and error of this process.
+func run(line []string) error {
+	cmd := command(line)
+	if err := startProcess(cmd, 0); err != nil {
+		return err
+	}
+	return waitProcess(cmd)
+}
+
+// command creates the command for the command line, forwarding its output to
+// the standard output and error of this process. ffmpeg's build banner is
+// turned off so that the output starts with the first diagnostic. It runs in
+// its own process group, see KillAll; start it with startProcess.
+func command(line []string) *exec.Cmd {
+	if line[0] == "ffmpeg" {
+		line = append([]string{line[0], "-hide_banner"}, line[1:]...)
+	}
+	cmd := exec.Command(line[0], line[1:]...)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+	setProcessGroup(cmd)
+	return cmd
+}
+
 // CommandLine returns the command line that will be used to convert the Video
 // if you were to call Render.
 func (v *Video) CommandLine(output string) []string {
-	var filters string
-	if len(v.filters) > 0 {
-		filters = strings.Join(v.filters, ",") + ","
-	}
-	filters += "setsar=1,fps=fps=" + strconv.Itoa(int(v.fps))
+	return v.commandLine(output)
+}
 
-	return []string{
+// commandLine returns the command line for rendering to output with the given
+// additional output options.
+func (v *Video) commandLine(output string, outputArgs ...string) []string {
+	var inputArgs, filterArgs []string
+	if v.preview != nil {
+		inputArgs, filterArgs = v.previewFilterArgs()
+	} else {
+		inputArgs, filterArgs = v.filterArgs(v.hardwareChain(v.chain()))
+	}
+	// The trim is applied to the output, after speed changes.
+	trim := v.trimArgs(v.outputTime(v.start), v.outputTime(v.end))
+	line := []string{
 		"ffmpeg",
 		"-y",
-		"-i", v.filepath,
-		"-ss", strconv.FormatFloat(v.start.Seconds(), 'f', -1, 64),
-		"-t", strconv.FormatFloat((v.end - v.start).Seconds(), 'f', -1, 64),
-		"-vf", filters,
-		"-strict", "-2",
-		output,
 	}
+	line = append(line, v.threadGlobalArgs()...)
+	line = append(line, v.input()...)
+	line = append(line, inputArgs...)
+	line = append(line, v.subtitleInputArgs()...)
+	chapterInput, chapterOutput := v.chapterArgs(
+		1 + countInputs(inputArgs) + len(v.subtitles))
+	line = append(line, chapterInput...)
+	line = append(line, trim...)
+	line = append(line, filterArgs...)
+	maps := v.streamArgs(filterArgs, output)
+	line = append(line, maps...)
+	line = append(line, v.subtitleOutputArgs(1+countInputs(inputArgs),
+		append(append([]string(nil), filterArgs...), maps...), output)...)
+	line = append(line, v.audioArgs()...)
+	line = append(line, v.resamplerArgs()...)
+	line = append(line, v.strictArgs()...)
+	line = append(line, v.audioEncoderArgs(output,
+		append(append([]string(nil), v.outputArgs...), outputArgs...))...)
+	line = append(line, v.hardwareOutputArgs()...)
+	line = append(line, v.encoderArgs()...)
+	line = append(line, v.threadOutputArgs()...)
+	line = append(line, v.cfrArgs()...)
+	line = append(line, v.metadataPolicyArgs(output)...)
+	line = append(line, chapterOutput...)
+	line = append(line, v.outputArgs...)
+	line = append(line, outputArgs...)
+	line = append(line, output)
+	return append(line, v.previewArgs(trim)...)
+}
+
+// chain returns the complete video filter chain in render order.
+func (v *Video) chain() []filter {
+	filters := append(v.sanitizeFilters(), v.reverseFilters()...)
+	filters = append(filters, v.stabilizeFilters()...)
+	filters = append(filters, v.pipeline()...)
+	if color := v.colorFilter(); color != "" {
+		filters = append(filters, filter{stage: StageFX, expr: color})
+	}
+	width, height := v.walkGeometry(filters, nil)
+	for _, f := range v.forensicFilters(width, height) {
+		filters = append(filters, filter{stage: StageFX, expr: f})
+	}
+	if tc := v.timecodeFilter(); tc != "" {
+		filters = append(filters, filter{stage: StageFX, expr: tc})
+	}
+	for _, g := range v.guideFilters() {
+		filters = append(filters, filter{stage: StageFX, expr: g})
+	}
+	// Speed changes come last so that all other filters see the timestamps
+	// of the input video.
+	if ramp := v.rampFilter(); ramp != "" {
+		filters = append(filters, filter{stage: StageTrim, expr: ramp})
+	}
+	if speed := v.speedFilter(); speed != "" {
+		filters = append(filters, filter{stage: StageTrim, expr: speed})
+	}
+	for _, f := range v.fadeFilters("fade") {
+		filters = append(filters, filter{stage: StageFX, expr: f})
+	}
+	return append(filters,
+		filter{stage: StageFX, expr: "setsar=1"},
+		filter{stage: StageFPS, expr: v.fpsFilter()},
+	)
 }
 
 // Trim sets the start and end time of the output video. It is always relative
@@ -157,8 +526,9 @@ func (v *Video) CommandLine(output string) []string {
 // nothing will change.
 func (v *Video) Trim(start, end time.Duration) {
 	if start <= end {
-		v.SetStart(start)
-		v.SetEnd(end)
+		v.record("Trim", start, end)
+		v.setStart(start)
+		v.setEnd(end)
 	}
 }
 
@@ -170,7 +540,12 @@ func (v *Video) Start() time.Duration {
 // SetStart sets the start time of the output video. It is always relative to
 // the original input video.
 func (v *Video) SetStart(start time.Duration) {
-	v.start = v.clampToDuration(start)
+	v.record("SetStart", start)
+	v.setStart(start)
+}
+
+func (v *Video) setStart(start time.Duration) {
+	v.start = v.snapStart(v.clampToDuration(start))
 	if v.start > v.end {
 		// keep c.start <= v.end
 		v.end = v.start
@@ -195,6 +570,11 @@ func (v *Video) End() time.Duration {
 // SetEnd sets the end time of the output video. It is always relative to the
 // original input video.
 func (v *Video) SetEnd(end time.Duration) {
+	v.record("SetEnd", end)
+	v.setEnd(end)
+}
+
+func (v *Video) setEnd(end time.Duration) {
 	v.end = v.clampToDuration(end)
 	if v.end < v.start {
 		// keep c.start <= v.end
@@ -204,35 +584,48 @@ func (v *Video) SetEnd(end time.Duration) {
 
 // SetFPS sets the framerate (frames per second) of the output video.
 func (v *Video) SetFPS(fps int) {
+	v.record("SetFPS", fps)
 	v.fps = fps
+	v.fpsRate = ""
 }
 
 // SetSize sets the width and height of the output video.
 func (v *Video) SetSize(width int, height int) {
-	v.width = width
-	v.height = height
-	v.filters = append(v.filters, fmt.Sprintf("scale=%d:%d", width, height))
+	v.record("SetSize", width, height)
+	v.filters = append(v.filters, filter{
+		stage:  StageScale,
+		kind:   scaleFilter,
+		expr:   fmt.Sprintf("scale=%d:%d", width, height),
+		width:  width,
+		height: height,
+	})
 }
 
-// Width returns the width of the video in pixels.
+// Width returns the width of the video in pixels. It is the same as
+// OutputWidth.
 func (v *Video) Width() int {
-	return v.width
+	return v.OutputWidth()
 }
 
-// Height returns the width of the video in pixels.
+// Height returns the height of the video in pixels. It is the same as
+// OutputHeight.
 func (v *Video) Height() int {
-	return v.height
+	return v.OutputHeight()
 }
 
 // Crop makes the output video a sub-rectangle of the input video. (0,0) is the
 // top-left of the video, x goes right, y goes down.
 func (v *Video) Crop(x, y, width, height int) {
-	v.width = width
-	v.height = height
-	v.filters = append(
-		v.filters,
-		fmt.Sprintf("crop=%d:%d:%d:%d", width, height, x, y),
-	)
+	v.record("Crop", x, y, width, height)
+	v.filters = append(v.filters, filter{
+		stage:  StageCrop,
+		kind:   cropFilter,
+		expr:   fmt.Sprintf("crop=%d:%d:%d:%d", width, height, x, y),
+		x:      x,
+		y:      y,
+		width:  width,
+		height: height,
+	})
 }
 
 // Filepath returns the path of the input video.
@@ -243,11 +636,24 @@ func (v *Video) Filepath() string {
 // Duration returns the duration of the original input video. It does not
 // account for any trim operation (Trim, SetStart, SetEnd).
 // To get the current trimmed duration use
-//     v.End() - v.Start()
+//
+//	v.End() - v.Start()
 func (v *Video) Duration() time.Duration {
 	return v.duration
 }
 
+// IsVFR reports whether the input video has a variable frame rate, i.e. its
+// frames are not evenly spaced in time. This is common for recordings from
+// phones and screen recorders. Timestamps of such videos cannot be converted
+// to frame numbers by multiplying them with the frame rate, and many editors
+// handle them badly; see ForceCFR.
+//
+// Detection is based on the stream headers: a video whose average frame rate
+// differs from its base frame rate is reported as variable.
+func (v *Video) IsVFR() bool {
+	return v.vfr
+}
+
 // Get the set fps of the current video struct
 func (v *Video) FPS() int {
 	return v.fps
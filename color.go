@@ -0,0 +1,123 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// colorSettings are the color adjustments of a Video. The has fields tell
+// whether a setting differs from the neutral value, since 0 is a valid
+// contrast and saturation.
+type colorSettings struct {
+	brightness    float64
+	contrast      float64
+	hasContrast   bool
+	saturation    float64
+	hasSaturation bool
+	gamma         float64
+	hasGamma      bool
+	hue           float64
+}
+
+// SetBrightness brightens or darkens the video. brightness goes from -1
+// (black) over 0 (unchanged) to 1 (white). Like the other color adjustments,
+// it is applied after all other operations, but before burned-in timecodes,
+// guides and watermarks. All color adjustments are combined into a single
+// filter. An error is returned if brightness is out of range.
+func (v *Video) SetBrightness(brightness float64) error {
+	if !inRange(brightness, -1, 1) {
+		return errors.New("cinema.Video.SetBrightness: brightness must be " +
+			"between -1 and 1")
+	}
+	v.record("SetBrightness", brightness)
+	v.color.brightness = brightness
+	return nil
+}
+
+// SetContrast changes the contrast of the video. contrast is a factor from 0
+// (flat gray) over 1 (unchanged) to 3. An error is returned if contrast is out
+// of range.
+func (v *Video) SetContrast(contrast float64) error {
+	if !inRange(contrast, 0, 3) {
+		return errors.New("cinema.Video.SetContrast: contrast must be " +
+			"between 0 and 3")
+	}
+	v.record("SetContrast", contrast)
+	v.color.contrast = contrast
+	v.color.hasContrast = contrast != 1
+	return nil
+}
+
+// SetSaturation changes the color saturation of the video. saturation is a
+// factor from 0 (black and white) over 1 (unchanged) to 3. An error is
+// returned if saturation is out of range.
+func (v *Video) SetSaturation(saturation float64) error {
+	if !inRange(saturation, 0, 3) {
+		return errors.New("cinema.Video.SetSaturation: saturation must be " +
+			"between 0 and 3")
+	}
+	v.record("SetSaturation", saturation)
+	v.color.saturation = saturation
+	v.color.hasSaturation = saturation != 1
+	return nil
+}
+
+// SetGamma applies a gamma correction to the video. Values above 1 brighten
+// the dark parts of the picture while keeping black and white, values below 1
+// darken them. gamma goes from 0.1 to 10, 1 leaves the video unchanged. An
+// error is returned if gamma is out of range.
+func (v *Video) SetGamma(gamma float64) error {
+	if !inRange(gamma, 0.1, 10) {
+		return errors.New("cinema.Video.SetGamma: gamma must be between 0.1 " +
+			"and 10")
+	}
+	v.record("SetGamma", gamma)
+	v.color.gamma = gamma
+	v.color.hasGamma = gamma != 1
+	return nil
+}
+
+// SetHue rotates the hue of all colors of the video by degrees, from -180 to
+// 180. An error is returned if degrees is out of range.
+func (v *Video) SetHue(degrees float64) error {
+	if !inRange(degrees, -180, 180) {
+		return errors.New("cinema.Video.SetHue: degrees must be between -180 " +
+			"and 180")
+	}
+	v.record("SetHue", degrees)
+	v.color.hue = degrees
+	return nil
+}
+
+// colorFilter returns the eq and hue filters of the color adjustments, or the
+// empty string if the colors are not changed.
+func (v *Video) colorFilter() string {
+	c := v.color
+	var eq []string
+	if c.brightness != 0 {
+		eq = append(eq, "brightness="+formatFloat(c.brightness))
+	}
+	if c.hasContrast {
+		eq = append(eq, "contrast="+formatFloat(c.contrast))
+	}
+	if c.hasSaturation {
+		eq = append(eq, "saturation="+formatFloat(c.saturation))
+	}
+	if c.hasGamma {
+		eq = append(eq, "gamma="+formatFloat(c.gamma))
+	}
+	var filters []string
+	if len(eq) > 0 {
+		filters = append(filters, "eq="+strings.Join(eq, ":"))
+	}
+	if c.hue != 0 {
+		filters = append(filters, "hue=h="+formatFloat(c.hue))
+	}
+	return strings.Join(filters, ",")
+}
+
+// inRange reports whether f is a number between min and max.
+func inRange(f, min, max float64) bool {
+	return !math.IsNaN(f) && f >= min && f <= max
+}
@@ -0,0 +1,145 @@
+package cinema
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// Color matching limits: the corrections stay gentle so that they even out
+// the differences between cameras without changing the look of a clip.
+const (
+	// maxBrightnessShift is the largest brightness correction, in the range
+	// of eq's brightness option.
+	maxBrightnessShift = 0.1
+	// maxSaturationScale is the largest factor saturation is changed by, in
+	// either direction.
+	maxSaturationScale = 1.25
+	// maxColorShift is the largest correction of the red, green and blue
+	// midtones, in the range of colorbalance's options.
+	maxColorShift = 0.1
+	// colorSampleLength is the length of the start and the end of the
+	// clips that is analyzed.
+	colorSampleLength = 500 * time.Millisecond
+)
+
+// colorStats are the average color of some frames, each value from 0 to 255.
+type colorStats struct {
+	// r, g and b are the averages of the channels.
+	r, g, b float64
+	// saturation is the average difference between the largest and the
+	// smallest channel of the pixels.
+	saturation float64
+}
+
+// luma returns the brightness of the average color.
+func (s colorStats) luma() float64 {
+	return 0.2126*s.r + 0.7152*s.g + 0.0722*s.b
+}
+
+// matchColor adds a filter to the Video that corrects the brightness,
+// saturation and color cast of the start of its output towards prev, the
+// colors at the end of the previous clip. It does nothing if the differences
+// are too small to notice.
+func (v *Video) matchColor(prev colorStats) error {
+	cur, err := v.colorStats(v.outputTime(v.start), v.outputTime(v.start)+colorSampleLength)
+	if err != nil {
+		return err
+	}
+	brightness := math.Max(-maxBrightnessShift, math.Min(maxBrightnessShift,
+		(prev.luma()-cur.luma())/255))
+	saturation := 1.0
+	// The saturation of nearly gray frames is mostly noise.
+	if cur.saturation > 5 && prev.saturation > 5 {
+		saturation = math.Max(1/maxSaturationScale, math.Min(maxSaturationScale,
+			prev.saturation/cur.saturation))
+	}
+	shift := func(p, c float64) float64 {
+		d := ((p - prev.luma()) - (c - cur.luma())) / 255
+		return math.Max(-maxColorShift, math.Min(maxColorShift, d))
+	}
+	r, g, b := shift(prev.r, cur.r), shift(prev.g, cur.g), shift(prev.b, cur.b)
+
+	var exprs []string
+	if math.Abs(brightness) >= 0.005 || math.Abs(saturation-1) >= 0.02 {
+		exprs = append(exprs, "eq=brightness="+formatFloat(brightness)+
+			":saturation="+formatFloat(saturation))
+	}
+	if math.Abs(r) >= 0.005 || math.Abs(g) >= 0.005 || math.Abs(b) >= 0.005 {
+		exprs = append(exprs, "colorbalance=rm="+formatFloat(r)+
+			":gm="+formatFloat(g)+":bm="+formatFloat(b))
+	}
+	for _, expr := range exprs {
+		v.filters = append(v.filters, filter{stage: StageFX, expr: expr})
+	}
+	return nil
+}
+
+// colorStats returns the average color of the output between the output
+// times from and to, limited to the trimmed range. Fades are left out so that
+// they do not darken the result.
+func (v *Video) colorStats(from, to time.Duration) (colorStats, error) {
+	start, end := v.outputTime(v.start), v.outputTime(v.end)
+	if from < start {
+		from = start
+	}
+	if to > end {
+		to = end
+	}
+	clip := v.snapshot()
+	clip.fadeIn, clip.fadeOut = 0, 0
+	clip.setStart(v.inputTime(from))
+	clip.setEnd(v.inputTime(to))
+	cleanup, err := clip.prepareRender()
+	if err != nil {
+		return colorStats{}, err
+	}
+	defer cleanup()
+	line := clip.commandLine("pipe:1",
+		"-an",
+		"-c:v", "rawvideo",
+		"-pix_fmt", "rgb24",
+		"-f", "rawvideo",
+	)
+
+	cmd := clip.command(line)
+	cmd.Stdout = nil
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return colorStats{}, errors.New("unable to read frames: " + err.Error())
+	}
+	if err := startProcess(cmd, clip.memoryLimit); err != nil {
+		return colorStats{}, errors.New("unable to start ffmpeg: " + err.Error())
+	}
+	var (
+		stats colorStats
+		n     float64
+	)
+	r := bufio.NewReader(stdout)
+	var pixel [3]byte
+	for {
+		if _, err := io.ReadFull(r, pixel[:]); err != nil {
+			break
+		}
+		red, green, blue := float64(pixel[0]), float64(pixel[1]), float64(pixel[2])
+		stats.r += red
+		stats.g += green
+		stats.b += blue
+		stats.saturation += math.Max(red, math.Max(green, blue)) -
+			math.Min(red, math.Min(green, blue))
+		n++
+	}
+	if err := waitProcess(cmd); err != nil {
+		return colorStats{}, errors.New("ffmpeg failed: " + err.Error())
+	}
+	if n == 0 {
+		return colorStats{}, errors.New("no frames decoded")
+	}
+	stats.r /= n
+	stats.g /= n
+	stats.b /= n
+	stats.saturation /= n
+	return stats, nil
+}
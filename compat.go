@@ -0,0 +1,241 @@
+package cinema
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DeviceProfile describes what a kind of playback device can play, see
+// CheckCompatibility. Empty fields mean there is no restriction.
+type DeviceProfile struct {
+	Name string
+	// Formats are the supported container formats, as reported by ffprobe,
+	// e.g. "mp4" or "matroska".
+	Formats []string
+	// VideoCodecs are the supported video codecs, as reported by ffprobe,
+	// e.g. "h264" or "hevc".
+	VideoCodecs []string
+	// Profiles are the supported codec profiles, as reported by ffprobe,
+	// e.g. "High" or "Main 10".
+	Profiles []string
+	// MaxLevels are the highest supported levels per video codec, e.g. 4.1
+	// for "h264".
+	MaxLevels map[string]float64
+	// PixelFormats are the supported pixel formats, e.g. "yuv420p".
+	PixelFormats []string
+	// MaxWidth and MaxHeight limit the resolution in pixels, MaxFrameRate
+	// the frame rate.
+	MaxWidth, MaxHeight int
+	MaxFrameRate        float64
+	// AudioCodecs are the supported audio codecs, as reported by ffprobe,
+	// e.g. "aac" or "opus". MaxAudioChannels limits the number of channels.
+	AudioCodecs      []string
+	MaxAudioChannels int
+}
+
+var (
+	// DeviceIOS are iPhones and iPads from the last years.
+	DeviceIOS = DeviceProfile{
+		Name:             "iOS",
+		Formats:          []string{"mp4", "mov"},
+		VideoCodecs:      []string{"h264", "hevc"},
+		Profiles:         []string{"Constrained Baseline", "Baseline", "Main", "High", "Main 10"},
+		MaxLevels:        map[string]float64{"h264": 5.2, "hevc": 5.1},
+		PixelFormats:     []string{"yuv420p", "yuvj420p", "yuv420p10le"},
+		MaxWidth:         3840,
+		MaxHeight:        2160,
+		MaxFrameRate:     60,
+		AudioCodecs:      []string{"aac", "alac", "mp3", "ac3", "eac3"},
+		MaxAudioChannels: 8,
+	}
+	// DeviceAndroid are Android phones and tablets, including older and
+	// low-end models.
+	DeviceAndroid = DeviceProfile{
+		Name:             "Android",
+		Formats:          []string{"mp4", "webm", "matroska"},
+		VideoCodecs:      []string{"h264", "vp8", "vp9"},
+		Profiles:         []string{"Constrained Baseline", "Baseline", "Main", "High", "Profile 0"},
+		MaxLevels:        map[string]float64{"h264": 4.1},
+		PixelFormats:     []string{"yuv420p", "yuvj420p"},
+		MaxWidth:         1920,
+		MaxHeight:        1080,
+		MaxFrameRate:     60,
+		AudioCodecs:      []string{"aac", "mp3", "opus", "vorbis", "flac"},
+		MaxAudioChannels: 2,
+	}
+	// DeviceSmartTV are smart TVs and set-top boxes with 4K support.
+	DeviceSmartTV = DeviceProfile{
+		Name:             "smart TV",
+		Formats:          []string{"mp4", "mpegts", "matroska"},
+		VideoCodecs:      []string{"h264", "hevc"},
+		Profiles:         []string{"Main", "High", "Main 10"},
+		MaxLevels:        map[string]float64{"h264": 4.1, "hevc": 5.1},
+		PixelFormats:     []string{"yuv420p", "yuv420p10le"},
+		MaxWidth:         3840,
+		MaxHeight:        2160,
+		MaxFrameRate:     60,
+		AudioCodecs:      []string{"aac", "ac3", "eac3", "mp3"},
+		MaxAudioChannels: 6,
+	}
+	// DeviceWeb are current desktop and mobile browsers.
+	DeviceWeb = DeviceProfile{
+		Name:             "web",
+		Formats:          []string{"mp4", "webm"},
+		VideoCodecs:      []string{"h264", "vp9", "av1"},
+		PixelFormats:     []string{"yuv420p"},
+		AudioCodecs:      []string{"aac", "mp3", "opus"},
+		MaxAudioChannels: 2,
+	}
+)
+
+// IssueKind is the kind of problem an Issue reports.
+type IssueKind string
+
+const (
+	// IssueFormat means the container format is not supported.
+	IssueFormat IssueKind = "format"
+	// IssueVideoCodec means the video codec is not supported.
+	IssueVideoCodec IssueKind = "video codec"
+	// IssueProfile means the profile of the video codec is not supported.
+	IssueProfile IssueKind = "profile"
+	// IssueLevel means the level of the video exceeds the decoder of the
+	// device.
+	IssueLevel IssueKind = "level"
+	// IssuePixelFormat means the pixel format, e.g. 4:2:2 or 10 bit, is not
+	// supported.
+	IssuePixelFormat IssueKind = "pixel format"
+	// IssueResolution means the video is too large.
+	IssueResolution IssueKind = "resolution"
+	// IssueFrameRate means the frame rate is too high.
+	IssueFrameRate IssueKind = "frame rate"
+	// IssueAudioCodec means the audio codec is not supported.
+	IssueAudioCodec IssueKind = "audio codec"
+	// IssueAudioChannels means the audio has too many channels.
+	IssueAudioChannels IssueKind = "audio channels"
+)
+
+// Issue is a reason why a device cannot play the input file, see
+// CheckCompatibility.
+type Issue struct {
+	Kind IssueKind
+	// Message describes the problem, e.g. "video codec vp9 is not
+	// supported".
+	Message string
+	// Fix describes the smallest change that solves the problem, in terms
+	// of the methods of Video.
+	Fix string
+	// Work is how much work the fix takes: remuxing, transcoding the audio
+	// or transcoding the video.
+	Work Profile
+}
+
+// CheckCompatibility compares the input file with what the target device can
+// play and returns the problems, in the order of the streams, or nil if the
+// device can play the file as it is. The operations applied to the Video are
+// not taken into account. Use the fixes to decide whether a file needs to be
+// transcoded at all, or whether remuxing or transcoding the audio is enough.
+func (v *Video) CheckCompatibility(target DeviceProfile) []Issue {
+	var issues []Issue
+	add := func(kind IssueKind, work Profile, message, fix string) {
+		issues = append(issues, Issue{
+			Kind:    kind,
+			Message: message,
+			Fix:     fix,
+			Work:    work,
+		})
+	}
+
+	formatOK := len(target.Formats) == 0
+	for _, f := range strings.Split(v.formatName, ",") {
+		formatOK = formatOK || accepts(target.Formats, f)
+	}
+	if !formatOK {
+		add(IssueFormat, ProfileRemux,
+			"container format "+v.formatName+" is not supported",
+			"render into "+target.Formats[0]+" with RenderCompliant, which "+
+				"copies the streams")
+	}
+
+	if v.videoCodec != "" {
+		encoder := "libx264"
+		if accepts(target.VideoCodecs, "hevc") && v.videoCodec == "hevc" {
+			encoder = "libx265"
+		}
+		if !accepts(target.VideoCodecs, v.videoCodec) {
+			add(IssueVideoCodec, ProfileTranscode,
+				"video codec "+v.videoCodec+" is not supported",
+				`SetVideoCodec("`+encoder+`")`)
+		} else {
+			v.checkProfileLevel(target, encoder, add)
+		}
+		if !accepts(target.PixelFormats, v.pixelFormat) {
+			add(IssuePixelFormat, ProfileTranscode,
+				"pixel format "+v.pixelFormat+" is not supported",
+				`SetPixelFormat("`+target.PixelFormats[0]+`")`)
+		}
+		if (target.MaxWidth > 0 && v.width > target.MaxWidth) ||
+			(target.MaxHeight > 0 && v.height > target.MaxHeight) {
+			add(IssueResolution, ProfileTranscode,
+				"resolution "+strconv.Itoa(v.width)+"x"+strconv.Itoa(v.height)+
+					" exceeds "+strconv.Itoa(target.MaxWidth)+"x"+
+					strconv.Itoa(target.MaxHeight),
+				"scale down with SetSize")
+		}
+		if rate := parseRate(v.frameRate); target.MaxFrameRate > 0 &&
+			rate > target.MaxFrameRate+0.01 {
+			add(IssueFrameRate, ProfileTranscode,
+				"frame rate "+formatFloat(rate)+" exceeds "+
+					formatFloat(target.MaxFrameRate),
+				"SetFPS("+formatFloat(target.MaxFrameRate)+")")
+		}
+	}
+
+	if v.audioCodec != "" {
+		if !accepts(target.AudioCodecs, v.audioCodec) {
+			add(IssueAudioCodec, ProfileTranscodeAudio,
+				"audio codec "+v.audioCodec+" is not supported",
+				"transcode the audio to "+target.AudioCodecs[0]+
+					" with RenderCompliant")
+		}
+		if target.MaxAudioChannels > 0 && v.audioChannels > target.MaxAudioChannels {
+			add(IssueAudioChannels, ProfileTranscodeAudio,
+				strconv.Itoa(v.audioChannels)+" audio channels exceed "+
+					strconv.Itoa(target.MaxAudioChannels),
+				"downmix to "+strconv.Itoa(target.MaxAudioChannels)+
+					" channels when transcoding the audio")
+		}
+	}
+	return issues
+}
+
+// checkProfileLevel adds the issues with the profile and level of the video
+// stream. encoder is the encoder to suggest for fixes.
+func (v *Video) checkProfileLevel(target DeviceProfile, encoder string, add func(IssueKind, Profile, string, string)) {
+	if v.videoProfile != "" && len(target.Profiles) > 0 {
+		ok := false
+		for _, p := range target.Profiles {
+			ok = ok || strings.EqualFold(p, v.videoProfile)
+		}
+		if !ok {
+			add(IssueProfile, ProfileTranscode,
+				"profile "+v.videoProfile+" is not supported",
+				`SetVideoCodec("`+encoder+`") with SetProfileLevel`)
+		}
+	}
+	max, ok := target.MaxLevels[v.videoCodec]
+	if !ok || v.videoLevel <= 0 {
+		return
+	}
+	// ffprobe reports H.264 levels times 10 and HEVC levels times 30.
+	level := float64(v.videoLevel) / 10
+	if v.videoCodec == "hevc" {
+		level = float64(v.videoLevel) / 30
+	}
+	if level > max+0.001 {
+		add(IssueLevel, ProfileTranscode,
+			"level "+strconv.FormatFloat(level, 'f', 1, 64)+" exceeds "+
+				strconv.FormatFloat(max, 'f', 1, 64),
+			`SetVideoCodec("`+encoder+`") with SetProfileLevel and a level of `+
+				strconv.FormatFloat(max, 'f', 1, 64))
+	}
+}
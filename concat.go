@@ -0,0 +1,227 @@
+package cinema
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConcatOptions configures ConcatWithOptions. Zero values select the
+// defaults.
+type ConcatOptions struct {
+	// MatchColor evens out the brightness, saturation and color cast of
+	// the videos at the cuts, e.g. of recordings of several cameras: the
+	// start of each video is analyzed and gently corrected towards the end
+	// of the previous one, which is corrected the same way. The first
+	// video is left as is. The videos are always re-encoded.
+	MatchColor bool
+}
+
+// Concat joins the videos back-to-back into output, in order.
+//
+// If none of the videos were edited and all of them have the same codecs,
+// size, frame rate and audio format, e.g. the parts of a split recording, the
+// streams are copied without re-encoding, which is fast and lossless.
+// Otherwise each video is rendered with its operations and normalized to the
+// size, frame rate and pixel aspect ratio of the first one: videos with a
+// different aspect ratio are scaled to fit and padded with black. Videos
+// without audio get silence if any of the others has audio.
+func Concat(videos []*Video, output string) error {
+	return concat("cinema.Concat", videos, output, ConcatOptions{})
+}
+
+// ConcatWithOptions is like Concat but configurable, see ConcatOptions.
+func ConcatWithOptions(videos []*Video, output string, opts ConcatOptions) error {
+	return concat("cinema.ConcatWithOptions", videos, output, opts)
+}
+
+// concat implements Concat and ConcatWithOptions. op is the name of the
+// function for error messages.
+func concat(op string, videos []*Video, output string, opts ConcatOptions) error {
+	if len(videos) == 0 {
+		return errors.New(op + ": no videos given")
+	}
+	for _, v := range videos {
+		if err := v.checkTrim(op); err != nil {
+			return err
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "cinema-concat-")
+	if err != nil {
+		return errors.New(op + ": unable to create temporary " +
+			"directory: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	var paths []string
+	if !opts.MatchColor && concatCompatible(videos) {
+		for _, v := range videos {
+			paths = append(paths, v.filepath)
+		}
+	} else {
+		paths, err = renderNormalized(videos, dir, filepath.Ext(output), opts.MatchColor)
+		if err != nil {
+			return errors.New(op + ": " + err.Error())
+		}
+	}
+
+	list := filepath.Join(dir, "list.txt")
+	if err := writeConcatList(list, paths); err != nil {
+		return errors.New(op + ": unable to write file list: " +
+			err.Error())
+	}
+	line := []string{
+		"ffmpeg",
+		"-y",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", list,
+		"-map", "0",
+		"-c", "copy",
+	}
+	line = append(line, copyArgs()...)
+	if err := run(append(line, output)); err != nil {
+		return errors.New(op + ": ffmpeg failed: " + err.Error())
+	}
+	return nil
+}
+
+// concatCompatible reports whether the input files of the videos can be
+// joined without re-encoding.
+func concatCompatible(videos []*Video) bool {
+	first := videos[0]
+	for _, v := range videos {
+		if len(v.history) > 0 || v.start != 0 || v.end != v.duration {
+			return false
+		}
+		if v.videoCodec != first.videoCodec ||
+			v.pixelFormat != first.pixelFormat ||
+			v.width != first.width ||
+			v.height != first.height ||
+			v.frameRate != first.frameRate ||
+			v.audioCodec != first.audioCodec ||
+			v.audioChannels != first.audioChannels ||
+			v.sampleRate != first.sampleRate {
+			return false
+		}
+	}
+	return true
+}
+
+// renderNormalized renders the videos into dir with the same size, frame
+// rate and stream formats, so that the files can be joined without
+// re-encoding. ext is the file extension of the output, which selects the
+// codecs. If matchColor is set, the colors of each video are matched to the
+// previous one, see ConcatOptions. The paths of the rendered files are
+// returned.
+func renderNormalized(videos []*Video, dir, ext string, matchColor bool) ([]string, error) {
+	first := videos[0]
+	width, height := first.OutputWidth(), first.OutputHeight()
+	sampleRate := 0
+	for _, v := range videos {
+		if v.audioChannels > 0 {
+			sampleRate = v.sampleRate
+			if sampleRate <= 0 {
+				sampleRate = 48000
+			}
+			break
+		}
+	}
+
+	var paths []string
+	// prev are the colors at the end of the previous video, after its
+	// correction.
+	var prev colorStats
+	for i, v := range videos {
+		n := strconv.Itoa(i + 1)
+		clip := v.snapshot()
+		clip.fitExactly(width, height)
+		clip.fps, clip.fpsRate, clip.cfr = first.fps, first.fpsRate, first.cfr
+		if matchColor && i > 0 {
+			if err := clip.matchColor(prev); err != nil {
+				return nil, errors.New("unable to analyze the colors of " +
+					"video " + n + ": " + err.Error())
+			}
+		}
+		if matchColor && i+1 < len(videos) {
+			end := clip.outputTime(clip.end)
+			stats, err := clip.colorStats(end-colorSampleLength, end)
+			if err != nil {
+				return nil, errors.New("unable to analyze the colors of " +
+					"video " + n + ": " + err.Error())
+			}
+			prev = stats
+		}
+		clip.outputArgs = append(clip.outputArgs, "-pix_fmt", "yuv420p")
+		if sampleRate > 0 && v.audioChannels > 0 {
+			clip.outputArgs = append(clip.outputArgs,
+				"-ar", strconv.Itoa(sampleRate),
+				"-ac", "2",
+			)
+		}
+		path := filepath.Join(dir, "clip-"+n+ext)
+		if err := clip.Render(path); err != nil {
+			return nil, errors.New("unable to render video " + n + ": " +
+				err.Error())
+		}
+
+		if sampleRate > 0 && v.audioChannels == 0 {
+			silent := path
+			path = filepath.Join(dir, "clip-"+n+"-audio"+ext)
+			err := run([]string{
+				"ffmpeg",
+				"-y",
+				"-i", silent,
+				"-f", "lavfi",
+				"-i", "anullsrc=r=" + strconv.Itoa(sampleRate) + ":cl=stereo",
+				"-map", "0:v",
+				"-map", "1:a",
+				"-c:v", "copy",
+				"-shortest",
+				path,
+			})
+			if err != nil {
+				return nil, errors.New("unable to add silence to video " + n +
+					": ffmpeg failed: " + err.Error())
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// fitExactly scales the output to fit into width x height, keeping its
+// aspect ratio, and pads it to exactly that size with black bars.
+func (v *Video) fitExactly(width, height int) {
+	w, h := v.OutputWidth(), v.OutputHeight()
+	if w <= 0 || h <= 0 || (w == width && h == height) {
+		return
+	}
+	scale := math.Min(float64(width)/float64(w), float64(height)/float64(h))
+	// Most encoders need even dimensions.
+	w = int(float64(w)*scale) / 2 * 2
+	h = int(float64(h)*scale) / 2 * 2
+	v.SetSize(w, h)
+	if w != width || h != height {
+		v.Pad(width, height, (width-w)/2, (height-h)/2, "black")
+	}
+}
+
+// writeConcatList writes the file list for ffmpeg's concat demuxer.
+func writeConcatList(path string, paths []string) error {
+	var b strings.Builder
+	b.WriteString("ffconcat version 1.0\n")
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		b.WriteString("file '" + strings.Replace(abs, "'", `'\''`, -1) + "'\n")
+	}
+	return ioutil.WriteFile(path, []byte(b.String()), 0644)
+}
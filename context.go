@@ -0,0 +1,57 @@
+package cinema
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// LoadContext is like Load but stops ffprobe and returns an error when ctx is
+// done before the file is probed, e.g. for slow network paths or files
+// without a duration, which have to be read completely.
+func LoadContext(ctx context.Context, path string) (*Video, error) {
+	return load(ctx, "cinema.LoadContext", path)
+}
+
+// RenderContext is like Render but kills ffmpeg when ctx is done before the
+// render is finished, e.g. because it was cancelled or timed out. In that
+// case the partially written output file is removed and the error of ctx is
+// returned as part of the error message.
+func (v *Video) RenderContext(ctx context.Context, output string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.New("cinema.Video.RenderContext: " + err.Error())
+	}
+	if err := v.checkTrim("cinema.Video.RenderContext"); err != nil {
+		return err
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderContext: " + err.Error())
+	}
+	defer cleanup()
+	cmd := v.command(v.CommandLine(output))
+	if err := startProcess(cmd, v.memoryLimit); err != nil {
+		return ffmpegFailed("cinema.Video.RenderContext", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelProcess(cmd)
+		case <-done:
+		}
+	}()
+	err = waitProcess(cmd)
+	close(done)
+
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		os.Remove(output)
+		return errors.New("cinema.Video.RenderContext: render stopped: " +
+			ctxErr.Error())
+	}
+	if err != nil {
+		return ffmpegFailed("cinema.Video.RenderContext", err)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package cinema
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CutlistFormat is a file format for lists of cuts, see ImportCutlist.
+type CutlistFormat int
+
+const (
+	// CutlistCSV is a comma separated list with the columns start, end and
+	// an optional label. Times are in seconds, e.g. "83.5", or in the format
+	// HH:MM:SS.mmm. If the first row is a header, further columns are read
+	// into the metadata of the cuts, with the header as key.
+	CutlistCSV CutlistFormat = iota
+	// CutlistEDL is a CMX3600 edit decision list, see ExportEDL. The source
+	// in and out timecodes of each event are used, at the output frame rate
+	// of the Video. The clip name is used as label, the event number and
+	// reel name are stored in the metadata keys "event" and "reel".
+	CutlistEDL
+)
+
+// Cut is a range of the input video.
+type Cut struct {
+	// Start and End are the times in the input video.
+	Start, End time.Duration
+	// Label describes the cut, e.g. the event it shows.
+	Label string
+	// Metadata are additional tags of the cut, e.g. the scene or the take.
+	Metadata map[string]string
+}
+
+// ImportCutlist reads a list of cuts of the Video in the given format from r,
+// e.g. a spreadsheet exported as CSV. Use RenderCuts to render them.
+func (v *Video) ImportCutlist(r io.Reader, format CutlistFormat) ([]Cut, error) {
+	var cuts []Cut
+	var err error
+	switch format {
+	case CutlistCSV:
+		cuts, err = readCSVCutlist(r)
+	case CutlistEDL:
+		cuts, err = v.readEDLCutlist(r)
+	default:
+		err = errors.New("unknown format " + strconv.Itoa(int(format)))
+	}
+	if err != nil {
+		return nil, errors.New("cinema.Video.ImportCutlist: " + err.Error())
+	}
+	return cuts, nil
+}
+
+// RenderCuts renders one output file per cut. All other operations apply to
+// every file, the trim of the Video is replaced by the cut.
+//
+// The output file names are created from pattern by replacing {n} with the
+// number of the cut, starting at 1 and zero padded to the same width for all
+// cuts, {label} with its label and {key} with the value of the metadata key,
+// e.g. {scene}. Characters that are not allowed in file names are replaced in
+// the values. The label is written into the title metadata of each file,
+// together with the metadata of the cut, so the files can be matched to the
+// events they show. The names of the created files are returned. If some
+// files could not be rendered, the error is an *OutputError, see
+// ContinueOnError.
+func (v *Video) RenderCuts(cuts []Cut, pattern string) ([]string, error) {
+	digits := len(strconv.Itoa(len(cuts)))
+	var outputs []string
+	for i, c := range cuts {
+		n := strconv.Itoa(i + 1)
+		n = strings.Repeat("0", digits-len(n)) + n
+		replace := []string{"{n}", n, "{label}", sanitizeFileName(c.Label)}
+		for key, value := range c.Metadata {
+			replace = append(replace, "{"+key+"}", sanitizeFileName(value))
+		}
+		outputs = append(outputs, strings.NewReplacer(replace...).Replace(pattern))
+	}
+	return v.renderOutputs("cinema.Video.RenderCuts", outputs, func(i int) error {
+		c := cuts[i]
+		clip := v.snapshot()
+		clip.setStart(c.Start)
+		clip.setEnd(c.End)
+		clip.outputArgs = append(clip.outputArgs,
+			metadataArgs(outputs[i], c.Label, c.Metadata)...)
+		return clip.Render(outputs[i])
+	})
+}
+
+// readCSVCutlist reads cuts from CSV, see CutlistCSV.
+func readCSVCutlist(r io.Reader) ([]Cut, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var cuts []Cut
+	var header []string
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			return nil, errors.New("row " + strconv.Itoa(row) + " has less " +
+				"than two columns")
+		}
+		start, ok1 := parseCutTime(record[0])
+		end, ok2 := parseCutTime(record[1])
+		if !ok1 || !ok2 {
+			if row == 1 {
+				header = record
+				continue
+			}
+			return nil, errors.New("row " + strconv.Itoa(row) + " has " +
+				"invalid times")
+		}
+		if end <= start {
+			return nil, errors.New("row " + strconv.Itoa(row) + " ends " +
+				"before it starts")
+		}
+		cut := Cut{Start: start, End: end}
+		if len(record) > 2 {
+			cut.Label = strings.TrimSpace(record[2])
+		}
+		for i := 3; i < len(record) && i < len(header); i++ {
+			key := strings.TrimSpace(header[i])
+			if key == "" {
+				continue
+			}
+			if cut.Metadata == nil {
+				cut.Metadata = make(map[string]string)
+			}
+			cut.Metadata[key] = strings.TrimSpace(record[i])
+		}
+		cuts = append(cuts, cut)
+	}
+	return cuts, nil
+}
+
+// parseCutTime parses a time in seconds or in the format HH:MM:SS.mmm.
+func parseCutTime(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ":") {
+		t := parseClock(s)
+		// parseClock returns 0 for invalid times as well.
+		return t, t > 0 || strings.Trim(s, "0:.") == ""
+	}
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(math.Round(secs * float64(time.Second))), true
+}
+
+// readEDLCutlist reads cuts from a CMX3600 EDL, see CutlistEDL.
+func (v *Video) readEDLCutlist(r io.Reader) ([]Cut, error) {
+	num, den := v.rateFraction()
+	base := int64(math.Round(float64(num) / float64(den)))
+	frameTime := func(frames int64) time.Duration {
+		return time.Duration(math.Round(float64(frames) * float64(den) /
+			float64(num) * float64(time.Second)))
+	}
+
+	var cuts []Cut
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "* FROM CLIP NAME:") && len(cuts) > 0 {
+			cuts[len(cuts)-1].Label = strings.TrimSpace(
+				strings.TrimPrefix(line, "* FROM CLIP NAME:"))
+			continue
+		}
+		// An event line is: number reel track transition [duration]
+		// source-in source-out record-in record-out.
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[0]); err != nil {
+			continue
+		}
+		in, ok1 := parseTimecode(fields[len(fields)-4], base)
+		out, ok2 := parseTimecode(fields[len(fields)-3], base)
+		if !ok1 || !ok2 {
+			return nil, errors.New("event " + fields[0] + " has invalid " +
+				"timecodes")
+		}
+		if out <= in {
+			continue
+		}
+		cuts = append(cuts, Cut{
+			Start: frameTime(in),
+			End:   frameTime(out),
+			Label: fields[0],
+			Metadata: map[string]string{
+				"event": fields[0],
+				"reel":  fields[1],
+			},
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cuts, nil
+}
+
+// parseTimecode parses a timecode HH:MM:SS:FF with base frames per second and
+// returns its frame number. Drop frame timecodes (HH:MM:SS;FF) are counted
+// like non-drop frame ones.
+func parseTimecode(tc string, base int64) (int64, bool) {
+	parts := strings.FieldsFunc(tc, func(r rune) bool {
+		return r == ':' || r == ';' || r == '.'
+	})
+	if len(parts) != 4 {
+		return 0, false
+	}
+	var n [4]int64
+	for i, p := range parts {
+		x, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || x < 0 {
+			return 0, false
+		}
+		n[i] = x
+	}
+	return ((n[0]*60+n[1])*60+n[2])*base + n[3], true
+}
+
+// metadataArgs returns the output arguments that replace the title and
+// chapters of the input with title and add the metadata tags. MP4 and MOV
+// files only store tags other than the standard ones with an extra flag.
+func metadataArgs(output, title string, metadata map[string]string) []string {
+	args := []string{"-map_chapters", "-1"}
+	if title != "" {
+		args = append(args, "-metadata", "title="+title)
+	}
+	keys := sortedKeys(metadata)
+	for _, key := range keys {
+		args = append(args, "-metadata", key+"="+metadata[key])
+	}
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".mp4", ".m4v", ".mov":
+		if len(keys) > 0 {
+			args = append(args, "-movflags", "+use_metadata_tags")
+		}
+	}
+	return args
+}
@@ -0,0 +1,141 @@
+package cinema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DASHOptions configures RenderDASH. Zero values select the defaults.
+type DASHOptions struct {
+	// Renditions are the video representations of the stream. They default
+	// to DefaultRenditions. The audio is encoded once, with the highest
+	// AudioBitrate of the renditions.
+	Renditions []Rendition
+	// SegmentDuration is the target length of the segments. It defaults to
+	// 4 seconds.
+	SegmentDuration time.Duration
+}
+
+// RenderDASH renders the Video as an MPEG-DASH stream for adaptive playback
+// in web players like dash.js and Shaka Player. The manifest "manifest.mpd"
+// and the segments of all representations are written to outputDir. Like
+// with RenderHLS, the representations have their keyframes at the same times
+// and the video is encoded with H.264 unless set with SetVideoCodec. All
+// representations are encoded in a single pass over the input, on the CPU
+// even if SetHardware was used.
+func (v *Video) RenderDASH(outputDir string, opts DASHOptions) error {
+	if opts.SegmentDuration == 0 {
+		opts.SegmentDuration = 4 * time.Second
+	}
+	if opts.SegmentDuration < 0 {
+		return errors.New("cinema.Video.RenderDASH: segment duration must " +
+			"be positive")
+	}
+	renditions, err := v.renditions(opts.Renditions)
+	if err != nil {
+		return errors.New("cinema.Video.RenderDASH: " + err.Error())
+	}
+	if err := v.checkTrim("cinema.Video.RenderDASH"); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return errors.New("cinema.Video.RenderDASH: unable to create " +
+			"directory: " + err.Error())
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderDASH: " + err.Error())
+	}
+	defer cleanup()
+
+	// The filter chain of the Video is applied once and its result is split
+	// into the representations, which needs the frames on the CPU.
+	clip := v.snapshot()
+	clip.hardware = NoHardware
+	inputArgs, graph := clip.filterGraph(clip.chain())
+	var pads, scales []string
+	for i, r := range renditions {
+		n := strconv.Itoa(i)
+		pads = append(pads, "[s"+n+"]")
+		scales = append(scales, "[s"+n+"]"+renditionScale(r)+"[r"+n+"]")
+	}
+	graph += ";[vout]split=" + strconv.Itoa(len(renditions)) +
+		strings.Join(pads, "") + ";" + strings.Join(scales, ";")
+
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, clip.threadGlobalArgs()...)
+	line = append(line, clip.input()...)
+	line = append(line, inputArgs...)
+	line = append(line, clip.trimArgs(clip.outputTime(clip.start), clip.outputTime(clip.end))...)
+	line = append(line, "-filter_complex", graph)
+	for i := range renditions {
+		line = append(line, "-map", "[r"+strconv.Itoa(i)+"]")
+	}
+	audioBitrate := 0
+	if clip.audioChannels > 0 {
+		line = append(line, "-map", "0:a:0")
+		line = append(line, clip.audioArgs()...)
+		line = append(line, clip.resamplerArgs()...)
+		for _, r := range renditions {
+			if r.AudioBitrate > audioBitrate {
+				audioBitrate = r.AudioBitrate
+			}
+		}
+	}
+	line = append(line, clip.strictArgs()...)
+	line = append(line, clip.threadOutputArgs()...)
+	line = append(line, clip.cfrArgs()...)
+
+	if clip.encoder.codec == "" {
+		clip.encoder.codec = "libx264"
+	}
+	clip.encoder.bitrate = 0
+	line = append(line, clip.encoderArgs()...)
+	for i, r := range renditions {
+		line = append(line, "-b:v:"+strconv.Itoa(i), strconv.Itoa(r.VideoBitrate)+"k")
+	}
+	line = append(line,
+		"-force_key_frames", "expr:gte(t,n_forced*"+
+			formatFloat(opts.SegmentDuration.Seconds())+")",
+	)
+	adaptationSets := "id=0,streams=v"
+	if audioBitrate > 0 {
+		line = append(line, "-c:a", "aac", "-b:a", strconv.Itoa(audioBitrate)+"k")
+		adaptationSets += " id=1,streams=a"
+	}
+	line = append(line, clip.outputArgs...)
+	line = append(line,
+		"-f", "dash",
+		"-seg_duration", formatFloat(opts.SegmentDuration.Seconds()),
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-adaptation_sets", adaptationSets,
+		"-init_seg_name", "init-$RepresentationID$.m4s",
+		"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+		filepath.Join(outputDir, "manifest.mpd"),
+	)
+	if err := clip.runFFmpeg(line); err != nil {
+		return ffmpegFailed("cinema.Video.RenderDASH", err)
+	}
+	return nil
+}
+
+// renditionScale returns the filter that scales a frame to the size of the
+// rendition, like renditionClip does.
+func renditionScale(r Rendition) string {
+	switch {
+	case r.Width > 0 && r.Height > 0:
+		w, h := strconv.Itoa(r.Width), strconv.Itoa(r.Height)
+		return "scale=" + w + ":" + h + ":force_original_aspect_ratio=decrease," +
+			"pad=" + w + ":" + h + ":(ow-iw)/2:(oh-ih)/2:black,setsar=1"
+	case r.Width > 0:
+		return "scale=" + strconv.Itoa(r.Width) + ":-2"
+	case r.Height > 0:
+		return "scale=-2:" + strconv.Itoa(r.Height)
+	}
+	return "null"
+}
@@ -0,0 +1,113 @@
+package cinema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// packetDuration determines the duration of the file at path by reading the
+// timestamps of all its packets. This is slow for long files but works when
+// neither the container nor the streams know their duration.
+func packetDuration(ctx context.Context, path string) (time.Duration, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		"ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_entries", "packet=pts_time,dts_time,duration_time",
+		path,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, errors.New("ffprobe failed: " + err.Error())
+	}
+	var desc struct {
+		Packets []struct {
+			PTSTime      json.Number `json:"pts_time"`
+			DTSTime      json.Number `json:"dts_time"`
+			DurationTime json.Number `json:"duration_time"`
+		} `json:"packets"`
+	}
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return 0, errors.New("unable to parse JSON output from ffprobe: " +
+			err.Error())
+	}
+	var end time.Duration
+	for _, p := range desc.Packets {
+		t, err := probeTime(p.PTSTime, p.DTSTime)
+		if err != nil {
+			return 0, errors.New("ffprobe returned invalid timestamp: " +
+				err.Error())
+		}
+		d, err := probeTime(p.DurationTime)
+		if err != nil {
+			return 0, errors.New("ffprobe returned invalid duration: " +
+				err.Error())
+		}
+		if t+d > end {
+			end = t + d
+		}
+	}
+	if end <= 0 {
+		return 0, errors.New("the file contains no timestamps")
+	}
+	return end, nil
+}
+
+// parseClock parses a duration of the form HH:MM:SS.fraction as written by
+// the Matroska muxer. It returns 0 if s is not of this form.
+func parseClock(s string) time.Duration {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	secs, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(math.Round(secs*float64(time.Second)))
+}
+
+// parseRate parses a frame rate fraction as reported by ffprobe, e.g.
+// "30000/1001". It returns 0 for invalid or unknown rates like "0/0".
+func parseRate(rate string) float64 {
+	parts := strings.Split(rate, "/")
+	num, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0
+	}
+	den := 1.0
+	if len(parts) == 2 {
+		den, err = strconv.ParseFloat(parts[1], 64)
+		if err != nil || den == 0 {
+			return 0
+		}
+	}
+	return num / den
+}
+
+// isVFR reports whether a video stream with the base frame rate r and the
+// average frame rate avg has a variable frame rate. For constant frame rates
+// both are the same, apart from rounding of the average.
+func isVFR(r, avg string) bool {
+	base, average := parseRate(r), parseRate(avg)
+	if base == 0 || average == 0 {
+		return false
+	}
+	return math.Abs(base-average) > base*1e-4
+}
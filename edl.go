@@ -0,0 +1,233 @@
+package cinema
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EDLFormat is a file format for edit decision lists, see ExportEDL.
+type EDLFormat int
+
+const (
+	// CMX3600 is the plain text EDL format understood by virtually every
+	// editing application.
+	CMX3600 EDLFormat = iota
+	// FCPXML is the XML format of Final Cut Pro, which is also read by
+	// DaVinci Resolve. It refers to the input by its absolute path.
+	FCPXML
+)
+
+// ExportEDL returns the edit of the Video as an edit decision list in the
+// given format, so that a rough cut made with cinema can be refined in a
+// professional editing application. The list contains a single clip: the
+// trimmed range of the input at the output frame rate (see SetFPS).
+//
+// Only the trim is exported. Filters, overlays and speed changes are not,
+// because the formats cannot describe them.
+func (v *Video) ExportEDL(format EDLFormat) ([]byte, error) {
+	switch format {
+	case CMX3600:
+		return v.cmx3600(), nil
+	case FCPXML:
+		out, err := v.fcpxml()
+		if err != nil {
+			return nil, errors.New("cinema.Video.ExportEDL: unable to create " +
+				"FCPXML: " + err.Error())
+		}
+		return out, nil
+	}
+	return nil, errors.New("cinema.Video.ExportEDL: unknown format " +
+		strconv.Itoa(int(format)))
+}
+
+// cmx3600 returns the edit as CMX3600 EDL.
+func (v *Video) cmx3600() []byte {
+	name := filepath.Base(v.filepath)
+	track := "V"
+	if v.audioChannels > 0 {
+		track = "B"
+	}
+	// Timecodes count frames. By convention, the record timecode of a
+	// program starts at one hour.
+	num, den := v.rateFraction()
+	base := int64(math.Round(float64(num) / float64(den)))
+	in, out := v.frames(v.start), v.frames(v.end)
+	record := 3600 * base
+
+	var b strings.Builder
+	b.WriteString("TITLE: " + strings.TrimSuffix(name, filepath.Ext(name)) + "\n")
+	b.WriteString("FCM: NON-DROP FRAME\n\n")
+	fmt.Fprintf(&b, "001  AX       %-4s C        %s %s %s %s\n",
+		track,
+		edlTimecode(in, base), edlTimecode(out, base),
+		edlTimecode(record, base), edlTimecode(record+out-in, base),
+	)
+	b.WriteString("* FROM CLIP NAME: " + name + "\n")
+	return []byte(b.String())
+}
+
+// edlTimecode formats the frame number as non-drop frame timecode
+// HH:MM:SS:FF with base frames per second.
+func edlTimecode(frames, base int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d:%02d",
+		frames/(base*3600), frames/(base*60)%60, frames/base%60, frames%base)
+}
+
+// frames returns the number of output frames in t.
+func (v *Video) frames(t time.Duration) int64 {
+	num, den := v.rateFraction()
+	return int64(math.Round(t.Seconds() * float64(num) / float64(den)))
+}
+
+// rateFraction returns the output frame rate as a fraction num/den.
+func (v *Video) rateFraction() (num, den int64) {
+	if parts := strings.Split(v.fpsRate, "/"); len(parts) == 2 {
+		n, err1 := strconv.ParseInt(parts[0], 10, 64)
+		d, err2 := strconv.ParseInt(parts[1], 10, 64)
+		if err1 == nil && err2 == nil && n > 0 && d > 0 {
+			return n, d
+		}
+	}
+	if rate := parseRate(v.fpsRate); rate > 0 {
+		return int64(math.Round(rate * 1000)), 1000
+	}
+	if v.fps <= 0 {
+		return 30, 1
+	}
+	return int64(v.fps), 1
+}
+
+// fcpxml returns the edit as FCPXML 1.9.
+func (v *Video) fcpxml() ([]byte, error) {
+	type format struct {
+		ID            string `xml:"id,attr"`
+		FrameDuration string `xml:"frameDuration,attr"`
+		Width         int    `xml:"width,attr"`
+		Height        int    `xml:"height,attr"`
+	}
+	type mediaRep struct {
+		Kind string `xml:"kind,attr"`
+		Src  string `xml:"src,attr"`
+	}
+	type asset struct {
+		ID       string   `xml:"id,attr"`
+		Name     string   `xml:"name,attr"`
+		Start    string   `xml:"start,attr"`
+		Duration string   `xml:"duration,attr"`
+		HasVideo int      `xml:"hasVideo,attr"`
+		HasAudio int      `xml:"hasAudio,attr"`
+		Format   string   `xml:"format,attr"`
+		MediaRep mediaRep `xml:"media-rep"`
+	}
+	type assetClip struct {
+		Ref      string `xml:"ref,attr"`
+		Name     string `xml:"name,attr"`
+		Offset   string `xml:"offset,attr"`
+		Start    string `xml:"start,attr"`
+		Duration string `xml:"duration,attr"`
+		Format   string `xml:"format,attr"`
+	}
+	type sequence struct {
+		Format   string      `xml:"format,attr"`
+		Duration string      `xml:"duration,attr"`
+		TCStart  string      `xml:"tcStart,attr"`
+		Clips    []assetClip `xml:"spine>asset-clip"`
+	}
+	type project struct {
+		Name     string   `xml:"name,attr"`
+		Sequence sequence `xml:"sequence"`
+	}
+	type event struct {
+		Name    string  `xml:"name,attr"`
+		Project project `xml:"project"`
+	}
+	type document struct {
+		XMLName xml.Name `xml:"fcpxml"`
+		Version string   `xml:"version,attr"`
+		Format  format   `xml:"resources>format"`
+		Asset   asset    `xml:"resources>asset"`
+		Event   event    `xml:"library>event"`
+	}
+
+	path, err := filepath.Abs(v.filepath)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(path)
+	hasAudio := 0
+	if v.audioChannels > 0 {
+		hasAudio = 1
+	}
+	length := v.fcpxTime(v.frames(v.end) - v.frames(v.start))
+	// File URLs of Windows paths need a slash before the drive letter.
+	src := filepath.ToSlash(path)
+	if !strings.HasPrefix(src, "/") {
+		src = "/" + src
+	}
+
+	doc := document{
+		Version: "1.9",
+		Format: format{
+			ID:            "r1",
+			FrameDuration: v.fcpxTime(1),
+			Width:         v.width,
+			Height:        v.height,
+		},
+		Asset: asset{
+			ID:       "r2",
+			Name:     name,
+			Start:    "0s",
+			Duration: v.fcpxTime(v.frames(v.duration)),
+			HasVideo: 1,
+			HasAudio: hasAudio,
+			Format:   "r1",
+			MediaRep: mediaRep{
+				Kind: "original-media",
+				Src:  (&url.URL{Scheme: "file", Path: src}).String(),
+			},
+		},
+		Event: event{
+			Name: "cinema",
+			Project: project{
+				Name: strings.TrimSuffix(name, filepath.Ext(name)),
+				Sequence: sequence{
+					Format:   "r1",
+					Duration: length,
+					TCStart:  "0s",
+					Clips: []assetClip{{
+						Ref:      "r2",
+						Name:     name,
+						Offset:   "0s",
+						Start:    v.fcpxTime(v.frames(v.start)),
+						Duration: length,
+						Format:   "r1",
+					}},
+				},
+			},
+		},
+	}
+	out, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	header := xml.Header + "<!DOCTYPE fcpxml>\n\n"
+	return append([]byte(header), append(out, '\n')...), nil
+}
+
+// fcpxTime formats the duration of the number of frames as FCPXML time, a
+// rational number of seconds.
+func (v *Video) fcpxTime(frames int64) string {
+	num, den := v.rateFraction()
+	if frames == 0 {
+		return "0s"
+	}
+	return strconv.FormatInt(frames*den, 10) + "/" +
+		strconv.FormatInt(num, 10) + "s"
+}
@@ -0,0 +1,230 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// encoderSettings are the video encoder options set with SetVideoCodec,
+// SetCRF, SetBitrate, SetPreset and SetPixelFormat.
+type encoderSettings struct {
+	codec       string
+	crf         int
+	hasCRF      bool
+	bitrate     int
+	preset      string
+	pixelFormat string
+	// params are the private options of the encoder, see SetEncoderParams.
+	// The map is replaced, never modified, so copies of the Video can share
+	// it.
+	params map[string]string
+	// tune is the x264 and x265 tuning, keyframes the maximum interval
+	// between keyframes. bitsPerPixel limits the bitrate relative to the
+	// pixels per second if there is no bitrate. They are set by the presets.
+	tune         string
+	keyframes    time.Duration
+	bitsPerPixel float64
+	// profile and level are set with SetProfileLevel.
+	profile string
+	level   string
+}
+
+// paramsOptions are the ffmpeg options that pass private options to the
+// encoders that support them.
+var paramsOptions = map[string]string{
+	"libx264":    "-x264-params",
+	"libx265":    "-x265-params",
+	"libsvtav1":  "-svtav1-params",
+	"libaom-av1": "-aom-params",
+}
+
+// presets are the encoding speed presets of x264 and x265, fastest first.
+var presets = []string{
+	"ultrafast", "superfast", "veryfast", "faster", "fast",
+	"medium", "slow", "slower", "veryslow", "placebo",
+}
+
+// SetVideoCodec sets the ffmpeg video encoder, e.g. "libx264", "libx265" or
+// "libvpx-vp9". By default ffmpeg chooses the encoder based on the output file
+// extension. With SetHardware the codec replaces the GPU encoder, so it must
+// be a hardware encoder like "hevc_nvenc" as well.
+func (v *Video) SetVideoCodec(codec string) {
+	v.record("SetVideoCodec", codec)
+	v.encoder.codec = codec
+}
+
+// SetCRF sets the constant rate factor, which keeps the quality constant and
+// lets the bitrate vary. Lower values give better quality and larger files:
+// 0 is lossless, 23 is the default of H.264 and 51 the worst. VP9 goes up to
+// 63. An error is returned if crf is out of range.
+func (v *Video) SetCRF(crf int) error {
+	if crf < 0 || crf > 63 {
+		return errors.New("cinema.Video.SetCRF: crf must be between 0 and 63")
+	}
+	v.record("SetCRF", crf)
+	v.encoder.crf = crf
+	v.encoder.hasCRF = true
+	return nil
+}
+
+// SetBitrate sets the video bitrate in kilobits per second. Without SetCRF it
+// is the average bitrate of the video. Together with SetCRF it is the maximum
+// bitrate, which keeps the quality constant but limits the bitrate in complex
+// scenes, e.g. for streaming. An error is returned if kbps is not positive.
+func (v *Video) SetBitrate(kbps int) error {
+	if kbps <= 0 {
+		return errors.New("cinema.Video.SetBitrate: bitrate must be positive")
+	}
+	v.record("SetBitrate", kbps)
+	v.encoder.bitrate = kbps
+	return nil
+}
+
+// SetPreset sets the encoding speed preset of x264 and x265, from
+// "ultrafast" to "placebo". Slower presets give smaller files at the same
+// quality. The default is "medium". For VP9 the preset is translated to the
+// corresponding speed setting. An error is returned if preset is unknown.
+func (v *Video) SetPreset(preset string) error {
+	if presetIndex(preset) < 0 {
+		return errors.New("cinema.Video.SetPreset: unknown preset " + preset)
+	}
+	v.record("SetPreset", preset)
+	v.encoder.preset = preset
+	return nil
+}
+
+// SetPixelFormat sets the pixel format of the output video, e.g. "yuv420p"
+// for maximum compatibility or "yuv420p10le" for 10 bit HDR video. By default
+// the encoder chooses the format closest to the input, which many players
+// cannot play for 4:2:2 or 4:4:4 inputs.
+func (v *Video) SetPixelFormat(format string) {
+	v.record("SetPixelFormat", format)
+	v.encoder.pixelFormat = format
+}
+
+// SetEncoderParams sets private options of the video encoder that ffmpeg does
+// not offer as options of its own, e.g. {"aq-mode": "3"} for x264 or
+// {"tune": "0", "film-grain": "8"} for SVT-AV1. They are passed with
+// -x264-params, -x265-params, -svtav1-params or -aom-params, depending on the
+// encoder set with SetVideoCodec or a preset, and ignored for other encoders.
+// The params are merged with those set before, an empty value removes a
+// param. An error is returned if a name or value contains ":" or "=", which
+// separate the params.
+func (v *Video) SetEncoderParams(params map[string]string) error {
+	for name, value := range params {
+		if name == "" || strings.ContainsAny(name+value, ":=") {
+			return errors.New("cinema.Video.SetEncoderParams: invalid param " +
+				name + "=" + value)
+		}
+	}
+	v.record("SetEncoderParams", params)
+	merged := map[string]string{}
+	for name, value := range v.encoder.params {
+		merged[name] = value
+	}
+	for name, value := range params {
+		if value == "" {
+			delete(merged, name)
+		} else {
+			merged[name] = value
+		}
+	}
+	v.encoder.params = merged
+	return nil
+}
+
+// presetIndex returns the position of preset in presets, -1 if it is unknown.
+func presetIndex(preset string) int {
+	for i, p := range presets {
+		if p == preset {
+			return i
+		}
+	}
+	return -1
+}
+
+// encoderArgs returns the output options for the video encoder settings.
+func (v *Video) encoderArgs() []string {
+	e := v.encoder
+	var args []string
+	if e.codec != "" {
+		args = append(args, "-c:v", e.codec)
+	}
+	vp9 := strings.Contains(e.codec, "vp9")
+	if e.hasCRF {
+		args = append(args, "-crf", strconv.Itoa(e.crf))
+	}
+	if e.bitrate == 0 && e.bitsPerPixel > 0 {
+		num, den := v.rateFraction()
+		pixels := float64(v.OutputWidth()*v.OutputHeight()) * float64(num) / float64(den)
+		e.bitrate = int(pixels * e.bitsPerPixel / 1000)
+	}
+	switch {
+	case e.bitrate > 0 && e.hasCRF && !vp9:
+		// x264 and x265 ignore the CRF if there is a bitrate.
+		args = append(args,
+			"-maxrate", strconv.Itoa(e.bitrate)+"k",
+			"-bufsize", strconv.Itoa(2*e.bitrate)+"k",
+		)
+	case e.bitrate > 0:
+		args = append(args, "-b:v", strconv.Itoa(e.bitrate)+"k")
+	case e.hasCRF && vp9:
+		// Without a bitrate of 0, VP9 limits the bitrate of CRF encodes.
+		args = append(args, "-b:v", "0")
+	}
+	if e.preset != "" {
+		if vp9 {
+			// libvpx goes from 0 (slowest) to 5 (fastest) in good quality
+			// mode.
+			speed := 5 - presetIndex(e.preset)*5/(len(presets)-2)
+			if speed < 0 {
+				speed = 0
+			}
+			args = append(args, "-cpu-used", strconv.Itoa(speed))
+		} else {
+			args = append(args, "-preset", e.preset)
+		}
+	}
+	if e.tune != "" && !vp9 {
+		args = append(args, "-tune", e.tune)
+	}
+	if e.profile != "" {
+		args = append(args, "-profile:v", e.profile)
+	}
+	params := e.params
+	if e.level != "" && e.codec == "libx265" {
+		// libx265 ignores -level, it takes the level as a param.
+		params = map[string]string{"level-idc": e.level}
+		for name, value := range e.params {
+			params[name] = value
+		}
+	} else if e.level != "" {
+		args = append(args, "-level:v", e.level)
+	}
+	if option := paramsOptions[e.codec]; option != "" && len(params) > 0 {
+		var list []string
+		for _, name := range sortedKeys(params) {
+			list = append(list, name+"="+params[name])
+		}
+		args = append(args, option, strings.Join(list, ":"))
+	}
+	if e.keyframes > 0 {
+		num, den := v.rateFraction()
+		gop := int64(math.Round(e.keyframes.Seconds() * float64(num) / float64(den)))
+		args = append(args, "-g", strconv.FormatInt(gop, 10))
+	}
+	if e.pixelFormat == "" && e.profile != "" {
+		if e.isHEVC() {
+			e.pixelFormat = hevcProfiles[e.profile]
+		} else {
+			e.pixelFormat = h264Profiles[e.profile]
+		}
+	}
+	if e.pixelFormat != "" {
+		args = append(args, "-pix_fmt", e.pixelFormat)
+	}
+	return args
+}
@@ -0,0 +1,54 @@
+package cinema
+
+import (
+	"errors"
+	"time"
+)
+
+// FadeIn fades the video in from black and the audio in from silence over the
+// first d of the output. The fade follows the trimmed start, so it stays at
+// the beginning of the output when the Video is trimmed afterwards. A
+// duration of 0 removes the fade. An error is returned if d is negative.
+func (v *Video) FadeIn(d time.Duration) error {
+	if d < 0 {
+		return errors.New("cinema.Video.FadeIn: duration must not be negative")
+	}
+	v.record("FadeIn", d)
+	v.fadeIn = d
+	return nil
+}
+
+// FadeOut fades the video out to black and the audio out to silence over the
+// last d of the output. Like FadeIn, it follows the trimmed end. A duration
+// of 0 removes the fade. An error is returned if d is negative.
+func (v *Video) FadeOut(d time.Duration) error {
+	if d < 0 {
+		return errors.New("cinema.Video.FadeOut: duration must not be negative")
+	}
+	v.record("FadeOut", d)
+	v.fadeOut = d
+	return nil
+}
+
+// fadeFilters returns the fade filters with the given name, "fade" or
+// "afade". They see the timestamps after all speed changes, so the times are
+// output times.
+func (v *Video) fadeFilters(name string) []string {
+	start, end := v.outputTime(v.start), v.outputTime(v.end)
+	var filters []string
+	if v.fadeIn > 0 {
+		filters = append(filters, name+"=t=in"+
+			":st="+formatFloat(start.Seconds())+
+			":d="+formatFloat(v.fadeIn.Seconds()))
+	}
+	if v.fadeOut > 0 {
+		from := end - v.fadeOut
+		if from < start {
+			from = start
+		}
+		filters = append(filters, name+"=t=out"+
+			":st="+formatFloat(from.Seconds())+
+			":d="+formatFloat((end-from).Seconds()))
+	}
+	return filters
+}
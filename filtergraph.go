@@ -0,0 +1,226 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Pad is a stream in a FilterGraph: a stream of an input or the output of a
+// filter. The output of a filter can be used only once, use Split to use it
+// several times.
+type Pad struct {
+	label string
+	audio bool
+}
+
+// GraphInput is an input file of a FilterGraph.
+type GraphInput struct {
+	index int
+}
+
+// Video returns the first video stream of the input.
+func (in GraphInput) Video() Pad {
+	return Pad{label: "[" + strconv.Itoa(in.index) + ":v:0]"}
+}
+
+// Audio returns the first audio stream of the input.
+func (in GraphInput) Audio() Pad {
+	return Pad{label: "[" + strconv.Itoa(in.index) + ":a:0]", audio: true}
+}
+
+// FilterGraph builds an ffmpeg -filter_complex graph from several inputs,
+// for compositions a Video cannot express, e.g. picture-in-picture or
+// side-by-side comparisons. Each method adds a filter that reads from the
+// given pads and returns its output pads.
+//
+// Errors, e.g. using the output of a filter twice, are recorded and returned
+// by Args and Render; after the first error, the methods do nothing.
+type FilterGraph struct {
+	inputs  [][]string
+	filters []string
+	pads    int
+	used    map[string]bool
+	err     error
+}
+
+// NewFilterGraph returns an empty FilterGraph.
+func NewFilterGraph() *FilterGraph {
+	return &FilterGraph{used: map[string]bool{}}
+}
+
+// Input adds the file at path as an input. options are ffmpeg input options
+// that are passed before it, e.g. "-ss", "10" to start reading at 10 seconds
+// or "-stream_loop", "-1" to loop it.
+func (g *FilterGraph) Input(path string, options ...string) GraphInput {
+	args := append(append([]string(nil), options...), "-i", path)
+	g.inputs = append(g.inputs, args)
+	return GraphInput{index: len(g.inputs) - 1}
+}
+
+// Filter adds the ffmpeg filter expr, e.g. "hflip" or "scale=640:-2", with
+// the pads as its inputs and returns its output. Use FilterN for filters with
+// several outputs.
+func (g *FilterGraph) Filter(expr string, inputs ...Pad) Pad {
+	outputs := g.FilterN(expr, 1, inputs...)
+	if len(outputs) == 0 {
+		return Pad{}
+	}
+	return outputs[0]
+}
+
+// FilterN adds the ffmpeg filter expr with the pads as its inputs and n video
+// outputs, or audio outputs if the first input is audio.
+func (g *FilterGraph) FilterN(expr string, n int, inputs ...Pad) []Pad {
+	if g.err != nil {
+		return nil
+	}
+	if len(inputs) == 0 {
+		g.err = errors.New("filter " + expr + " has no inputs")
+		return nil
+	}
+	var in string
+	for _, p := range inputs {
+		if p.label == "" {
+			g.err = errors.New("filter " + expr + " reads from an invalid pad")
+			return nil
+		}
+		// Streams of inputs may be read several times, filter outputs
+		// only once.
+		if !strings.Contains(p.label, ":") {
+			if g.used[p.label] {
+				g.err = errors.New("pad " + p.label + " is used twice, use Split")
+				return nil
+			}
+			g.used[p.label] = true
+		}
+		in += p.label
+	}
+	outputs := make([]Pad, n)
+	var out string
+	for i := range outputs {
+		outputs[i] = Pad{label: "[g" + strconv.Itoa(g.pads) + "]", audio: inputs[0].audio}
+		g.pads++
+		out += outputs[i].label
+	}
+	g.filters = append(g.filters, in+expr+out)
+	return outputs
+}
+
+// Split returns n copies of the pad.
+func (g *FilterGraph) Split(in Pad, n int) []Pad {
+	if in.audio {
+		return g.FilterN("asplit="+strconv.Itoa(n), n, in)
+	}
+	return g.FilterN("split="+strconv.Itoa(n), n, in)
+}
+
+// Scale scales the video to width x height pixels. Like in SetSize, -2 keeps
+// the aspect ratio for one of them.
+func (g *FilterGraph) Scale(in Pad, width, height int) Pad {
+	return g.Filter("scale="+strconv.Itoa(width)+":"+strconv.Itoa(height), in)
+}
+
+// Overlay draws top onto base with its top-left corner at (x,y). The output
+// ends with base.
+func (g *FilterGraph) Overlay(base, top Pad, x, y int) Pad {
+	return g.Filter("overlay="+strconv.Itoa(x)+":"+strconv.Itoa(y)+
+		":eof_action=pass", base, top)
+}
+
+// HStack places the videos side by side, from left to right. They must have
+// the same height.
+func (g *FilterGraph) HStack(inputs ...Pad) Pad {
+	return g.Filter("hstack=inputs="+strconv.Itoa(len(inputs)), inputs...)
+}
+
+// VStack places the videos on top of each other, from top to bottom. They
+// must have the same width.
+func (g *FilterGraph) VStack(inputs ...Pad) Pad {
+	return g.Filter("vstack=inputs="+strconv.Itoa(len(inputs)), inputs...)
+}
+
+// Concat plays the segments one after the other. Each segment is a video pad
+// followed by an audio pad if audio is true, e.g. Concat(true, v1, a1, v2,
+// a2). The outputs are the video and, with audio, the audio.
+func (g *FilterGraph) Concat(audio bool, segments ...Pad) []Pad {
+	streams := 1
+	if audio {
+		streams = 2
+	}
+	if g.err == nil && (len(segments) == 0 || len(segments)%streams != 0) {
+		g.err = errors.New("concat needs a video and an audio pad per segment")
+	}
+	if g.err != nil {
+		return nil
+	}
+	outputs := g.FilterN("concat=n="+strconv.Itoa(len(segments)/streams)+
+		":v=1:a="+strconv.Itoa(streams-1), streams, segments...)
+	if len(outputs) == 2 {
+		outputs[1].audio = true
+	}
+	return outputs
+}
+
+// String returns the graph in the syntax of -filter_complex.
+func (g *FilterGraph) String() string {
+	return strings.Join(g.filters, ";")
+}
+
+// Args returns the ffmpeg arguments for the inputs and the graph, which write
+// the output pads into the next output file. An error is returned if the
+// graph has an error.
+func (g *FilterGraph) Args(outputs ...Pad) ([]string, error) {
+	args, err := g.args(outputs)
+	if err != nil {
+		return nil, errors.New("cinema.FilterGraph.Args: " + err.Error())
+	}
+	return args, nil
+}
+
+// args implements Args.
+func (g *FilterGraph) args(outputs []Pad) ([]string, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	if len(g.inputs) == 0 {
+		return nil, errors.New("the graph has no inputs")
+	}
+	var args []string
+	for _, in := range g.inputs {
+		args = append(args, in...)
+	}
+	if len(g.filters) > 0 {
+		args = append(args, "-filter_complex", g.String())
+	}
+	for _, p := range outputs {
+		if p.label == "" {
+			return nil, errors.New("invalid output pad")
+		}
+		label := p.label
+		if strings.Contains(label, ":") {
+			// Streams of inputs are mapped without brackets.
+			label = strings.Trim(label, "[]")
+		}
+		args = append(args, "-map", label)
+	}
+	return args, nil
+}
+
+// Render writes the output pads, usually a video and an audio pad, to output.
+// outputArgs are ffmpeg output options, e.g. "-c:v", "libx264". An error is
+// returned if the graph has an error or ffmpeg fails.
+func (g *FilterGraph) Render(output string, outputs []Pad, outputArgs ...string) error {
+	args, err := g.args(outputs)
+	if err != nil {
+		return errors.New("cinema.FilterGraph.Render: " + err.Error())
+	}
+	line := append([]string{"ffmpeg", "-y"}, args...)
+	line = append(line, outputArgs...)
+	line = append(line, output)
+	if err := run(line); err != nil {
+		return errors.New("cinema.FilterGraph.Render: ffmpeg failed: " +
+			err.Error())
+	}
+	return nil
+}
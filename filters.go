@@ -0,0 +1,222 @@
+package cinema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Stage is a step in the canonical filter pipeline. With canonical ordering
+// enabled (see SetCanonicalOrder) filters are grouped by their stage and the
+// stages are applied in the order trim, crop, scale, fx, fps, no matter in
+// which order the operations were called.
+type Stage int
+
+const (
+	// StageTrim contains filters that cut the timeline.
+	StageTrim Stage = iota
+	// StageCrop contains filters that cut out a part of the frame.
+	StageCrop
+	// StageScale contains filters that resize the frame.
+	StageScale
+	// StageFX contains effects that keep the frame size, e.g. color changes.
+	StageFX
+	// StageFPS contains filters that change the frame rate.
+	StageFPS
+)
+
+// defaultStageOrder is the canonical pipeline order.
+var defaultStageOrder = []Stage{StageTrim, StageCrop, StageScale, StageFX, StageFPS}
+
+// filterKind tells how a filter changes the frame geometry.
+type filterKind int
+
+const (
+	// otherFilter does not change the frame geometry.
+	otherFilter filterKind = iota
+	// cropFilter cuts the width x height rectangle at (x,y) out of the
+	// frame.
+	cropFilter
+	// scaleFilter resizes the frame to width x height.
+	scaleFilter
+	// padFilter places the frame at (x,y) on a width x height canvas.
+	padFilter
+	// rotateRightFilter and rotateLeftFilter rotate the frame by 90 degrees
+	// clockwise and counterclockwise, rotate180Filter by 180 degrees.
+	rotateRightFilter
+	rotateLeftFilter
+	rotate180Filter
+	// hflipFilter and vflipFilter mirror the frame horizontally and
+	// vertically.
+	hflipFilter
+	vflipFilter
+	// fitFilter scales the frame to fit into width x height, keeping the
+	// aspect ratio, and centers it on a canvas of that size. fillFilter
+	// scales the frame to cover width x height and crops the center.
+	fitFilter
+	fillFilter
+)
+
+// filter is a single entry of the video filter chain.
+type filter struct {
+	stage Stage
+	kind  filterKind
+	expr  string
+	// x, y, width and height describe the geometry of crop, scale and pad
+	// filters, see filterKind.
+	x, y          int
+	width, height int
+	// overlay is the second input of an overlay filter, nil for filters
+	// with a single input.
+	overlay *overlaySource
+	// window limits the filter to a range of the output, see ShowBetween.
+	window *timeWindow
+	// outputRelative makes the filter see timestamps that start at 0 at the
+	// start of the output instead of the timestamps of the input.
+	outputRelative bool
+	// text marks filters that draw text, which can be limited to a range of
+	// the output like overlays.
+	text bool
+	// script is a file the filter reads, nil for filters without one.
+	script *scriptFile
+}
+
+// SetCanonicalOrder enables or disables canonical filter ordering. By default
+// filters are applied in the order the operations were called, which means
+// that e.g. cropping after scaling gives a different result than scaling after
+// cropping. With canonical ordering the filters are applied stage by stage, see
+// Stage and SetStageOrder.
+func (v *Video) SetCanonicalOrder(enabled bool) {
+	v.record("SetCanonicalOrder", enabled)
+	v.canonical = enabled
+}
+
+// SetStageOrder overrides the order in which stages are applied when
+// canonical ordering is enabled. Stages that are not listed are applied after
+// the listed ones, in their default order. Calling SetStageOrder without
+// arguments restores the default order.
+func (v *Video) SetStageOrder(stages ...Stage) {
+	v.record("SetStageOrder", stages)
+	v.stageOrder = append([]Stage(nil), stages...)
+}
+
+// AddFilter appends a raw ffmpeg video filter to the operations, e.g.
+// "unsharp=5:5:1.0" or a chain like "eq=gamma=1.2,vignette". It is inserted
+// as is and belongs to StageFX. The package does not know what the filter
+// does, so it must not change the frame size, or OutputWidth, OutputHeight
+// and the operations that depend on them will be wrong.
+func (v *Video) AddFilter(expr string) {
+	v.record("AddFilter", expr)
+	v.filters = append(v.filters, filter{stage: StageFX, expr: expr})
+}
+
+// Filters returns the filters of the operations that were called on the
+// Video, in call order, one expression per operation. Filters the package adds
+// when rendering, e.g. for speed changes, fades and the frame rate, are not
+// included, and canonical ordering may apply them in a different order, see
+// SetCanonicalOrder.
+func (v *Video) Filters() []string {
+	exprs := make([]string, len(v.filters))
+	for i, f := range v.filters {
+		exprs[i] = f.expr
+	}
+	return exprs
+}
+
+// MoveFilter moves the filter at index from of Filters to index to, shifting
+// the filters in between. An error is returned if an index is out of range.
+func (v *Video) MoveFilter(from, to int) error {
+	if from < 0 || from >= len(v.filters) || to < 0 || to >= len(v.filters) {
+		return errors.New("cinema.Video.MoveFilter: index out of range")
+	}
+	v.record("MoveFilter", from, to)
+	filters := append([]filter(nil), v.filters...)
+	f := filters[from]
+	filters = append(filters[:from], filters[from+1:]...)
+	filters = append(filters[:to], append([]filter{f}, filters[to:]...)...)
+	v.filters = filters
+	return nil
+}
+
+// Warnings returns descriptions of problems with the current filter chain,
+// e.g. a crop rectangle that does not fit into the frame it is applied to or
+// an output size that differs from what the operations were called for because
+// canonical ordering rearranged them. An empty result means no problems were
+// found.
+func (v *Video) Warnings() []string {
+	var warnings []string
+	width, height := v.walkGeometry(v.pipeline(), func(f filter, width, height int) {
+		if f.kind == cropFilter && (f.x < 0 || f.y < 0 ||
+			f.width <= 0 || f.height <= 0 ||
+			f.x+f.width > width || f.y+f.height > height) {
+			warnings = append(warnings, fmt.Sprintf(
+				"crop of %dx%d at (%d,%d) does not fit into the %dx%d frame "+
+					"it is applied to",
+				f.width, f.height, f.x, f.y, width, height,
+			))
+		}
+		if f.kind == padFilter && (f.x < 0 || f.y < 0 ||
+			f.x+width > f.width || f.y+height > f.height) {
+			warnings = append(warnings, fmt.Sprintf(
+				"%dx%d frame at (%d,%d) does not fit into the %dx%d padding",
+				width, height, f.x, f.y, f.width, f.height,
+			))
+		}
+	})
+	calledWidth, calledHeight := v.walkGeometry(v.filters, nil)
+	if width != calledWidth || height != calledHeight {
+		warnings = append(warnings, fmt.Sprintf(
+			"filter pipeline produces %dx%d output but the operations were "+
+				"called for %dx%d",
+			width, height, calledWidth, calledHeight,
+		))
+	}
+	return append(warnings, v.reverseWarnings()...)
+}
+
+// pipeline returns the filters in the order they are applied on render. Times
+// of the filters are converted for the current trim, see resolveTiming.
+func (v *Video) pipeline() []filter {
+	filters := append([]filter(nil), v.filters...)
+	if !v.canonical {
+		return v.resolveTiming(filters)
+	}
+	rank := make(map[Stage]int)
+	for i, s := range v.stageOrder {
+		if _, ok := rank[s]; !ok {
+			rank[s] = i
+		}
+	}
+	for _, s := range defaultStageOrder {
+		if _, ok := rank[s]; !ok {
+			rank[s] = len(rank)
+		}
+	}
+	sort.SliceStable(filters, func(i, j int) bool {
+		return rank[filters[i].stage] < rank[filters[j].stage]
+	})
+	return v.resolveTiming(filters)
+}
+
+// scaledSize returns the frame size after scaling a width x height frame to
+// newWidth x newHeight. Like in ffmpeg's scale filter, a negative value keeps
+// the aspect ratio for that dimension.
+func scaledSize(width, height, newWidth, newHeight int) (int, int) {
+	if newWidth < 0 && newHeight < 0 {
+		return width, height
+	}
+	if newWidth < 0 && height != 0 {
+		newWidth = newHeight * width / height
+	}
+	if newHeight < 0 && width != 0 {
+		newHeight = newWidth * height / width
+	}
+	return newWidth, newHeight
+}
+
+// formatFloat formats f for use in ffmpeg arguments, without exponent and
+// with as few digits as necessary.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,122 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// FitStyle is how AutoFit fills the parts of the canvas that the video does
+// not cover.
+type FitStyle int
+
+const (
+	// FitBlur fills the bars with a blurred and enlarged copy of the video,
+	// like social platforms show portrait videos.
+	FitBlur FitStyle = iota
+	// FitPad fills the bars with black.
+	FitPad
+	// FitCrop enlarges the video to cover the whole canvas and crops the
+	// parts that stick out, so there are no bars but the edges are lost.
+	FitCrop
+)
+
+// AutoFit places the video on a canvas of canvasWidth x canvasHeight pixels,
+// e.g. a portrait phone video on a 1920x1080 landscape canvas or the other way
+// round. If the video has the aspect ratio of the canvas, it is simply
+// scaled. Otherwise it is scaled to fit, keeping its aspect ratio, and the
+// bars on the sides or at the top and bottom are filled according to style.
+// An error is returned if the canvas size is not positive or style is
+// unknown.
+func (v *Video) AutoFit(canvasWidth, canvasHeight int, style FitStyle) error {
+	if canvasWidth <= 0 || canvasHeight <= 0 {
+		return errors.New("cinema.Video.AutoFit: canvas size must be positive")
+	}
+	if style < FitBlur || style > FitCrop {
+		return errors.New("cinema.Video.AutoFit: unknown style " +
+			strconv.Itoa(int(style)))
+	}
+	v.record("AutoFit", canvasWidth, canvasHeight, style)
+	width, height := v.OutputWidth(), v.OutputHeight()
+	if width > 0 && height > 0 {
+		aspect := float64(width) / float64(height)
+		canvasAspect := float64(canvasWidth) / float64(canvasHeight)
+		if math.Abs(aspect/canvasAspect-1) < 0.01 {
+			v.filters = append(v.filters, filter{
+				stage:  StageScale,
+				kind:   scaleFilter,
+				expr:   "scale=" + strconv.Itoa(canvasWidth) + ":" + strconv.Itoa(canvasHeight),
+				width:  canvasWidth,
+				height: canvasHeight,
+			})
+			return nil
+		}
+	}
+	v.filters = append(v.filters, v.fitFilter(canvasWidth, canvasHeight, style))
+	return nil
+}
+
+// fitFilter returns the filter that places the video on a width x height
+// canvas in the given style.
+func (v *Video) fitFilter(width, height int, style FitStyle) filter {
+	w, h := strconv.Itoa(width), strconv.Itoa(height)
+	f := filter{stage: StageScale, kind: fitFilter, width: width, height: height}
+	switch style {
+	case FitCrop:
+		f.kind = fillFilter
+		f.expr = "scale=" + w + ":" + h + ":force_original_aspect_ratio=increase," +
+			"crop=" + w + ":" + h + ",setsar=1"
+	case FitPad:
+		f.expr = "scale=" + w + ":" + h + ":force_original_aspect_ratio=decrease," +
+			"pad=" + w + ":" + h + ":(ow-iw)/2:(oh-ih)/2:black,setsar=1"
+	default:
+		// The graph splits the video, so its pads need names that are unique
+		// in the chain.
+		n := strconv.Itoa(len(v.filters))
+		f.expr = "split[fitbg" + n + "][fitfg" + n + "];" +
+			"[fitbg" + n + "]scale=" + w + ":" + h +
+			":force_original_aspect_ratio=increase,crop=" + w + ":" + h +
+			",boxblur=" + strconv.Itoa(blurRadius(width, height)) + ":2" +
+			"[fitbgblur" + n + "];" +
+			"[fitfg" + n + "]scale=" + w + ":" + h +
+			":force_original_aspect_ratio=decrease[fitfgscaled" + n + "];" +
+			"[fitbgblur" + n + "][fitfgscaled" + n + "]overlay=(W-w)/2:(H-h)/2," +
+			"setsar=1"
+	}
+	return f
+}
+
+// blurRadius returns the radius of the background blur for a canvas, which
+// scales with its size so the background looks the same at all resolutions.
+// boxblur limits the radius to half the size of the chroma planes.
+func blurRadius(width, height int) int {
+	short := width
+	if height < short {
+		short = height
+	}
+	radius := short / 40
+	if radius > short/4 {
+		radius = short / 4
+	}
+	if radius < 1 {
+		radius = 1
+	}
+	return radius
+}
+
+// BlurredBackgroundFill fills a canvas of width x height pixels with the
+// video, scaled to fit and keeping its aspect ratio, in front of a blurred
+// copy of itself that is enlarged to cover the whole canvas. It is the usual
+// way to turn a portrait video into a landscape one, or the other way round,
+// without black bars. Unlike AutoFit with FitBlur, the blurred background is
+// used even if the aspect ratios match. An error is returned if the canvas
+// size is not positive.
+func (v *Video) BlurredBackgroundFill(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("cinema.Video.BlurredBackgroundFill: canvas size " +
+			"must be positive")
+	}
+	v.record("BlurredBackgroundFill", width, height)
+	v.filters = append(v.filters, v.fitFilter(width, height, FitBlur))
+	return nil
+}
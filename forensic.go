@@ -0,0 +1,154 @@
+package cinema
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// ForensicWatermarker marks a copy of a video so that it can be traced back to
+// the recipient it was made for. Set one with SetForensicWatermark to render
+// individual screener copies from the same master. PatternWatermark is a
+// ready to use implementation.
+type ForensicWatermarker interface {
+	// Filters returns the video filters that mark the video for the
+	// recipient described by m. They are applied after all other operations,
+	// so they work on the output geometry.
+	Filters(m MarkContext) []string
+}
+
+// MarkContext describes the video a ForensicWatermarker is applied to.
+type MarkContext struct {
+	// Recipient identifies who the copy is made for, e.g. a user ID.
+	Recipient string
+	// Width and Height are the size of the frames in pixels.
+	Width, Height int
+	// Start and End are the section of the input video that is rendered.
+	// Filters see the timestamps of the input video, so time based filter
+	// options should use times in this range.
+	Start, End time.Duration
+}
+
+// SetForensicWatermark marks the output video for the recipient using w. Pass
+// a nil w to remove the mark.
+func (v *Video) SetForensicWatermark(w ForensicWatermarker, recipient string) {
+	v.record("SetForensicWatermark", w, recipient)
+	v.forensic = w
+	v.recipient = recipient
+}
+
+// forensicFilters returns the filters of the forensic watermark, if there is
+// one. width and height are the size of the frames it is applied to.
+func (v *Video) forensicFilters(width, height int) []string {
+	if v.forensic == nil {
+		return nil
+	}
+	return v.forensic.Filters(MarkContext{
+		Recipient: v.recipient,
+		Width:     width,
+		Height:    height,
+		Start:     v.start,
+		End:       v.end,
+	})
+}
+
+// PatternWatermark is a ForensicWatermarker that hides the recipient ID as
+// nearly invisible text at pseudo-random positions and times. The pattern is
+// derived from Seed and the recipient so it can be reproduced with Pattern to
+// check which recipient a leaked copy belongs to.
+type PatternWatermark struct {
+	// Seed makes the pattern unpredictable for anyone who does not know it.
+	Seed int64
+	// Count is the number of marks, it defaults to 20.
+	Count int
+	// Length is how long each mark is visible, it defaults to 2 seconds.
+	Length time.Duration
+	// Opacity of the marks from 0 (invisible) to 1 (opaque). It defaults to
+	// 0.03.
+	Opacity float64
+	// FontSize is the text height in pixels, it defaults to 16.
+	FontSize int
+	// FontFile is the path to the font file to use. If it is empty, ffmpeg
+	// uses its default font, which requires ffmpeg to be built with
+	// fontconfig.
+	FontFile string
+}
+
+// Mark is a single occurrence of a PatternWatermark in the video.
+type Mark struct {
+	// X and Y are the top-left corner of the text.
+	X, Y int
+	// Start and End are the times the mark is visible, in timestamps of the
+	// input video.
+	Start, End time.Duration
+}
+
+// Pattern returns the marks that Filters places for m.
+func (p PatternWatermark) Pattern(m MarkContext) []Mark {
+	p = p.withDefaults()
+	h := fnv.New64a()
+	h.Write([]byte(m.Recipient))
+	r := rand.New(rand.NewSource(p.Seed ^ int64(h.Sum64())))
+
+	// Keep the text inside the frame, assuming characters are about as
+	// wide as they are high.
+	maxX := m.Width - p.FontSize*len(m.Recipient)
+	maxY := m.Height - p.FontSize
+	span := m.End - m.Start - p.Length
+
+	marks := make([]Mark, p.Count)
+	for i := range marks {
+		var mark Mark
+		if maxX > 0 {
+			mark.X = r.Intn(maxX)
+		}
+		if maxY > 0 {
+			mark.Y = r.Intn(maxY)
+		}
+		mark.Start = m.Start
+		if span > 0 {
+			mark.Start += time.Duration(r.Int63n(int64(span)))
+		}
+		mark.End = mark.Start + p.Length
+		marks[i] = mark
+	}
+	return marks
+}
+
+// Filters implements ForensicWatermarker.
+func (p PatternWatermark) Filters(m MarkContext) []string {
+	p = p.withDefaults()
+	var filters []string
+	for _, mark := range p.Pattern(m) {
+		enable := "between(t," + formatFloat(mark.Start.Seconds()) + "," +
+			formatFloat(mark.End.Seconds()) + ")"
+		f := "drawtext=text=" + filterValue(m.Recipient) +
+			":fontsize=" + strconv.Itoa(p.FontSize) +
+			":fontcolor=white@" + formatFloat(p.Opacity) +
+			":x=" + strconv.Itoa(mark.X) +
+			":y=" + strconv.Itoa(mark.Y) +
+			":enable=" + filterValue(enable)
+		if p.FontFile != "" {
+			f += ":fontfile=" + filterValue(p.FontFile)
+		}
+		filters = append(filters, f)
+	}
+	return filters
+}
+
+func (p PatternWatermark) withDefaults() PatternWatermark {
+	if p.Count <= 0 {
+		p.Count = 20
+	}
+	if p.Length <= 0 {
+		p.Length = 2 * time.Second
+	}
+	if p.Opacity <= 0 {
+		p.Opacity = 0.03
+	}
+	if p.FontSize <= 0 {
+		p.FontSize = 16
+	}
+	return p
+}
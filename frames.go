@@ -0,0 +1,159 @@
+package cinema
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// FrameInfo describes a single decoded frame of the input as reported by
+// ffprobe, see ProbeFrames.
+type FrameInfo struct {
+	// Stream is the index of the stream the frame belongs to.
+	Stream int
+	// MediaType is "video" or "audio".
+	MediaType string
+	// PTS is the presentation time of the frame in the input file.
+	PTS time.Duration
+	// Duration is how long the frame is shown, 0 if unknown.
+	Duration time.Duration
+	// KeyFrame reports whether the frame can be decoded on its own.
+	KeyFrame bool
+	// PictType is the picture type of video frames: "I", "P" or "B".
+	PictType string
+	// Size is the size of the compressed frame in bytes.
+	Size int
+}
+
+// ProbeOptions limits which frames ProbeFrames returns.
+type ProbeOptions struct {
+	// Streams is an ffmpeg stream specifier selecting the streams to probe,
+	// e.g. "v:0" for the first video stream or "a" for all audio streams.
+	// It defaults to "v:0".
+	Streams string
+	// Start and End limit the frames to this time range of the input file.
+	// A zero End probes until the end of the file. Note that probing starts
+	// at the key frame before Start, so a few earlier frames may be
+	// returned.
+	Start time.Duration
+	End   time.Duration
+}
+
+// ProbeFrames decodes the input and returns information about each of its
+// frames, e.g. to find key frames or measure the frame rate. This reads the
+// whole selected range of the file, so it takes a while for long inputs;
+// limit it with opts. A nil opts probes all frames of the first video stream.
+//
+// The times refer to the input file and are not affected by trimming or
+// other operations of the Video. Like all times of the Video, they are
+// relative to the start of the input, see StartTimeOffset.
+func (v *Video) ProbeFrames(opts *ProbeOptions) ([]FrameInfo, error) {
+	var o ProbeOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Streams == "" {
+		o.Streams = "v:0"
+	}
+	if o.Start < 0 || (o.End != 0 && o.End <= o.Start) {
+		return nil, errors.New("cinema.Video.ProbeFrames: invalid time range " +
+			o.Start.String() + " to " + o.End.String())
+	}
+	// ffprobe reads intervals in the timestamps of the file.
+	interval := formatFloat((v.startTime + o.Start).Seconds()) + "%"
+	if o.End != 0 {
+		interval += formatFloat((v.startTime + o.End).Seconds())
+	}
+
+	cmd := exec.Command(
+		"ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-select_streams", o.Streams,
+		"-read_intervals", interval,
+		"-show_entries", "frame=stream_index,media_type,key_frame,pict_type,"+
+			"pts_time,best_effort_timestamp_time,duration_time,"+
+			"pkt_duration_time,pkt_size",
+		v.filepath,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.New("cinema.Video.ProbeFrames: ffprobe failed: " +
+			err.Error())
+	}
+
+	var desc struct {
+		Frames []struct {
+			StreamIndex     int         `json:"stream_index"`
+			MediaType       string      `json:"media_type"`
+			KeyFrame        int         `json:"key_frame"`
+			PictType        string      `json:"pict_type"`
+			PTSTime         json.Number `json:"pts_time"`
+			TimestampTime   json.Number `json:"best_effort_timestamp_time"`
+			DurationTime    json.Number `json:"duration_time"`
+			PktDurationTime json.Number `json:"pkt_duration_time"`
+			PktSize         json.Number `json:"pkt_size"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return nil, errors.New("cinema.Video.ProbeFrames: unable to parse " +
+			"JSON output from ffprobe: " + err.Error())
+	}
+
+	frames := make([]FrameInfo, 0, len(desc.Frames))
+	for _, f := range desc.Frames {
+		// Frames without a pts still have a timestamp guessed by ffmpeg. Older
+		// versions of ffprobe only report the duration of the packet.
+		pts, err := probeTime(f.PTSTime, f.TimestampTime)
+		if err != nil {
+			return nil, errors.New("cinema.Video.ProbeFrames: ffprobe " +
+				"returned invalid timestamp: " + err.Error())
+		}
+		duration, err := probeTime(f.DurationTime, f.PktDurationTime)
+		if err != nil {
+			return nil, errors.New("cinema.Video.ProbeFrames: ffprobe " +
+				"returned invalid duration: " + err.Error())
+		}
+		var size int64
+		if f.PktSize != "" {
+			size, err = f.PktSize.Int64()
+			if err != nil {
+				return nil, errors.New("cinema.Video.ProbeFrames: ffprobe " +
+					"returned invalid frame size: " + err.Error())
+			}
+		}
+		pictType := f.PictType
+		if pictType == "?" {
+			pictType = ""
+		}
+		frames = append(frames, FrameInfo{
+			Stream:    f.StreamIndex,
+			MediaType: f.MediaType,
+			PTS:       pts - v.startTime,
+			Duration:  duration,
+			KeyFrame:  f.KeyFrame == 1,
+			PictType:  pictType,
+			Size:      int(size),
+		})
+	}
+	return frames, nil
+}
+
+// probeTime parses the first of the ffprobe times in seconds that is set. It
+// returns 0 if none is set.
+func probeTime(secs ...json.Number) (time.Duration, error) {
+	for _, s := range secs {
+		if s == "" || s == "N/A" {
+			continue
+		}
+		f, err := strconv.ParseFloat(string(s), 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(math.Round(f * float64(time.Second))), nil
+	}
+	return 0, nil
+}
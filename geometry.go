@@ -0,0 +1,177 @@
+package cinema
+
+import (
+	"fmt"
+	"math"
+)
+
+// Pad places the video on a canvas of the given size, filling the rest with
+// color, e.g. "black" or "#00FF00". (x,y) is the position of the video's
+// top-left corner on the canvas.
+func (v *Video) Pad(width, height, x, y int, color string) {
+	v.record("Pad", width, height, x, y, color)
+	v.filters = append(v.filters, filter{
+		stage:  StageScale,
+		kind:   padFilter,
+		expr:   fmt.Sprintf("pad=%d:%d:%d:%d:%s", width, height, x, y, color),
+		x:      x,
+		y:      y,
+		width:  width,
+		height: height,
+	})
+}
+
+// OutputWidth returns the width of the output video in pixels, i.e. the width
+// of the input video after all crop, scale and pad operations were applied in
+// render order.
+func (v *Video) OutputWidth() int {
+	width, _ := v.walkGeometry(v.pipeline(), nil)
+	return width
+}
+
+// OutputHeight returns the height of the output video in pixels, i.e. the
+// height of the input video after all crop, scale and pad operations were
+// applied in render order.
+func (v *Video) OutputHeight() int {
+	_, height := v.walkGeometry(v.pipeline(), nil)
+	return height
+}
+
+// SourceToOutput converts the point (x,y) in the input video to the
+// corresponding point in the output video. Use it to place overlays relative
+// to content of the input video after cropping and resizing. The result may
+// lie outside the output frame if the point was cropped away.
+func (v *Video) SourceToOutput(x, y float64) (float64, float64) {
+	v.walkGeometry(v.pipeline(), func(f filter, width, height int) {
+		x, y = mapToFilterOutput(f, width, height, x, y)
+	})
+	return x, y
+}
+
+// OutputToSource converts the point (x,y) in the output video to the
+// corresponding point in the input video. It is the inverse of SourceToOutput.
+func (v *Video) OutputToSource(x, y float64) (float64, float64) {
+	type step struct {
+		f             filter
+		width, height int
+	}
+	var steps []step
+	v.walkGeometry(v.pipeline(), func(f filter, width, height int) {
+		steps = append(steps, step{f, width, height})
+	})
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		x, y = mapToFilterInput(s.f, s.width, s.height, x, y)
+	}
+	return x, y
+}
+
+// walkGeometry goes through the filters, starting with the input video size,
+// and returns the final frame size. If visit is not nil, it is called for each
+// filter with the size of the frame the filter is applied to.
+func (v *Video) walkGeometry(
+	filters []filter,
+	visit func(f filter, width, height int),
+) (int, int) {
+	width, height := v.width, v.height
+	for _, f := range filters {
+		if visit != nil {
+			visit(f, width, height)
+		}
+		switch f.kind {
+		case cropFilter, padFilter:
+			width, height = f.width, f.height
+		case scaleFilter:
+			width, height = scaledSize(width, height, f.width, f.height)
+		case rotateRightFilter, rotateLeftFilter:
+			width, height = height, width
+		case fitFilter, fillFilter:
+			width, height = f.width, f.height
+		}
+	}
+	return width, height
+}
+
+// mapToFilterOutput converts (x,y) in a width x height frame to the frame that
+// f produces from it.
+func mapToFilterOutput(f filter, width, height int, x, y float64) (float64, float64) {
+	switch f.kind {
+	case cropFilter:
+		return x - float64(f.x), y - float64(f.y)
+	case padFilter:
+		return x + float64(f.x), y + float64(f.y)
+	case scaleFilter:
+		newWidth, newHeight := scaledSize(width, height, f.width, f.height)
+		if width != 0 {
+			x *= float64(newWidth) / float64(width)
+		}
+		if height != 0 {
+			y *= float64(newHeight) / float64(height)
+		}
+	case rotateRightFilter:
+		return float64(height) - y, x
+	case rotateLeftFilter:
+		return y, float64(width) - x
+	case rotate180Filter:
+		return float64(width) - x, float64(height) - y
+	case hflipFilter:
+		return float64(width) - x, y
+	case vflipFilter:
+		return x, float64(height) - y
+	case fitFilter, fillFilter:
+		scale, dx, dy := fitTransform(f, width, height)
+		return x*scale + dx, y*scale + dy
+	}
+	return x, y
+}
+
+// mapToFilterInput is the inverse of mapToFilterOutput.
+func mapToFilterInput(f filter, width, height int, x, y float64) (float64, float64) {
+	switch f.kind {
+	case cropFilter:
+		return x + float64(f.x), y + float64(f.y)
+	case padFilter:
+		return x - float64(f.x), y - float64(f.y)
+	case scaleFilter:
+		newWidth, newHeight := scaledSize(width, height, f.width, f.height)
+		if newWidth != 0 {
+			x *= float64(width) / float64(newWidth)
+		}
+		if newHeight != 0 {
+			y *= float64(height) / float64(newHeight)
+		}
+	case rotateRightFilter:
+		return y, float64(height) - x
+	case rotateLeftFilter:
+		return float64(width) - y, x
+	case rotate180Filter:
+		return float64(width) - x, float64(height) - y
+	case hflipFilter:
+		return float64(width) - x, y
+	case vflipFilter:
+		return x, float64(height) - y
+	case fitFilter, fillFilter:
+		scale, dx, dy := fitTransform(f, width, height)
+		if scale != 0 {
+			return (x - dx) / scale, (y - dy) / scale
+		}
+	}
+	return x, y
+}
+
+// fitTransform returns the scale factor and the offset that a fitFilter or
+// fillFilter applies to a width x height frame.
+func fitTransform(f filter, width, height int) (scale, dx, dy float64) {
+	if width <= 0 || height <= 0 {
+		return 1, 0, 0
+	}
+	sx := float64(f.width) / float64(width)
+	sy := float64(f.height) / float64(height)
+	scale = math.Min(sx, sy)
+	if f.kind == fillFilter {
+		scale = math.Max(sx, sy)
+	}
+	dx = (float64(f.width) - float64(width)*scale) / 2
+	dy = (float64(f.height) - float64(height)*scale) / 2
+	return scale, dx, dy
+}
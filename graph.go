@@ -0,0 +1,93 @@
+package cinema
+
+import (
+	"strconv"
+	"strings"
+)
+
+// overlaySource describes the second input of an overlay filter, i.e. what is
+// drawn on top of the video.
+type overlaySource struct {
+	// path is the file to read the overlay from. It is empty for overlays
+	// that are generated inside the filtergraph, e.g. text.
+	path string
+	// inputOptions are passed to ffmpeg right before the -i of path.
+	inputOptions []string
+	// graph returns the filtergraph fragment that produces the overlay on
+	// the output pad out. in is the input pad of the file or the empty
+	// string if there is no file. width and height are the size of the
+	// frame the overlay is placed on.
+	graph func(in, out string, width, height int) string
+}
+
+// filterArgs returns the ffmpeg arguments for the video filter chain. inputArgs
+// are the additional inputs the filters need and have to come after the
+// video's own input, filterArgs are the output options.
+//
+// A plain chain of filters is passed with -vf. As soon as overlays are
+// involved, the chain is split at each overlay and compiled into a
+// -filter_complex graph.
+func (v *Video) filterArgs(chain []filter) (inputArgs, filterArgs []string) {
+	hasOverlay := false
+	for _, f := range chain {
+		if f.overlay != nil {
+			hasOverlay = true
+		}
+	}
+	if !hasOverlay {
+		exprs := make([]string, len(chain))
+		for i, f := range chain {
+			exprs[i] = f.expr
+		}
+		return nil, []string{"-vf", strings.Join(exprs, ",")}
+	}
+
+	inputArgs, graph := v.filterGraph(chain)
+	return inputArgs, append([]string{
+		"-filter_complex", graph,
+		"-map", "[vout]",
+	}, v.audioMapArgs("0:a?")...)
+}
+
+// filterGraph compiles the chain into a -filter_complex graph that reads the
+// selected video stream, [0:v] by default, and writes the result to the pad
+// [vout]. inputArgs are the additional inputs the graph needs, see
+// filterArgs.
+func (v *Video) filterGraph(chain []filter) (inputArgs []string, graph string) {
+	var (
+		parts    []string
+		segment  []string
+		pads     = "[" + v.videoStream("0:v") + "]"
+		inputs   = 1
+		overlays = 0
+	)
+	v.walkGeometry(chain, func(f filter, width, height int) {
+		if f.overlay == nil {
+			segment = append(segment, f.expr)
+			return
+		}
+		n := strconv.Itoa(overlays)
+		overlays++
+		if len(segment) == 0 {
+			segment = append(segment, "null")
+		}
+		parts = append(parts, pads+strings.Join(segment, ",")+"[v"+n+"]")
+
+		var in string
+		if f.overlay.path != "" {
+			inputArgs = append(inputArgs, f.overlay.inputOptions...)
+			inputArgs = append(inputArgs, "-i", f.overlay.path)
+			in = "[" + strconv.Itoa(inputs) + ":v]"
+			inputs++
+		}
+		parts = append(parts, f.overlay.graph(in, "[o"+n+"]", width, height))
+
+		pads = "[v" + n + "][o" + n + "]"
+		segment = []string{f.expr}
+	})
+	if len(segment) == 0 {
+		segment = append(segment, "null")
+	}
+	parts = append(parts, pads+strings.Join(segment, ",")+"[vout]")
+	return inputArgs, strings.Join(parts, ";")
+}
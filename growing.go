@@ -0,0 +1,80 @@
+package cinema
+
+import (
+	"strconv"
+	"time"
+)
+
+// FollowOptions configures reading an input file that is still being written,
+// see Follow.
+type FollowOptions struct {
+	// IdleTimeout ends the input when no new data was written to the file
+	// for this long. It defaults to 10 seconds.
+	IdleTimeout time.Duration
+	// Tail starts reading this far before the current end of the file
+	// instead of at its beginning, like tail -f. 0 reads the whole file.
+	Tail time.Duration
+}
+
+// Follow makes renders read the input file while it is still being written,
+// e.g. by a recorder, so processing can start before the recording finishes.
+// Reaching the end of the file waits for more data instead of ending the
+// input. The input ends when no new data arrives for opts.IdleTimeout.
+//
+// Unless the Video is trimmed with an explicit end (see SetEnd), the output
+// covers everything that is written to the file until then, not just the
+// duration that was known when loading the file. Use a container that can be
+// read while it is written, e.g. MPEG-TS, Matroska or fragmented MP4.
+//
+// Call Follow with a nil opts to read the file normally again.
+func (v *Video) Follow(opts *FollowOptions) {
+	v.record("Follow", opts)
+	if opts == nil {
+		v.follow = nil
+		return
+	}
+	o := *opts
+	if o.IdleTimeout <= 0 {
+		o.IdleTimeout = 10 * time.Second
+	}
+	v.follow = &o
+}
+
+// following reports whether the input is followed and read until it stops
+// growing, i.e. Follow was called and the end was not trimmed.
+func (v *Video) following() bool {
+	return v.follow != nil && v.end == v.duration
+}
+
+// input returns the ffmpeg arguments for reading the input file.
+func (v *Video) input() []string {
+	return append(v.hardwareInputArgs(), v.inputWith(v.seekArgs())...)
+}
+
+// inputWith returns the ffmpeg arguments for reading the input file like
+// input, but with the input options seek instead of the seek to the trimmed
+// start, for commands that seek to times of their own. seek is not used for
+// followed inputs that are read from their tail. The frames are decoded on
+// the CPU, since these commands filter them there.
+func (v *Video) inputWith(seek []string) []string {
+	args := append(v.threadInputArgs(), v.sanitizeInputArgs()...)
+	if v.inputFormat != "" {
+		args = append(args, "-f", v.inputFormat)
+	}
+	if v.follow == nil {
+		args = append(args, seek...)
+		return append(args, "-i", v.filepath)
+	}
+	args = append(args,
+		"-follow", "1",
+		"-rw_timeout", strconv.FormatInt(v.follow.IdleTimeout.Microseconds(), 10),
+	)
+	if v.follow.Tail > 0 {
+		args = append(args, "-sseof", formatFloat(-v.follow.Tail.Seconds()))
+	} else {
+		args = append(args, seek...)
+	}
+	// The follow option belongs to the file protocol, which has to be
+	// selected explicitly.
+	return append(args, "-i", "file:"+v.filepath)
+}
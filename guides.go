@@ -0,0 +1,58 @@
+package cinema
+
+import "strconv"
+
+// Guides is a set of visual guides that can be drawn on top of the output
+// video, see SetGuides. Combine them with |, e.g.
+//
+//	ThirdsGuide | TitleSafeGuide
+type Guides int
+
+const (
+	// ThirdsGuide draws a rule-of-thirds grid.
+	ThirdsGuide Guides = 1 << iota
+	// ActionSafeGuide draws the action-safe area, the inner 90% of the frame.
+	ActionSafeGuide
+	// TitleSafeGuide draws the title-safe area, the inner 80% of the frame.
+	TitleSafeGuide
+)
+
+// NoGuides disables all guides.
+const NoGuides Guides = 0
+
+// SetGuides draws the given guides on top of the output video. It is meant
+// for preview renders that help positioning overlays and crops before the
+// final export. The guides are drawn after all other filters so they always
+// refer to the output frame.
+func (v *Video) SetGuides(guides Guides) {
+	v.record("SetGuides", guides)
+	v.guides = guides
+}
+
+// Guides returns the guides that are drawn on the output video.
+func (v *Video) Guides() Guides {
+	return v.guides
+}
+
+func (v *Video) guideFilters() []string {
+	var filters []string
+	if v.guides&ThirdsGuide != 0 {
+		filters = append(filters, "drawgrid=w=iw/3:h=ih/3:t=2:c=white@0.6")
+	}
+	if v.guides&ActionSafeGuide != 0 {
+		filters = append(filters, safeAreaBox(90, "yellow@0.8"))
+	}
+	if v.guides&TitleSafeGuide != 0 {
+		filters = append(filters, safeAreaBox(80, "red@0.8"))
+	}
+	return filters
+}
+
+// safeAreaBox returns a drawbox filter outlining the centered area that covers
+// the given percentage of the frame's width and height.
+func safeAreaBox(percent int, color string) string {
+	margin := strconv.Itoa((100 - percent) / 2)
+	size := strconv.Itoa(percent)
+	return "drawbox=x=iw*" + margin + "/100:y=ih*" + margin + "/100" +
+		":w=iw*" + size + "/100:h=ih*" + size + "/100:color=" + color + ":t=2"
+}
@@ -0,0 +1,129 @@
+package cinema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hardware selects a GPU API for decoding, filtering and encoding, see
+// SetHardware.
+type Hardware int
+
+const (
+	// NoHardware does all the work on the CPU. This is the default.
+	NoHardware Hardware = iota
+	// CUDA uses an NVIDIA GPU: NVDEC for decoding, scale_cuda for resizing
+	// and NVENC for encoding H.264.
+	CUDA
+	// VAAPI uses the Video Acceleration API available for Intel and AMD GPUs
+	// on Linux: it decodes, resizes with scale_vaapi and encodes H.264.
+	VAAPI
+)
+
+// defaultVAAPIDevice is the render node of the first GPU on Linux.
+const defaultVAAPIDevice = "/dev/dri/renderD128"
+
+// SetHardware makes renders run the whole pipeline on the GPU: frames are
+// decoded into GPU memory, resized there and encoded from there without being
+// copied to the CPU, which matters a lot for 4K transcodes. device selects the
+// GPU for VAAPI, e.g. "/dev/dri/renderD129"; if empty, the first GPU is used.
+//
+// Only resizing (SetSize) and timing filters run on the GPU. Other operations,
+// e.g. Crop or overlays, are CPU-only; for them the frames are downloaded to
+// the CPU and uploaded again afterwards. This still works but is slower, so
+// keep CPU-only operations next to each other. GPU filtering supports 8 bit
+// video only.
+func (v *Video) SetHardware(hw Hardware, device string) {
+	v.record("SetHardware", hw, device)
+	v.hardware = hw
+	v.hwDevice = device
+}
+
+// hardwareInputArgs returns the input options that decode into GPU memory.
+func (v *Video) hardwareInputArgs() []string {
+	switch v.hardware {
+	case CUDA:
+		return []string{"-hwaccel", "cuda", "-hwaccel_output_format", "cuda"}
+	case VAAPI:
+		return []string{
+			"-hwaccel", "vaapi",
+			"-hwaccel_device", orDefault(v.hwDevice, defaultVAAPIDevice),
+			"-hwaccel_output_format", "vaapi",
+		}
+	}
+	return nil
+}
+
+// hardwareOutputArgs returns the output options that select the GPU encoder,
+// unless another encoder was set with SetVideoCodec.
+func (v *Video) hardwareOutputArgs() []string {
+	if v.encoder.codec != "" {
+		return nil
+	}
+	switch v.hardware {
+	case CUDA:
+		return []string{"-c:v", "h264_nvenc"}
+	case VAAPI:
+		return []string{"-c:v", "h264_vaapi"}
+	}
+	return nil
+}
+
+// hardwareChain converts the filter chain to work on GPU frames. Filters with
+// a GPU version are replaced by it, runs of CPU-only filters are surrounded by
+// a download to and an upload from the CPU. The result ends on the GPU so the
+// hardware encoder can be used.
+func (v *Video) hardwareChain(chain []filter) []filter {
+	if v.hardware == NoHardware {
+		return chain
+	}
+	upload := "hwupload_cuda"
+	if v.hardware == VAAPI {
+		upload = "format=nv12,hwupload"
+	}
+
+	var result []filter
+	onGPU := true
+	for _, f := range chain {
+		gpu, ok := v.gpuFilter(f)
+		if ok && !onGPU {
+			result = append(result, filter{stage: f.stage, expr: upload})
+			onGPU = true
+		}
+		if !ok && onGPU {
+			result = append(result, filter{
+				stage: f.stage,
+				expr:  "hwdownload,format=nv12",
+			})
+			onGPU = false
+		}
+		if ok {
+			f.expr = gpu
+		}
+		result = append(result, f)
+	}
+	if !onGPU {
+		result = append(result, filter{stage: StageFPS, expr: upload})
+	}
+	return result
+}
+
+// gpuFilter returns the GPU version of f, ok is false if f is CPU-only.
+func (v *Video) gpuFilter(f filter) (expr string, ok bool) {
+	if f.overlay != nil {
+		return "", false
+	}
+	if f.kind == scaleFilter {
+		if v.hardware == CUDA {
+			return fmt.Sprintf("scale_cuda=%d:%d", f.width, f.height), true
+		}
+		return fmt.Sprintf("scale_vaapi=w=%d:h=%d", f.width, f.height), true
+	}
+	// These filters only look at timestamps and metadata, not at pixels.
+	for _, prefix := range []string{"setsar=", "fps=", "setpts=", "null"} {
+		if strings.HasPrefix(f.expr, prefix) {
+			return f.expr, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,67 @@
+package cinema
+
+// Operation describes a single edit that was applied to a Video, e.g. a call
+// to Crop or SetSize. Use Video.History to list the operations and Video.Undo
+// to revert them.
+type Operation struct {
+	// Name is the name of the Video method that was called, e.g. "Crop".
+	Name string
+	// Args are the arguments the method was called with, in order.
+	Args []interface{}
+
+	// before is the state of the Video right before the operation was
+	// applied. Undo restores it.
+	before Video
+}
+
+// History returns all operations applied to the Video since it was loaded or
+// last reset, oldest first.
+func (v *Video) History() []Operation {
+	return append([]Operation(nil), v.history...)
+}
+
+// Undo reverts the most recent operation. It does nothing if there are no
+// operations to undo.
+func (v *Video) Undo() {
+	if len(v.history) == 0 {
+		return
+	}
+	last := len(v.history) - 1
+	history := v.history[:last]
+	*v = v.history[last].before
+	v.history = history
+}
+
+// Reset reverts all operations, leaving the Video in the state it was in right
+// after Load.
+func (v *Video) Reset() {
+	if len(v.history) == 0 {
+		return
+	}
+	*v = v.history[0].before
+	v.history = nil
+}
+
+// record adds an operation to the history, remembering the current state so
+// it can be restored by Undo. Call it before changing the Video.
+func (v *Video) record(name string, args ...interface{}) {
+	v.history = append(v.history, Operation{
+		Name:   name,
+		Args:   args,
+		before: v.snapshot(),
+	})
+}
+
+// snapshot returns a copy of the Video that does not share any mutable state
+// with v.
+func (v *Video) snapshot() Video {
+	s := *v
+	s.filters = append([]filter(nil), v.filters...)
+	s.audioFilters = append([]string(nil), v.audioFilters...)
+	s.stageOrder = append([]Stage(nil), v.stageOrder...)
+	s.outputArgs = append([]string(nil), v.outputArgs...)
+	s.subtitles = append([]subtitleTrack(nil), v.subtitles...)
+	s.outputChapters = append([]Chapter(nil), v.outputChapters...)
+	s.history = nil
+	return s
+}
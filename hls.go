@@ -0,0 +1,235 @@
+package cinema
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rendition is one variant of an adaptive stream, see RenderHLS.
+type Rendition struct {
+	// Name is the name of the directory of the rendition. It defaults to
+	// the height, e.g. "720p".
+	Name string
+	// Width and Height are the size of the rendition in pixels. If only one
+	// of them is set, the other one keeps the aspect ratio of the Video. If
+	// both are set, the video is scaled to fit and padded with black. If
+	// none is set, the size of the Video is kept.
+	Width, Height int
+	// VideoBitrate is the average video bitrate in kilobits per second.
+	VideoBitrate int
+	// AudioBitrate is the AAC audio bitrate in kilobits per second. It
+	// defaults to 128.
+	AudioBitrate int
+}
+
+// DefaultRenditions is a bitrate ladder for H.264 web streaming. Renditions
+// that are larger than the video are skipped.
+var DefaultRenditions = []Rendition{
+	{Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+	{Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Height: 480, VideoBitrate: 1400, AudioBitrate: 128},
+	{Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+}
+
+// HLSPlaylistType is the type of the media playlists of an HLS stream.
+type HLSPlaylistType int
+
+const (
+	// HLSVOD marks the playlists as complete, so players can seek anywhere.
+	HLSVOD HLSPlaylistType = iota
+	// HLSEvent marks the playlists as growing, for streams that are
+	// published while they are rendered.
+	HLSEvent
+)
+
+// HLSOptions configures RenderHLS. Zero values select the defaults.
+type HLSOptions struct {
+	// Renditions are the variants of the stream. They default to
+	// DefaultRenditions.
+	Renditions []Rendition
+	// SegmentDuration is the target length of the segments. It defaults to
+	// 6 seconds.
+	SegmentDuration time.Duration
+	// PlaylistType defaults to HLSVOD.
+	PlaylistType HLSPlaylistType
+	// FMP4 writes fragmented MP4 segments instead of MPEG-TS, which is
+	// needed for HEVC and shares the segments with DASH players.
+	FMP4 bool
+}
+
+// RenderHLS renders the Video as an HLS stream for adaptive playback in
+// browsers and on mobile devices. Each rendition is written to its own
+// directory in outputDir with a playlist "index.m3u8" and its segments, and
+// the master playlist "master.m3u8" in outputDir lists all renditions. The
+// renditions have their keyframes at the same times, so that players can
+// switch between them at every segment. The video is encoded with H.264
+// unless set with SetVideoCodec.
+//
+// The paths of the created playlists are returned, the master playlist
+// first. If some renditions could not be rendered, the error is an
+// *OutputError and the master playlist lists the others, see
+// ContinueOnError.
+func (v *Video) RenderHLS(outputDir string, opts HLSOptions) ([]string, error) {
+	if opts.SegmentDuration == 0 {
+		opts.SegmentDuration = 6 * time.Second
+	}
+	if opts.SegmentDuration < 0 {
+		return nil, errors.New("cinema.Video.RenderHLS: segment duration " +
+			"must be positive")
+	}
+	if opts.PlaylistType != HLSVOD && opts.PlaylistType != HLSEvent {
+		return nil, errors.New("cinema.Video.RenderHLS: unknown playlist " +
+			"type " + strconv.Itoa(int(opts.PlaylistType)))
+	}
+	renditions, err := v.renditions(opts.Renditions)
+	if err != nil {
+		return nil, errors.New("cinema.Video.RenderHLS: " + err.Error())
+	}
+	if err := v.checkTrim("cinema.Video.RenderHLS"); err != nil {
+		return nil, err
+	}
+
+	playlistType, segmentExt := "vod", ".ts"
+	if opts.PlaylistType == HLSEvent {
+		playlistType = "event"
+	}
+	if opts.FMP4 {
+		segmentExt = ".m4s"
+	}
+	var playlists []string
+	for _, r := range renditions {
+		playlists = append(playlists, filepath.Join(outputDir, r.Name, "index.m3u8"))
+	}
+	clips := make([]Video, len(renditions))
+	created, err := v.renderOutputs("cinema.Video.RenderHLS", playlists, func(i int) error {
+		r := renditions[i]
+		dir := filepath.Join(outputDir, r.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.New("unable to create directory: " + err.Error())
+		}
+		clips[i] = v.renditionClip(r, opts.SegmentDuration)
+		clip := &clips[i]
+		clip.outputArgs = append(clip.outputArgs,
+			"-f", "hls",
+			"-hls_time", formatFloat(opts.SegmentDuration.Seconds()),
+			"-hls_playlist_type", playlistType,
+			"-hls_segment_filename", filepath.Join(dir, "segment-%05d"+segmentExt),
+		)
+		if opts.FMP4 {
+			clip.outputArgs = append(clip.outputArgs,
+				"-hls_segment_type", "fmp4",
+				"-hls_fmp4_init_filename", "init.mp4",
+			)
+		}
+		return clip.Render(playlists[i])
+	})
+
+	var lines []string
+	version := "3"
+	if opts.FMP4 {
+		version = "7"
+	}
+	lines = append(lines, "#EXTM3U", "#EXT-X-VERSION:"+version)
+	for i, r := range renditions {
+		if !contains(created, playlists[i]) {
+			continue
+		}
+		bandwidth := (r.VideoBitrate + r.AudioBitrate) * 1000
+		lines = append(lines,
+			"#EXT-X-STREAM-INF:BANDWIDTH="+strconv.Itoa(bandwidth)+
+				",RESOLUTION="+strconv.Itoa(clips[i].OutputWidth())+"x"+
+				strconv.Itoa(clips[i].OutputHeight()),
+			r.Name+"/index.m3u8",
+		)
+	}
+	if len(created) == 0 {
+		return nil, err
+	}
+	master := filepath.Join(outputDir, "master.m3u8")
+	werr := ioutil.WriteFile(master, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if werr != nil {
+		return created, errors.New("cinema.Video.RenderHLS: unable to write " +
+			"master playlist: " + werr.Error())
+	}
+	return append([]string{master}, created...), err
+}
+
+// renditions returns the renditions with their defaults, or
+// DefaultRenditions that fit into the Video if there are none.
+func (v *Video) renditions(renditions []Rendition) ([]Rendition, error) {
+	if len(renditions) == 0 {
+		for _, r := range DefaultRenditions {
+			if r.Height <= v.OutputHeight() {
+				renditions = append(renditions, r)
+			}
+		}
+		if len(renditions) == 0 {
+			renditions = DefaultRenditions[len(DefaultRenditions)-1:]
+		}
+	}
+	var result []Rendition
+	names := map[string]bool{}
+	for _, r := range renditions {
+		if r.Width < 0 || r.Height < 0 {
+			return nil, errors.New("rendition size must not be negative")
+		}
+		if r.VideoBitrate <= 0 || r.AudioBitrate < 0 {
+			return nil, errors.New("rendition bitrates must be positive")
+		}
+		if r.AudioBitrate == 0 {
+			r.AudioBitrate = 128
+		}
+		if r.Name == "" {
+			height := r.Height
+			if height == 0 {
+				height = v.OutputHeight()
+			}
+			r.Name = strconv.Itoa(height) + "p"
+		}
+		if names[r.Name] {
+			return nil, errors.New("rendition name " + r.Name + " is used twice")
+		}
+		names[r.Name] = true
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// renditionClip returns a copy of the Video that renders the rendition with
+// keyframes at every segment boundary.
+func (v *Video) renditionClip(r Rendition, segment time.Duration) Video {
+	clip := v.snapshot()
+	switch {
+	case r.Width > 0 && r.Height > 0:
+		clip.fitExactly(r.Width, r.Height)
+	case r.Width > 0:
+		clip.SetSize(r.Width, -2)
+	case r.Height > 0:
+		clip.SetSize(-2, r.Height)
+	}
+	if clip.encoder.codec == "" && clip.hardware == NoHardware {
+		clip.encoder.codec = "libx264"
+	}
+	clip.encoder.bitrate = r.VideoBitrate
+	clip.outputArgs = append(clip.outputArgs,
+		"-force_key_frames", "expr:gte(t,n_forced*"+formatFloat(segment.Seconds())+")",
+		"-c:a", "aac",
+		"-b:a", strconv.Itoa(r.AudioBitrate)+"k",
+	)
+	return clip
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
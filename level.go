@@ -0,0 +1,154 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// codecLevel describes the limits of a level of H.264 or HEVC. For H.264 the
+// sizes are in macroblocks of 16x16 pixels, for HEVC in luma samples.
+type codecLevel struct {
+	name           string
+	maxFrameSize   int64
+	maxSampleRate  int64
+	macroblockUnit bool
+}
+
+// h264Levels are the levels of H.264 with the maximum frame size and
+// macroblock rate from table A-1 of the specification.
+var h264Levels = []codecLevel{
+	{"1", 99, 1485, true},
+	{"1b", 99, 1485, true},
+	{"1.1", 396, 3000, true},
+	{"1.2", 396, 6000, true},
+	{"1.3", 396, 11880, true},
+	{"2", 396, 11880, true},
+	{"2.1", 792, 19800, true},
+	{"2.2", 1620, 20250, true},
+	{"3", 1620, 40500, true},
+	{"3.1", 3600, 108000, true},
+	{"3.2", 5120, 216000, true},
+	{"4", 8192, 245760, true},
+	{"4.1", 8192, 245760, true},
+	{"4.2", 8704, 522240, true},
+	{"5", 22080, 589824, true},
+	{"5.1", 36864, 983040, true},
+	{"5.2", 36864, 2073600, true},
+	{"6", 139264, 4177920, true},
+	{"6.1", 139264, 8355840, true},
+	{"6.2", 139264, 16711680, true},
+}
+
+// hevcLevels are the levels of HEVC with the maximum picture size and luma
+// sample rate from table A.8 of the specification.
+var hevcLevels = []codecLevel{
+	{"1", 36864, 552960, false},
+	{"2", 122880, 3686400, false},
+	{"2.1", 245760, 7372800, false},
+	{"3", 552960, 16588800, false},
+	{"3.1", 983040, 33177600, false},
+	{"4", 2228224, 66846720, false},
+	{"4.1", 2228224, 133693440, false},
+	{"5", 8912896, 267386880, false},
+	{"5.1", 8912896, 534773760, false},
+	{"5.2", 8912896, 1069547520, false},
+	{"6", 35651584, 1069547520, false},
+	{"6.1", 35651584, 2139095040, false},
+	{"6.2", 35651584, 4278190080, false},
+}
+
+// h264Profiles and hevcProfiles map the supported profiles to the pixel
+// format they are used with by default.
+var (
+	h264Profiles = map[string]string{
+		"baseline": "yuv420p",
+		"main":     "yuv420p",
+		"high":     "yuv420p",
+		"high10":   "yuv420p10le",
+		"high422":  "yuv422p",
+		"high444":  "yuv444p",
+	}
+	hevcProfiles = map[string]string{
+		"main":   "yuv420p",
+		"main10": "yuv420p10le",
+	}
+)
+
+// SetProfileLevel restricts the encoding to a profile and level of H.264 or
+// HEVC, e.g. "high" and "4.1" for most TVs and set-top boxes or "main10" and
+// "5.1" for HDR streams. Devices reject streams above the level their decoder
+// supports. HEVC is used if it was selected with SetVideoCodec, e.g.
+// "libx265". Unless a pixel format was set, the format of the profile is
+// used, e.g. 10 bit for "high10" and "main10".
+//
+// An error is returned if the profile or level is unknown, or if the current
+// output size or frame rate exceed the limits of the level. Operations that
+// change them later are not checked.
+func (v *Video) SetProfileLevel(profile, level string) error {
+	hevc := v.encoder.isHEVC()
+	profiles, levels, codec := h264Profiles, h264Levels, "H.264"
+	if hevc {
+		profiles, levels, codec = hevcProfiles, hevcLevels, "HEVC"
+	}
+	if _, ok := profiles[profile]; !ok {
+		if _, ok := hevcProfiles[profile]; ok && !hevc {
+			return errors.New("cinema.Video.SetProfileLevel: profile " +
+				profile + " needs an HEVC encoder, see SetVideoCodec")
+		}
+		return errors.New("cinema.Video.SetProfileLevel: unknown " + codec +
+			" profile " + profile)
+	}
+	level = strings.TrimSuffix(level, ".0")
+	if len(level) == 2 && level != "1b" {
+		// ffmpeg also accepts levels without the dot, e.g. "41".
+		level = level[:1] + "." + level[1:]
+	}
+	var limits *codecLevel
+	for i := range levels {
+		if levels[i].name == level {
+			limits = &levels[i]
+		}
+	}
+	if limits == nil {
+		return errors.New("cinema.Video.SetProfileLevel: unknown " + codec +
+			" level " + level)
+	}
+	if err := v.checkLevel(*limits); err != nil {
+		return errors.New("cinema.Video.SetProfileLevel: " + err.Error() +
+			" exceeds " + codec + " level " + level)
+	}
+	v.record("SetProfileLevel", profile, level)
+	v.encoder.profile = profile
+	v.encoder.level = level
+	return nil
+}
+
+// checkLevel returns an error if the output size or frame rate of the Video
+// exceed the limits.
+func (v *Video) checkLevel(limits codecLevel) error {
+	width, height := int64(v.OutputWidth()), int64(v.OutputHeight())
+	num, den := v.rateFraction()
+	size := strconv.FormatInt(width, 10) + "x" + strconv.FormatInt(height, 10)
+	if limits.macroblockUnit {
+		width, height = (width+15)/16, (height+15)/16
+	}
+	frameSize := width * height
+	// Neither side may be longer than that of a square of 8 times the
+	// maximum frame size.
+	maxSide := int64(math.Sqrt(float64(limits.maxFrameSize) * 8))
+	if frameSize > limits.maxFrameSize || width > maxSide || height > maxSide {
+		return errors.New("the size " + size)
+	}
+	if float64(frameSize)*float64(num)/float64(den) > float64(limits.maxSampleRate) {
+		return errors.New("the frame rate " + formatFloat(float64(num)/float64(den)) +
+			" at " + size)
+	}
+	return nil
+}
+
+// isHEVC reports whether the encoder produces HEVC.
+func (e encoderSettings) isHEVC() bool {
+	return strings.Contains(e.codec, "265") || strings.Contains(e.codec, "hevc")
+}
@@ -0,0 +1,62 @@
+package cinema
+
+import "errors"
+
+// LogLevel is the amount of messages ffmpeg writes to its standard error, see
+// SetFFmpegLogLevel.
+type LogLevel string
+
+const (
+	// LogQuiet writes nothing.
+	LogQuiet LogLevel = "quiet"
+	// LogFatal writes only errors after which ffmpeg exits.
+	LogFatal LogLevel = "fatal"
+	// LogError writes all errors, including those ffmpeg recovers from.
+	LogError LogLevel = "error"
+	// LogWarning writes errors and warnings, e.g. about damaged input.
+	LogWarning LogLevel = "warning"
+	// LogInfo writes informational messages as well, like the description
+	// of the inputs and outputs. This is ffmpeg's default.
+	LogInfo LogLevel = "info"
+	// LogVerbose writes more details than LogInfo.
+	LogVerbose LogLevel = "verbose"
+	// LogDebug writes everything, which is only useful for debugging ffmpeg.
+	LogDebug LogLevel = "debug"
+)
+
+// quieterThanInfo are the log levels at which ffmpeg's progress line is not
+// wanted.
+var quieterThanInfo = map[LogLevel]bool{
+	LogQuiet:   true,
+	LogFatal:   true,
+	LogError:   true,
+	LogWarning: true,
+}
+
+// SetFFmpegLogLevel sets how much ffmpeg writes to its standard error when
+// the Video is rendered. Below LogInfo, the progress line is turned off as
+// well, so the output contains only diagnostics that can be parsed. ffmpeg's
+// build banner is never written. An error is returned if level is unknown.
+func (v *Video) SetFFmpegLogLevel(level LogLevel) error {
+	switch level {
+	case LogQuiet, LogFatal, LogError, LogWarning, LogInfo, LogVerbose, LogDebug:
+	default:
+		return errors.New("cinema.Video.SetFFmpegLogLevel: unknown level " +
+			string(level))
+	}
+	v.record("SetFFmpegLogLevel", level)
+	v.logLevel = level
+	return nil
+}
+
+// logArgs returns the global options that set the log level.
+func (v *Video) logArgs() []string {
+	if v.logLevel == "" {
+		return nil
+	}
+	args := []string{"-loglevel", string(v.logLevel)}
+	if quieterThanInfo[v.logLevel] {
+		args = append(args, "-nostats")
+	}
+	return args
+}
@@ -0,0 +1,59 @@
+package cinema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LUTInterpolation is how ApplyLUT computes colors that fall between the
+// points of the LUT.
+type LUTInterpolation string
+
+const (
+	// LUTTetrahedral gives the most accurate colors. It is the default.
+	LUTTetrahedral LUTInterpolation = "tetrahedral"
+	// LUTTrilinear is slightly faster and softer than LUTTetrahedral.
+	LUTTrilinear LUTInterpolation = "trilinear"
+	// LUTNearest uses the closest point of the LUT, which is the fastest
+	// but shows banding with small LUTs.
+	LUTNearest LUTInterpolation = "nearest"
+)
+
+// lutFormats are the file extensions of the 3D LUT formats ffmpeg reads.
+var lutFormats = []string{".cube", ".3dl", ".dat", ".m3d", ".csp"}
+
+// ApplyLUT applies the 3D LUT in the file at path to the colors of the video,
+// e.g. a grade exported as a .cube file from DaVinci Resolve or Premiere Pro.
+// The formats .cube, .3dl, .dat, .m3d and .csp are supported. An empty
+// interpolation selects LUTTetrahedral. Most LUTs expect the video in Rec.709,
+// log footage usually needs a conversion LUT first. An error is returned if
+// the file does not exist, its format is not supported or interpolation is
+// unknown.
+func (v *Video) ApplyLUT(path string, interpolation LUTInterpolation) error {
+	if interpolation == "" {
+		interpolation = LUTTetrahedral
+	}
+	switch interpolation {
+	case LUTTetrahedral, LUTTrilinear, LUTNearest:
+	default:
+		return errors.New("cinema.Video.ApplyLUT: unknown interpolation " +
+			string(interpolation))
+	}
+	if !contains(lutFormats, strings.ToLower(filepath.Ext(path))) {
+		return errors.New("cinema.Video.ApplyLUT: unsupported LUT format " +
+			filepath.Ext(path))
+	}
+	if _, err := os.Stat(path); err != nil {
+		return errors.New("cinema.Video.ApplyLUT: unable to load LUT: " +
+			err.Error())
+	}
+	v.record("ApplyLUT", path, interpolation)
+	v.filters = append(v.filters, filter{
+		stage: StageFX,
+		expr: "lut3d=file=" + filterValue(path) +
+			":interp=" + string(interpolation),
+	})
+	return nil
+}
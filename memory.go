@@ -0,0 +1,93 @@
+package cinema
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+)
+
+// SetMemoryLimit limits the memory each ffmpeg process started for the Video
+// may use to bytes, so that a single huge filtergraph cannot take down the
+// whole host. A limit of 0 removes it.
+//
+// On Linux, the limit is enforced by the kernel for the address space of the
+// process, which is limited before ffmpeg runs; ffmpeg fails when it tries to
+// allocate more and the render returns a *MemoryError. Note that the address
+// space is larger than the memory actually used, e.g. it includes the stacks
+// of all threads, so leave some headroom. On other systems, only single
+// allocations larger than the limit are rejected by ffmpeg itself.
+func (v *Video) SetMemoryLimit(bytes int64) error {
+	if bytes < 0 {
+		return errors.New("cinema.Video.SetMemoryLimit: limit must not be " +
+			"negative: " + strconv.FormatInt(bytes, 10))
+	}
+	v.record("SetMemoryLimit", bytes)
+	v.memoryLimit = bytes
+	return nil
+}
+
+// MemoryError is returned by renders when ffmpeg was killed by the operating
+// system, which usually means that the system or the container ran out of
+// memory, or when ffmpeg failed to allocate memory under the limit set with
+// SetMemoryLimit. Reduce the memory limit of the host's other processes,
+// render fewer Videos in parallel, reduce the size of the filtergraph or
+// raise the limit.
+type MemoryError struct {
+	// Op is the operation that failed, e.g. "cinema.Video.Render".
+	Op string
+	// Limit is the limit set with SetMemoryLimit, 0 if there was none.
+	Limit int64
+	// Err is the error returned by the process.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *MemoryError) Error() string {
+	return e.Op + ": ffmpeg failed or was killed, probably because it ran " +
+		"out of memory: " + e.Err.Error()
+}
+
+// runFFmpeg runs the ffmpeg command line with the memory limit of the Video.
+func (v *Video) runFFmpeg(line []string) error {
+	cmd := v.command(line)
+	if err := startProcess(cmd, v.memoryLimit); err != nil {
+		return err
+	}
+	return waitProcess(cmd)
+}
+
+// command creates the command for the ffmpeg command line like the command
+// function, with the log level set with SetFFmpegLogLevel. If there is a
+// memory limit, ffmpeg also rejects single allocations above it, which is
+// the only limit on systems where the operating system cannot enforce it. For
+// a Video loaded with LoadReader, the input is fed to the standard input.
+func (v *Video) command(line []string) *exec.Cmd {
+	global := v.logArgs()
+	if v.memoryLimit > 0 {
+		global = append(global,
+			"-max_alloc", strconv.FormatInt(v.memoryLimit, 10))
+	}
+	if len(global) > 0 {
+		line = append(append([]string{line[0]}, global...), line[1:]...)
+	}
+	cmd := command(line)
+	if v.source != nil {
+		for _, arg := range line {
+			if arg == "pipe:0" {
+				cmd.Stdin = v.source.stdin()
+				break
+			}
+		}
+	}
+	return cmd
+}
+
+// ffmpegFailed returns the error for op when running ffmpeg failed with err.
+// A *MemoryError is returned as is so callers can detect it.
+func ffmpegFailed(op string, err error) error {
+	if m, ok := err.(*MemoryError); ok {
+		m.Op = op
+		return m
+	}
+	return errors.New(op + ": ffmpeg failed: " + err.Error())
+}
@@ -0,0 +1,207 @@
+package cinema
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MetadataPolicy controls which metadata of the input is written to the
+// output, see SetMetadataPolicy.
+type MetadataPolicy struct {
+	// DropGlobal drops the container metadata of the input, e.g. title,
+	// encoder and creation_time.
+	DropGlobal bool
+	// DropStreams drops the metadata of the streams of the input, e.g.
+	// language and handler_name.
+	DropStreams bool
+	// Set sets container tags of the output. An empty value removes the tag.
+	Set map[string]string
+	// SetStreams sets tags of output streams by ffmpeg stream specifier,
+	// e.g. {"a:0": {"language": "eng"}}. An empty value removes the tag.
+	SetStreams map[string]map[string]string
+	// Rename moves the values of container tags of the input to other keys,
+	// e.g. {"comment": "description"} for players that only show the
+	// description.
+	Rename map[string]string
+	// KeepCustom keeps tags with non-standard keys in MP4 and MOV outputs,
+	// whose muxers drop them otherwise. Other muxers always keep them.
+	KeepCustom bool
+}
+
+// SetMetadataPolicy sets which metadata is copied from the input, changed or
+// dropped. Without a policy, ffmpeg copies the container and stream metadata
+// of the input, but what the output keeps depends on the muxer: e.g. MP4 only
+// stores a fixed set of keys while Matroska stores all of them. The policy
+// replaces the changes made with SetMetadata and ClearMetadata before. An
+// error is returned if a stream specifier or key is empty.
+func (v *Video) SetMetadataPolicy(policy MetadataPolicy) error {
+	for key := range policy.Set {
+		if key == "" {
+			return errors.New("cinema.Video.SetMetadataPolicy: empty key")
+		}
+	}
+	for spec, tags := range policy.SetStreams {
+		if spec == "" {
+			return errors.New("cinema.Video.SetMetadataPolicy: empty " +
+				"stream specifier")
+		}
+		for key := range tags {
+			if key == "" {
+				return errors.New("cinema.Video.SetMetadataPolicy: empty " +
+					"key for stream " + spec)
+			}
+		}
+	}
+	for from, to := range policy.Rename {
+		if from == "" || to == "" {
+			return errors.New("cinema.Video.SetMetadataPolicy: empty key " +
+				"in rename")
+		}
+	}
+	v.record("SetMetadataPolicy", policy)
+
+	// Copy the maps so that later changes by the caller have no effect.
+	v.metadata = &policy
+	p := v.metadataPolicy()
+	v.metadata = &p
+	return nil
+}
+
+// Metadata returns the container metadata of the input, e.g. "title",
+// "artist", "creation_time" and custom tags, as read by ffprobe. The keys are
+// those of the container, e.g. Matroska files often have upper case keys.
+func (v *Video) Metadata() map[string]string {
+	return copyTags(v.formatTags)
+}
+
+// movTags are the keys the MP4 and MOV muxers store without
+// KeepCustom.
+var movTags = map[string]bool{
+	"title": true, "artist": true, "album_artist": true, "album": true,
+	"date": true, "year": true, "creation_time": true, "comment": true,
+	"description": true, "synopsis": true, "copyright": true, "genre": true,
+	"composer": true, "encoder": true, "grouping": true, "lyrics": true,
+	"track": true, "disc": true, "show": true, "episode_id": true,
+	"network": true, "compilation": true,
+}
+
+// SetMetadata sets the container tag key of the output to value, e.g.
+// SetMetadata("title", "Holiday") or a custom tag. An empty value removes the
+// tag. It changes the metadata policy, see SetMetadataPolicy; custom keys
+// make MP4 and MOV outputs keep custom tags. An error is returned if key is
+// empty.
+func (v *Video) SetMetadata(key, value string) error {
+	if key == "" {
+		return errors.New("cinema.Video.SetMetadata: empty key")
+	}
+	v.record("SetMetadata", key, value)
+	p := v.metadataPolicy()
+	if p.Set == nil {
+		p.Set = make(map[string]string)
+	}
+	p.Set[key] = value
+	if value != "" && !movTags[strings.ToLower(key)] {
+		p.KeepCustom = true
+	}
+	v.metadata = &p
+	return nil
+}
+
+// ClearMetadata drops the container metadata of the input and the tags set
+// with SetMetadata, so the output only has the tags set afterwards. Stream
+// metadata is kept. It changes the metadata policy, see SetMetadataPolicy.
+func (v *Video) ClearMetadata() {
+	v.record("ClearMetadata")
+	p := v.metadataPolicy()
+	p.DropGlobal = true
+	p.Set = nil
+	p.Rename = nil
+	v.metadata = &p
+}
+
+// metadataPolicy returns a copy of the metadata policy, which may be changed
+// without affecting copies of the Video.
+func (v *Video) metadataPolicy() MetadataPolicy {
+	if v.metadata == nil {
+		return MetadataPolicy{}
+	}
+	p := *v.metadata
+	p.Set = copyTags(p.Set)
+	p.Rename = copyTags(p.Rename)
+	p.SetStreams = make(map[string]map[string]string)
+	for spec, tags := range v.metadata.SetStreams {
+		p.SetStreams[spec] = copyTags(tags)
+	}
+	return p
+}
+
+// metadataPolicyArgs returns the output options that apply the metadata
+// policy when writing to output.
+func (v *Video) metadataPolicyArgs(output string) []string {
+	p := v.metadata
+	if p == nil {
+		return nil
+	}
+	var args []string
+	if p.DropGlobal {
+		args = append(args, "-map_metadata:g", "-1")
+	}
+	if p.DropStreams {
+		args = append(args, "-map_metadata:s", "-1")
+	}
+	for _, from := range sortedKeys(p.Rename) {
+		value, ok := v.formatTags[from]
+		if !ok {
+			continue
+		}
+		args = append(args, "-metadata", p.Rename[from]+"="+value)
+		if !p.DropGlobal {
+			args = append(args, "-metadata", from+"=")
+		}
+	}
+	for _, key := range sortedKeys(p.Set) {
+		args = append(args, "-metadata", key+"="+p.Set[key])
+	}
+	var specs []string
+	for spec := range p.SetStreams {
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+	for _, spec := range specs {
+		tags := p.SetStreams[spec]
+		for _, key := range sortedKeys(tags) {
+			args = append(args, "-metadata:s:"+spec, key+"="+tags[key])
+		}
+	}
+	if p.KeepCustom {
+		switch strings.ToLower(filepath.Ext(output)) {
+		case ".mp4", ".m4v", ".m4a", ".mov":
+			args = append(args, "-movflags", "+use_metadata_tags")
+		}
+	}
+	return args
+}
+
+// copyTags returns a copy of tags.
+func copyTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	c := make(map[string]string, len(tags))
+	for key, value := range tags {
+		c[key] = value
+	}
+	return c
+}
+
+// sortedKeys returns the keys of tags in order.
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,235 @@
+package cinema
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MontageOptions configures AutoMontage. Zero values select the defaults.
+type MontageOptions struct {
+	// BeatsPerCut is the number of beats of the music between two cuts. It
+	// defaults to 4, a bar of most pop music.
+	BeatsPerCut int
+	// Duration limits the length of the montage. It defaults to the length
+	// of the trimmed music.
+	Duration time.Duration
+	// Transition is the name of the transition between the clips, one of
+	// the transitions of ffmpeg's xfade filter, e.g. "fade", "wipeleft" or
+	// "slideup". Empty makes hard cuts.
+	Transition string
+	// TransitionDuration is the length of the transitions. It defaults to a
+	// quarter of a second. The transitions start on the beat.
+	TransitionDuration time.Duration
+	// Width and Height are the size of the montage. They default to the
+	// output size of the first clip. Clips with a different aspect ratio
+	// are scaled to fit and padded with black.
+	Width, Height int
+}
+
+// AutoMontage cuts the clips together to the music in the file at music, with
+// a cut on every few beats, see DetectBeats. The clips are used in turn, each
+// one with its operations applied: the first cut shows the start of the first
+// clip, the next cut the start of the second one, and when all clips have been
+// shown, the next parts of the clips follow. Clips that are too short for a
+// cut are skipped. The montage has the audio of the music, which fades out
+// at the end, and the frame rate of the first clip.
+//
+// An error is returned if there are no clips, the music has no audio or no
+// beats, no clip is long enough for a cut, or ffmpeg fails.
+func AutoMontage(clips []*Video, music, output string, opts MontageOptions) error {
+	if len(clips) == 0 {
+		return errors.New("cinema.AutoMontage: no clips given")
+	}
+	if opts.BeatsPerCut == 0 {
+		opts.BeatsPerCut = 4
+	}
+	if opts.Transition != "" && opts.TransitionDuration == 0 {
+		opts.TransitionDuration = 250 * time.Millisecond
+	}
+	if opts.Transition == "" {
+		opts.TransitionDuration = 0
+	}
+	if opts.BeatsPerCut < 0 || opts.Duration < 0 || opts.TransitionDuration < 0 ||
+		opts.Width < 0 || opts.Height < 0 {
+		return errors.New("cinema.AutoMontage: options must not be negative")
+	}
+	if opts.Width == 0 || opts.Height == 0 {
+		opts.Width, opts.Height = clips[0].OutputWidth(), clips[0].OutputHeight()
+	}
+	for _, c := range clips {
+		if err := c.checkTrim("cinema.AutoMontage"); err != nil {
+			return err
+		}
+	}
+
+	song, err := Load(music)
+	if err != nil {
+		return errors.New("cinema.AutoMontage: " + err.Error())
+	}
+	beats, err := song.DetectBeats()
+	if err != nil {
+		return errors.New("cinema.AutoMontage: " + err.Error())
+	}
+	length := song.duration
+	if opts.Duration > 0 && opts.Duration < length {
+		length = opts.Duration
+	}
+	cuts := montageCuts(beats.Beats, opts.BeatsPerCut, length)
+	if len(cuts) < 2 {
+		return errors.New("cinema.AutoMontage: no beats found in the music")
+	}
+
+	dir, err := ioutil.TempDir("", "cinema-montage-")
+	if err != nil {
+		return errors.New("cinema.AutoMontage: unable to create temporary " +
+			"directory: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+	segments, err := renderMontageSegments(clips, cuts, dir, opts)
+	if err != nil {
+		return errors.New("cinema.AutoMontage: " + err.Error())
+	}
+
+	var line []string
+	line = append(line, "ffmpeg", "-y")
+	for _, s := range segments {
+		line = append(line, "-i", s)
+	}
+	line = append(line, "-i", music)
+	total := cuts[len(cuts)-1]
+	fade := time.Second
+	if fade > total/4 {
+		fade = total / 4
+	}
+	graph := montageGraph(len(segments), cuts, opts) +
+		";[" + strconv.Itoa(len(segments)) + ":a:0]atrim=0:" +
+		formatFloat(total.Seconds()) + ",afade=t=out:st=" +
+		formatFloat((total - fade).Seconds()) + ":d=" +
+		formatFloat(fade.Seconds()) + "[aout]"
+	line = append(line,
+		"-filter_complex", graph,
+		"-map", "[vout]",
+		"-map", "[aout]",
+		"-c:v", "libx264",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-t", formatFloat(total.Seconds()),
+		output,
+	)
+	if err := run(line); err != nil {
+		return errors.New("cinema.AutoMontage: ffmpeg failed: " + err.Error())
+	}
+	return nil
+}
+
+// montageCuts returns the times of the cuts of a montage of the given length:
+// 0, every beatsPerCut-th beat and the length.
+func montageCuts(beats []time.Duration, beatsPerCut int, length time.Duration) []time.Duration {
+	// Cuts closer than this to the previous one are skipped, e.g. when the
+	// first beat is right at the start.
+	const minCut = 500 * time.Millisecond
+	cuts := []time.Duration{0}
+	for i := beatsPerCut; i < len(beats); i += beatsPerCut {
+		if beats[i]-cuts[len(cuts)-1] < minCut {
+			continue
+		}
+		if length-beats[i] < minCut {
+			break
+		}
+		cuts = append(cuts, beats[i])
+	}
+	if len(cuts) == 1 {
+		return cuts
+	}
+	return append(cuts, length)
+}
+
+// renderMontageSegments renders the part of the clips shown between each two
+// cuts into dir, normalized to the size and frame rate of the montage, and
+// returns their paths. Each segment but the last one is longer than its cut
+// by the transition duration, for the transition into the next segment.
+func renderMontageSegments(clips []*Video, cuts []time.Duration, dir string, opts MontageOptions) ([]string, error) {
+	first := clips[0]
+	// positions are the output times in the clips where the next segment
+	// of each clip starts.
+	positions := make([]time.Duration, len(clips))
+	next := 0
+	var paths []string
+	for i := 0; i+1 < len(cuts); i++ {
+		length := cuts[i+1] - cuts[i]
+		if i+2 < len(cuts) {
+			length += opts.TransitionDuration
+		}
+		var clip Video
+		found := false
+		for tries := 0; tries < len(clips) && !found; tries++ {
+			j := next
+			next = (next + 1) % len(clips)
+			c := clips[j]
+			start, end := c.outputTime(c.start), c.outputTime(c.end)
+			if end-start < length {
+				continue
+			}
+			if start+positions[j]+length > end {
+				positions[j] = 0
+			}
+			clip = c.snapshot()
+			clip.setStart(c.inputTime(start + positions[j]))
+			clip.setEnd(c.inputTime(start + positions[j] + length))
+			positions[j] += length
+			found = true
+		}
+		if !found {
+			return nil, errors.New("no clip is long enough for the cut at " +
+				cuts[i].String())
+		}
+		clip.fitExactly(opts.Width, opts.Height)
+		clip.fps, clip.fpsRate, clip.cfr = first.fps, first.fpsRate, first.cfr
+		clip.outputArgs = append(clip.outputArgs, "-an", "-pix_fmt", "yuv420p")
+		path := filepath.Join(dir, "segment-"+strconv.Itoa(i+1)+".mp4")
+		if err := clip.Render(path); err != nil {
+			return nil, errors.New("unable to render segment " +
+				strconv.Itoa(i+1) + ": " + err.Error())
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// montageGraph returns the filtergraph that joins the n segments, which are
+// the first inputs, into the pad [vout]: with the xfade transition of opts
+// starting at each cut, or with hard cuts.
+func montageGraph(n int, cuts []time.Duration, opts MontageOptions) string {
+	var parts []string
+	for i := 0; i < n; i++ {
+		// xfade needs the same time base in all inputs.
+		parts = append(parts, "["+strconv.Itoa(i)+":v:0]settb=AVTB,setpts=PTS-STARTPTS[s"+
+			strconv.Itoa(i)+"]")
+	}
+	if opts.Transition == "" || n == 1 {
+		var pads string
+		for i := 0; i < n; i++ {
+			pads += "[s" + strconv.Itoa(i) + "]"
+		}
+		return strings.Join(parts, ";") + ";" + pads + "concat=n=" +
+			strconv.Itoa(n) + ":v=1:a=0[vout]"
+	}
+	prev := "[s0]"
+	for i := 1; i < n; i++ {
+		out := "[x" + strconv.Itoa(i) + "]"
+		if i == n-1 {
+			out = "[vout]"
+		}
+		parts = append(parts, prev+"[s"+strconv.Itoa(i)+"]xfade=transition="+
+			filterValue(opts.Transition)+
+			":duration="+formatFloat(opts.TransitionDuration.Seconds())+
+			":offset="+formatFloat(cuts[i].Seconds())+out)
+		prev = out
+	}
+	return strings.Join(parts, ";")
+}
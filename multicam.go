@@ -0,0 +1,215 @@
+package cinema
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Multicam renders a program that cuts between the angles of a multi-camera
+// recording, e.g. the close-up of whoever is talking. Create it with
+// NewMulticam, choose the angles with Cut and render it with Render.
+//
+// The program follows the timeline of the first angle: it is as long as the
+// trimmed first angle and its times are output times of the first angle.
+type Multicam struct {
+	angles  []*Video
+	offsets []time.Duration
+	cuts    map[time.Duration]int
+	audio   int
+}
+
+// NewMulticam creates a Multicam from the angles and their offsets on the
+// timeline of the first angle, as returned by SyncByAudio: a moment at time t
+// of angle i is at time t+offsets[i] of the first angle. The operations of
+// each angle are applied to it; the frames of all angles are scaled to the
+// size of the first one, keeping their aspect ratio. Until the first cut,
+// the program shows the first angle. An error is returned if there is not
+// one offset per angle.
+func NewMulticam(angles []*Video, offsets []time.Duration) (*Multicam, error) {
+	if len(angles) == 0 {
+		return nil, errors.New("cinema.NewMulticam: no angles given")
+	}
+	if len(offsets) != len(angles) {
+		return nil, errors.New("cinema.NewMulticam: " +
+			strconv.Itoa(len(offsets)) + " offsets given for " +
+			strconv.Itoa(len(angles)) + " angles")
+	}
+	return &Multicam{
+		angles:  append([]*Video(nil), angles...),
+		offsets: append([]time.Duration(nil), offsets...),
+		cuts:    map[time.Duration]int{},
+	}, nil
+}
+
+// Cut switches the program to the angle with the given index at the time at
+// of the program. The angle is shown until the next cut. A later cut at the
+// same time replaces the earlier one. An error is returned if there is no
+// such angle or the time is outside of the program.
+func (m *Multicam) Cut(at time.Duration, angle int) error {
+	if angle < 0 || angle >= len(m.angles) {
+		return errors.New("cinema.Multicam.Cut: there is no angle " +
+			strconv.Itoa(angle))
+	}
+	if at < 0 || at >= m.length() {
+		return errors.New("cinema.Multicam.Cut: time " + at.String() +
+			" is outside of the program")
+	}
+	m.cuts[at] = angle
+	return nil
+}
+
+// SetAudio selects the angle whose audio is used for the whole program,
+// usually the one with the best microphone. It defaults to the first angle.
+// An error is returned if there is no such angle or it has no audio.
+func (m *Multicam) SetAudio(angle int) error {
+	if angle < 0 || angle >= len(m.angles) {
+		return errors.New("cinema.Multicam.SetAudio: there is no angle " +
+			strconv.Itoa(angle))
+	}
+	if m.angles[angle].audioChannels == 0 {
+		return errors.New("cinema.Multicam.SetAudio: angle " +
+			strconv.Itoa(angle) + " has no audio")
+	}
+	m.audio = angle
+	return nil
+}
+
+// Render renders the program to output. The shots are rendered one by one
+// and joined without re-encoding them again. An error is returned if an
+// angle does not cover the whole time it is shown or the audio angle does not
+// cover the whole program, or ffmpeg fails.
+func (m *Multicam) Render(output string) error {
+	for _, a := range m.angles {
+		if err := a.checkTrim("cinema.Multicam.Render"); err != nil {
+			return err
+		}
+	}
+	dir, err := ioutil.TempDir("", "cinema-multicam-")
+	if err != nil {
+		return errors.New("cinema.Multicam.Render: unable to create " +
+			"temporary directory: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	first := m.angles[0]
+	width, height := first.OutputWidth(), first.OutputHeight()
+	var paths []string
+	for i, s := range m.shots() {
+		n := strconv.Itoa(i + 1)
+		clip, err := m.section(s.angle, s.start, s.end)
+		if err != nil {
+			return errors.New("cinema.Multicam.Render: " + err.Error())
+		}
+		clip.fitExactly(width, height)
+		clip.fps, clip.fpsRate, clip.cfr = first.fps, first.fpsRate, first.cfr
+		clip.outputArgs = append(clip.outputArgs, "-an", "-pix_fmt", "yuv420p")
+		path := filepath.Join(dir, "shot-"+n+filepath.Ext(output))
+		if err := clip.Render(path); err != nil {
+			return errors.New("cinema.Multicam.Render: unable to render " +
+				"shot " + n + ": " + err.Error())
+		}
+		paths = append(paths, path)
+	}
+	list := filepath.Join(dir, "list.txt")
+	if err := writeConcatList(list, paths); err != nil {
+		return errors.New("cinema.Multicam.Render: unable to write file " +
+			"list: " + err.Error())
+	}
+
+	line := []string{
+		"ffmpeg",
+		"-y",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", list,
+	}
+	if m.angles[m.audio].audioChannels > 0 {
+		audio, err := m.section(m.audio, 0, m.length())
+		if err != nil {
+			return errors.New("cinema.Multicam.Render: " + err.Error())
+		}
+		path := filepath.Join(dir, "audio.wav")
+		audioLine := []string{"ffmpeg", "-y"}
+		audioLine = append(audioLine, audio.input()...)
+		audioLine = append(audioLine, audio.trimArgs(audio.outputTime(audio.start),
+			audio.outputTime(audio.end))...)
+		audioLine = append(audioLine, audio.audioArgs()...)
+		audioLine = append(audioLine, audio.resamplerArgs()...)
+		audioLine = append(audioLine, "-vn", path)
+		if err := audio.runFFmpeg(audioLine); err != nil {
+			return errors.New("cinema.Multicam.Render: unable to render " +
+				"the audio: " + err.Error())
+		}
+		line = append(line, "-i", path, "-map", "0:v", "-map", "1:a")
+	} else {
+		line = append(line, "-map", "0:v")
+	}
+	line = append(line, "-c:v", "copy")
+	line = append(line, copyArgs()...)
+	if err := run(append(line, output)); err != nil {
+		return errors.New("cinema.Multicam.Render: ffmpeg failed: " +
+			err.Error())
+	}
+	return nil
+}
+
+// shot is a part of the program that shows a single angle, from start to end
+// in program time.
+type shot struct {
+	angle      int
+	start, end time.Duration
+}
+
+// shots returns the parts of the program between the cuts.
+func (m *Multicam) shots() []shot {
+	times := []time.Duration{0}
+	for at := range m.cuts {
+		if at > 0 {
+			times = append(times, at)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	var shots []shot
+	for i, at := range times {
+		end := m.length()
+		if i+1 < len(times) {
+			end = times[i+1]
+		}
+		// Without a cut at 0, the program starts with the first angle.
+		angle := m.cuts[at]
+		if len(shots) > 0 && shots[len(shots)-1].angle == angle {
+			shots[len(shots)-1].end = end
+			continue
+		}
+		shots = append(shots, shot{angle, at, end})
+	}
+	return shots
+}
+
+// section returns a copy of the angle trimmed to the range from start to end
+// of the program.
+func (m *Multicam) section(angle int, start, end time.Duration) (Video, error) {
+	a := m.angles[angle]
+	from := a.outputTime(a.start) + start - m.offsets[angle]
+	to := a.outputTime(a.start) + end - m.offsets[angle]
+	if from < a.outputTime(a.start) || to > a.outputTime(a.end) {
+		return Video{}, errors.New("angle " + strconv.Itoa(angle) +
+			" does not cover the program from " + start.String() + " to " +
+			end.String())
+	}
+	clip := a.snapshot()
+	clip.setStart(a.inputTime(from))
+	clip.setEnd(a.inputTime(to))
+	return clip, nil
+}
+
+// length returns the length of the program.
+func (m *Multicam) length() time.Duration {
+	first := m.angles[0]
+	return first.outputTime(first.end) - first.outputTime(first.start)
+}
@@ -0,0 +1,151 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MXFPattern is an MXF operational pattern, i.e. how the streams are stored
+// in MXF files.
+type MXFPattern int
+
+const (
+	// OP1a stores all streams interleaved in a single file. It is the most
+	// widely accepted pattern for file delivery to broadcasters.
+	OP1a MXFPattern = iota
+	// OPAtom stores each stream in its own file, every audio channel
+	// separately. It is the native media format of Avid editing systems.
+	OPAtom
+)
+
+// MXFOptions configures RenderMXF. Zero values select the defaults.
+type MXFOptions struct {
+	// Pattern is the operational pattern. It defaults to OP1a.
+	Pattern MXFPattern
+	// Timecode is the timecode of the first frame, HH:MM:SS:FF or
+	// HH:MM:SS;FF for drop frame timecode, e.g. "10:00:00:00" as commonly
+	// required for programs. It is written into the timecode track. If
+	// empty, the timecode of the input is kept, if it has one.
+	Timecode string
+	// VideoEncoder is the ffmpeg video encoder, e.g. "mpeg2video" for XDCAM
+	// style files. It defaults to "dnxhd" with the DNxHR HQ profile, which
+	// works for all resolutions. Encoders other than the default are used
+	// with their default settings.
+	VideoEncoder string
+}
+
+var mxfTimecode = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}[:;][0-9]{2}$`)
+
+// RenderMXF renders the Video as MXF for delivery to broadcast and asset
+// management systems. The audio is stored as uncompressed 24 bit PCM at 48
+// kHz, which MXF requires. The output frame rate (see SetFPS) must be one of
+// the common broadcast rates, e.g. 25 or 30000/1001.
+//
+// With OP1a a single file is written to output. With OPAtom the file names
+// are created from output by adding "_v1" for the video and "_a1", "_a2" and
+// so on for the audio channels before the extension. The names of the created
+// files are returned. If some files could not be rendered, the error is an
+// *OutputError, see ContinueOnError.
+func (v *Video) RenderMXF(output string, opts MXFOptions) ([]string, error) {
+	if opts.Pattern != OP1a && opts.Pattern != OPAtom {
+		return nil, errors.New("cinema.Video.RenderMXF: unknown pattern " +
+			strconv.Itoa(int(opts.Pattern)))
+	}
+	if opts.Timecode != "" && !mxfTimecode.MatchString(opts.Timecode) {
+		return nil, errors.New("cinema.Video.RenderMXF: invalid timecode " +
+			opts.Timecode)
+	}
+	num, den := v.rateFraction()
+	if !mxfRate(num, den) {
+		return nil, errors.New("cinema.Video.RenderMXF: MXF does not support " +
+			"the frame rate " + strconv.FormatInt(num, 10) + "/" +
+			strconv.FormatInt(den, 10))
+	}
+	if err := v.checkTrim("cinema.Video.RenderMXF"); err != nil {
+		return nil, err
+	}
+
+	videoArgs := []string{
+		"-c:v", "dnxhd",
+		"-profile:v", "dnxhr_hq",
+		"-pix_fmt", "yuv422p",
+	}
+	if opts.VideoEncoder != "" {
+		videoArgs = []string{"-c:v", opts.VideoEncoder}
+	}
+	var timecodeArgs []string
+	if opts.Timecode != "" {
+		timecodeArgs = []string{"-timecode", opts.Timecode}
+	}
+	audioArgs := []string{"-c:a", "pcm_s24le", "-ar", "48000"}
+
+	if opts.Pattern == OP1a {
+		clip := v.snapshot()
+		clip.outputArgs = append(clip.outputArgs, videoArgs...)
+		clip.outputArgs = append(clip.outputArgs, audioArgs...)
+		clip.outputArgs = append(clip.outputArgs, timecodeArgs...)
+		clip.outputArgs = append(clip.outputArgs, "-f", "mxf")
+		return v.renderOutputs("cinema.Video.RenderMXF", []string{output}, func(int) error {
+			return clip.Render(output)
+		})
+	}
+
+	// OPAtom files hold a single stream. Audio files are cut into edit
+	// units of one video frame.
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	outputs := []string{base + "_v1" + ext}
+	for i := 1; i <= v.audioChannels; i++ {
+		outputs = append(outputs, base+"_a"+strconv.Itoa(i)+ext)
+	}
+	editRate := strconv.FormatInt(num, 10) + "/" + strconv.FormatInt(den, 10)
+	return v.renderOutputs("cinema.Video.RenderMXF", outputs, func(i int) error {
+		if i == 0 {
+			clip := v.snapshot()
+			clip.outputArgs = append(clip.outputArgs, "-an")
+			clip.outputArgs = append(clip.outputArgs, videoArgs...)
+			clip.outputArgs = append(clip.outputArgs, timecodeArgs...)
+			clip.outputArgs = append(clip.outputArgs, "-f", "mxf_opatom")
+			return clip.Render(outputs[i])
+		}
+		filters := append(v.audioChain(),
+			"pan=mono|c0=c"+strconv.Itoa(i-1))
+		line := []string{"ffmpeg", "-y"}
+		line = append(line, v.input()...)
+		line = append(line, v.trimArgs(v.outputTime(v.start), v.outputTime(v.end))...)
+		line = append(line, "-vn", "-map", "0:a:0")
+		line = append(line, audioFilterArgs(filters)...)
+		line = append(line, audioArgs...)
+		line = append(line, timecodeArgs...)
+		line = append(line,
+			"-f", "mxf_opatom",
+			"-mxf_audio_edit_rate", editRate,
+			outputs[i],
+		)
+		if err := v.runFFmpeg(line); err != nil {
+			return ffmpegFailed("cinema.Video.RenderMXF", err)
+		}
+		return nil
+	})
+}
+
+// mxfRates are the frame rates ffmpeg's MXF muxers support.
+var mxfRates = []string{
+	"24000/1001", "24", "25", "30000/1001", "30",
+	"48", "50", "60000/1001", "60",
+}
+
+// mxfRate reports whether MXF supports the frame rate num/den.
+func mxfRate(num, den int64) bool {
+	rate := float64(num) / float64(den)
+	for _, r := range mxfRates {
+		if math.Abs(parseRate(r)-rate) < 1e-6 {
+			return true
+		}
+	}
+	return false
+}
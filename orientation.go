@@ -0,0 +1,56 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Rotate rotates the video clockwise by degrees, which must be a multiple of
+// 90, e.g. to fix a phone video that was recorded sideways. Use a negative
+// value to rotate counterclockwise. For 90 and 270 degrees the width and
+// height of the output are swapped, which Width and Height reflect. The
+// rotation stored in the input file is already applied when it is decoded, so
+// Rotate turns the video as it appears in players. An error is returned if
+// degrees is not a multiple of 90.
+func (v *Video) Rotate(degrees int) error {
+	if degrees%90 != 0 {
+		return errors.New("cinema.Video.Rotate: " + strconv.Itoa(degrees) +
+			" degrees is not a multiple of 90")
+	}
+	v.record("Rotate", degrees)
+	var f filter
+	switch (degrees/90%4 + 4) % 4 {
+	case 0:
+		return nil
+	case 1:
+		f = filter{kind: rotateRightFilter, expr: "transpose=clock"}
+	case 2:
+		f = filter{kind: rotate180Filter, expr: "hflip,vflip"}
+	case 3:
+		f = filter{kind: rotateLeftFilter, expr: "transpose=cclock"}
+	}
+	f.stage = StageScale
+	v.filters = append(v.filters, f)
+	return nil
+}
+
+// FlipHorizontal mirrors the video horizontally, i.e. left becomes right, e.g.
+// to undo the mirroring of front camera recordings.
+func (v *Video) FlipHorizontal() {
+	v.record("FlipHorizontal")
+	v.filters = append(v.filters, filter{
+		stage: StageScale,
+		kind:  hflipFilter,
+		expr:  "hflip",
+	})
+}
+
+// FlipVertical mirrors the video vertically, i.e. the top becomes the bottom.
+func (v *Video) FlipVertical() {
+	v.record("FlipVertical")
+	v.filters = append(v.filters, filter{
+		stage: StageScale,
+		kind:  vflipFilter,
+		expr:  "vflip",
+	})
+}
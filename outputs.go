@@ -0,0 +1,80 @@
+package cinema
+
+import "strconv"
+
+// OutputResult is the result of rendering a single output file of a render
+// that creates several files, see OutputError.
+type OutputResult struct {
+	// Output is the name of the file.
+	Output string
+	// Err is the error rendering the file, nil if it was created.
+	Err error
+}
+
+// OutputError is returned by renders that create several output files, like
+// RenderCuts and SplitByChapters, when some of the files could not be
+// rendered. The files that were created are returned as well.
+type OutputError struct {
+	// Op is the operation that failed, e.g. "cinema.Video.RenderCuts".
+	Op string
+	// Results lists the outputs in order. Outputs that were not rendered
+	// because an earlier one failed are not listed, see ContinueOnError.
+	Results []OutputResult
+}
+
+// Error implements the error interface.
+func (e *OutputError) Error() string {
+	failed := e.Failed()
+	msg := e.Op + ": unable to render " + strconv.Itoa(len(failed)) + " of " +
+		strconv.Itoa(len(e.Results)) + " outputs"
+	if len(failed) > 0 {
+		msg += ", " + failed[0].Output + ": " + failed[0].Err.Error()
+	}
+	return msg
+}
+
+// Failed returns the results of the outputs that could not be rendered.
+func (e *OutputError) Failed() []OutputResult {
+	var failed []OutputResult
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// ContinueOnError sets whether renders that create several output files, like
+// RenderCuts and SplitByChapters, continue with the remaining files when one
+// of them fails, e.g. because a hardware encoder is missing for one variant.
+// By default they stop at the first failure. Either way an *OutputError
+// reports which files failed.
+func (v *Video) ContinueOnError(enabled bool) {
+	v.record("ContinueOnError", enabled)
+	v.keepGoing = enabled
+}
+
+// renderOutputs calls render for each of the outputs and returns the outputs
+// that were created. If any of them failed, the error is an *OutputError for
+// op.
+func (v *Video) renderOutputs(op string, outputs []string, render func(i int) error) ([]string, error) {
+	var created []string
+	var results []OutputResult
+	failed := false
+	for i, output := range outputs {
+		err := render(i)
+		results = append(results, OutputResult{Output: output, Err: err})
+		if err == nil {
+			created = append(created, output)
+			continue
+		}
+		failed = true
+		if !v.keepGoing {
+			break
+		}
+	}
+	if failed {
+		return created, &OutputError{Op: op, Results: results}
+	}
+	return created, nil
+}
@@ -0,0 +1,102 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Position is the corner or the center of the video that an overlay is placed
+// relative to, see Overlay.
+type Position int
+
+const (
+	// TopLeft places the top-left corner of the overlay x pixels right and y
+	// pixels down from the top-left corner of the video.
+	TopLeft Position = iota
+	// TopRight places the top-right corner of the overlay x pixels left and
+	// y pixels down from the top-right corner of the video.
+	TopRight
+	// BottomLeft places the bottom-left corner of the overlay x pixels right
+	// and y pixels up from the bottom-left corner of the video.
+	BottomLeft
+	// BottomRight places the bottom-right corner of the overlay x pixels
+	// left and y pixels up from the bottom-right corner of the video.
+	BottomRight
+	// Center places the center of the overlay x pixels right and y pixels
+	// down from the center of the video.
+	Center
+)
+
+// OverlayOptions configures Overlay. Zero values select the defaults.
+type OverlayOptions struct {
+	// Position is what x and y are relative to. It defaults to TopLeft.
+	Position Position
+	// Opacity of the image from 0 (invisible) to 1 (opaque). It defaults to
+	// 1.
+	Opacity float64
+	// Scale is the width of the image relative to the width of the video,
+	// e.g. 0.1 for a logo that covers a tenth of the width. The aspect ratio
+	// of the image is kept. 0 keeps the size of the image.
+	Scale float64
+}
+
+// Overlay draws the image at imagePath on top of the video, e.g. a logo or a
+// watermark. x and y are the distance in pixels from the corner or center of
+// the video selected by opts.Position, so that a logo stays in the corner
+// when the output size changes. Images with an alpha channel, e.g. PNG, are
+// blended. Use ShowBetween right afterwards to show the image only for a part
+// of the video. An error is returned if an option is out of range.
+func (v *Video) Overlay(imagePath string, x, y int, opts OverlayOptions) error {
+	if opts.Opacity == 0 {
+		opts.Opacity = 1
+	}
+	switch {
+	case opts.Position < TopLeft || opts.Position > Center:
+		return errors.New("cinema.Video.Overlay: unknown position " +
+			strconv.Itoa(int(opts.Position)))
+	case opts.Opacity < 0 || opts.Opacity > 1:
+		return errors.New("cinema.Video.Overlay: opacity must be between 0 " +
+			"and 1")
+	case opts.Scale < 0:
+		return errors.New("cinema.Video.Overlay: scale must not be negative")
+	}
+	v.record("Overlay", imagePath, x, y, opts)
+	v.filters = append(v.filters, filter{
+		stage: StageFX,
+		expr:  "overlay=" + overlayPosition(opts.Position, x, y),
+		overlay: &overlaySource{
+			path: imagePath,
+			graph: func(in, out string, width, _ int) string {
+				graph := in + "format=rgba"
+				if opts.Opacity < 1 {
+					graph += ",colorchannelmixer=aa=" + formatFloat(opts.Opacity)
+				}
+				if opts.Scale > 0 {
+					graph += ",scale=" +
+						strconv.Itoa(int(float64(width)*opts.Scale)) + ":-1"
+				}
+				return graph + out
+			},
+		},
+	})
+	return nil
+}
+
+// overlayPosition returns the x and y options of the overlay filter that
+// place the overlay at (x,y) relative to position.
+func overlayPosition(position Position, x, y int) string {
+	left, top := strconv.Itoa(x), strconv.Itoa(y)
+	right := "main_w-overlay_w-" + strconv.Itoa(x)
+	bottom := "main_h-overlay_h-" + strconv.Itoa(y)
+	switch position {
+	case TopRight:
+		return "x=" + right + ":y=" + top
+	case BottomLeft:
+		return "x=" + left + ":y=" + bottom
+	case BottomRight:
+		return "x=" + right + ":y=" + bottom
+	case Center:
+		return "x=(main_w-overlay_w)/2+" + left + ":y=(main_h-overlay_h)/2+" + top
+	}
+	return "x=" + left + ":y=" + top
+}
@@ -0,0 +1,66 @@
+package cinema
+
+import (
+	"errors"
+	"os"
+)
+
+// Pipe is a named pipe (FIFO) that connects ffmpeg to this program or to other
+// processes without writing data to disk. Pass Path to ffmpeg as an input or
+// output file name and open the other end with OpenReader or OpenWriter, or
+// pass it to a second process.
+//
+// On Unix systems the pipe is a FIFO in a temporary directory and any number
+// of processes may open it. On Windows the pipe lives in the \\.\pipe\
+// namespace and this program always owns one end: exactly one of OpenReader
+// or OpenWriter has to be called and exactly one other process can connect to
+// the pipe.
+type Pipe struct {
+	path string
+	sys  pipeSys
+}
+
+// NewPipe creates a new named pipe. Call Close to remove it when it is no
+// longer needed.
+func NewPipe() (*Pipe, error) {
+	p, err := newPipe()
+	if err != nil {
+		return nil, errors.New("cinema.NewPipe: unable to create pipe: " +
+			err.Error())
+	}
+	return p, nil
+}
+
+// Path returns the file name of the pipe.
+func (p *Pipe) Path() string {
+	return p.path
+}
+
+// OpenReader opens the pipe for reading what another process writes into it.
+// It blocks until the other process opens the pipe.
+func (p *Pipe) OpenReader() (*os.File, error) {
+	f, err := p.open(false)
+	if err != nil {
+		return nil, errors.New("cinema.Pipe.OpenReader: " + err.Error())
+	}
+	return f, nil
+}
+
+// OpenWriter opens the pipe for writing data that another process reads from
+// it. It blocks until the other process opens the pipe.
+func (p *Pipe) OpenWriter() (*os.File, error) {
+	f, err := p.open(true)
+	if err != nil {
+		return nil, errors.New("cinema.Pipe.OpenWriter: " + err.Error())
+	}
+	return f, nil
+}
+
+// Close removes the pipe. Ends that are already open stay usable on Unix
+// systems.
+func (p *Pipe) Close() error {
+	if err := p.remove(); err != nil {
+		return errors.New("cinema.Pipe.Close: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build !unix && !windows
+
+package cinema
+
+import (
+	"errors"
+	"os"
+)
+
+type pipeSys struct{}
+
+var errPipeUnsupported = errors.New("named pipes are not supported on this " +
+	"operating system")
+
+func newPipe() (*Pipe, error) {
+	return nil, errPipeUnsupported
+}
+
+func (p *Pipe) open(write bool) (*os.File, error) {
+	return nil, errPipeUnsupported
+}
+
+func (p *Pipe) remove() error {
+	return errPipeUnsupported
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package cinema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+type pipeSys struct {
+	dir string
+}
+
+func newPipe() (*Pipe, error) {
+	dir, err := ioutil.TempDir("", "cinema-pipe-")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "pipe")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &Pipe{path: path, sys: pipeSys{dir: dir}}, nil
+}
+
+func (p *Pipe) open(write bool) (*os.File, error) {
+	if write {
+		return os.OpenFile(p.path, os.O_WRONLY, 0)
+	}
+	return os.OpenFile(p.path, os.O_RDONLY, 0)
+}
+
+func (p *Pipe) remove() error {
+	return os.RemoveAll(p.sys.dir)
+}
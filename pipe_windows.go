@@ -0,0 +1,78 @@
+//go:build windows
+
+package cinema
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procCreateNamedPipeW = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = kernel32.NewProc("ConnectNamedPipe")
+)
+
+const (
+	pipeAccessDuplex   = 0x3
+	pipeTypeByte       = 0x0
+	pipeBufferSize     = 1 << 16
+	errorPipeConnected = syscall.Errno(535)
+)
+
+// pipeCount makes pipe names unique within this process.
+var pipeCount int32
+
+type pipeSys struct {
+	handle syscall.Handle
+	opened bool
+}
+
+func newPipe() (*Pipe, error) {
+	n := atomic.AddInt32(&pipeCount, 1)
+	path := `\\.\pipe\cinema-` + strconv.Itoa(os.Getpid()) + "-" +
+		strconv.Itoa(int(n))
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, _, err := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(name)),
+		pipeAccessDuplex,
+		pipeTypeByte,
+		1, // a single instance, i.e. a single client
+		pipeBufferSize,
+		pipeBufferSize,
+		0,
+		0,
+	)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return nil, err
+	}
+	return &Pipe{path: path, sys: pipeSys{handle: syscall.Handle(h)}}, nil
+}
+
+func (p *Pipe) open(write bool) (*os.File, error) {
+	if p.sys.opened {
+		return nil, errors.New("a pipe can only be opened once on Windows")
+	}
+	r, _, err := procConnectNamedPipe.Call(uintptr(p.sys.handle), 0)
+	if r == 0 && err != errorPipeConnected {
+		return nil, err
+	}
+	p.sys.opened = true
+	return os.NewFile(uintptr(p.sys.handle), p.path), nil
+}
+
+func (p *Pipe) remove() error {
+	// The pipe disappears when its last handle is closed. Once opened, the
+	// handle belongs to the returned file.
+	if p.sys.opened {
+		return nil
+	}
+	return syscall.CloseHandle(p.sys.handle)
+}
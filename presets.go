@@ -0,0 +1,78 @@
+package cinema
+
+import (
+	"math"
+	"time"
+)
+
+// PresetScreencast sets up the encoding for screen recordings like tutorials
+// and demos: H.264 tuned for sharp text and mostly static content, a keyframe
+// only every 10 seconds since most frames differ little, sharp Lanczos
+// scaling that keeps small text readable and Opus audio tuned for speech. Use
+// a container that supports Opus, e.g. MP4, MKV or WebM.
+//
+// The preset replaces the encoder settings of the Video. Call SetCRF,
+// SetBitrate and the like afterwards to adjust them.
+func (v *Video) PresetScreencast() {
+	v.record("PresetScreencast")
+	v.encoder = encoderSettings{
+		codec:       "libx264",
+		crf:         22,
+		hasCRF:      true,
+		preset:      "medium",
+		pixelFormat: "yuv420p",
+		// stillimage keeps text sharp in static scenes. For live streams,
+		// where latency matters more, zerolatency is the better choice.
+		tune:      "stillimage",
+		keyframes: 10 * time.Second,
+	}
+	v.outputArgs = append(v.outputArgs,
+		"-sws_flags", "lanczos+accurate_rnd",
+		"-c:a", "libopus",
+		"-application", "voip",
+		"-b:a", "48k",
+	)
+}
+
+// PresetHighMotion sets up the encoding for fast-moving content like gameplay
+// captures and sports: H.264 at a high quality with psychovisual tuning that
+// keeps fine detail and grain in motion, and a bitrate ceiling relative to the
+// resolution and frame rate that is high enough for fast scenes but keeps
+// the files streamable. The frame rate of the input is kept, rates above 60
+// are reduced to 60, and a variable frame rate, which is common for game
+// captures, is made constant (see ForceCFR). The audio is AAC at 192 kbit/s.
+//
+// The preset replaces the encoder settings of the Video. Call SetCRF,
+// SetBitrate and the like afterwards to adjust them.
+func (v *Video) PresetHighMotion() {
+	v.record("PresetHighMotion")
+	// The output keeps the frame rate of the input, which is the point of
+	// high-motion footage, instead of the default of 30.
+	if parseRate(v.frameRate) > 61 {
+		v.fps = 60
+		v.fpsRate = ""
+	} else if rate := cfrRate(v.frameRate); rate != "" {
+		v.fps = int(math.Round(parseRate(rate)))
+		v.fpsRate = rate
+	}
+	if v.vfr {
+		v.cfr = true
+	}
+	v.encoder = encoderSettings{
+		codec:       "libx264",
+		crf:         18,
+		hasCRF:      true,
+		preset:      "slow",
+		pixelFormat: "yuv420p",
+		// Stronger psychovisual optimization keeps the detail that motion
+		// would otherwise smear, and adaptive quantization spends bits on
+		// dark and flat areas where banding is visible.
+		params: map[string]string{
+			"psy-rd":  "1.0,0.15",
+			"aq-mode": "3",
+		},
+		keyframes:    2 * time.Second,
+		bitsPerPixel: 0.15,
+	}
+	v.outputArgs = append(v.outputArgs, "-c:a", "aac", "-b:a", "192k")
+}
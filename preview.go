@@ -0,0 +1,108 @@
+package cinema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// PreviewOptions configures the preview stream, see SetPreviewStream.
+type PreviewOptions struct {
+	// Width of the preview in pixels. It defaults to 320, the height keeps
+	// the aspect ratio.
+	Width int
+	// SegmentDuration is the length of the HLS segments, shorter segments
+	// mean less latency. It defaults to 1 second.
+	SegmentDuration time.Duration
+	// Playlist is the file name of the HLS playlist in the preview
+	// directory. It defaults to "preview.m3u8".
+	Playlist string
+}
+
+type previewStream struct {
+	dir  string
+	opts PreviewOptions
+}
+
+// SetPreviewStream makes Render write a low resolution HLS live stream of
+// what it is encoding into dir, next to the actual output. Dashboards can play
+// the stream to visually monitor long renders while they are running. Only the
+// most recent segments are kept. The preview is encoded separately from the
+// output, with settings tuned for speed, so it costs little extra time.
+//
+// Pass an empty dir to disable the preview again.
+func (v *Video) SetPreviewStream(dir string, opts PreviewOptions) {
+	v.record("SetPreviewStream", dir, opts)
+	if dir == "" {
+		v.preview = nil
+		return
+	}
+	if opts.Width <= 0 {
+		opts.Width = 320
+	}
+	if opts.SegmentDuration <= 0 {
+		opts.SegmentDuration = time.Second
+	}
+	if opts.Playlist == "" {
+		opts.Playlist = "preview.m3u8"
+	}
+	v.preview = &previewStream{dir: dir, opts: opts}
+}
+
+// previewFilterArgs returns the input and filter arguments like filterArgs,
+// with the filtered video split into the output and the preview.
+func (v *Video) previewFilterArgs() (inputArgs, filterArgs []string) {
+	inputArgs, graph := v.filterGraph(v.hardwareChain(v.chain()))
+	// The preview is encoded on the CPU.
+	download := ""
+	if v.hardware != NoHardware {
+		download = "hwdownload,format=nv12,"
+	}
+	graph += ";[vout]split=2[main][preview]" +
+		";[preview]" + download +
+		"scale=" + strconv.Itoa(v.preview.opts.Width) + ":-2[previewout]"
+	return inputArgs, append([]string{
+		"-filter_complex", graph,
+		"-map", "[main]",
+	}, v.audioMapArgs("0:a?")...)
+}
+
+// previewArgs returns the ffmpeg arguments for the preview output. trim are
+// the trim options of the main output, they have to be repeated because
+// output options only apply to a single output.
+func (v *Video) previewArgs(trim []string) []string {
+	if v.preview == nil {
+		return nil
+	}
+	opts := v.preview.opts
+	args := []string{"-map", "[previewout]"}
+	args = append(args, trim...)
+	return append(args,
+		"-an",
+		"-c:v", "libx264",
+		"-preset", "ultrafast",
+		"-tune", "zerolatency",
+		"-pix_fmt", "yuv420p",
+		"-force_key_frames", "expr:gte(t,n_forced*"+
+			formatFloat(opts.SegmentDuration.Seconds())+")",
+		"-f", "hls",
+		"-hls_time", formatFloat(opts.SegmentDuration.Seconds()),
+		"-hls_list_size", "6",
+		"-hls_flags", "delete_segments+independent_segments",
+		filepath.Join(v.preview.dir, opts.Playlist),
+	)
+}
+
+// createPreviewDir creates the directory of the preview stream, if there is
+// one, because ffmpeg does not create it.
+func (v *Video) createPreviewDir() error {
+	if v.preview == nil {
+		return nil
+	}
+	if err := os.MkdirAll(v.preview.dir, 0755); err != nil {
+		return errors.New("unable to create preview directory: " + err.Error())
+	}
+	return nil
+}
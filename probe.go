@@ -0,0 +1,301 @@
+package cinema
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// ProbeResult is what ffprobe reports about a media file, see Probe.
+type ProbeResult struct {
+	// FormatName is the container format, e.g. "mov,mp4,m4a,3gp,3g2,mj2",
+	// FormatLongName its description.
+	FormatName     string
+	FormatLongName string
+	// Duration is the length of the file, 0 if the container does not
+	// store it. StartTime is its first timestamp.
+	Duration  time.Duration
+	StartTime time.Duration
+	// BitRate is the overall bit rate in bits per second, 0 if unknown.
+	BitRate int
+	// Size is the size of the file in bytes, 0 if unknown.
+	Size int64
+	// Tags is the container metadata, e.g. "title" or "creation_time".
+	Tags map[string]string
+	// Streams are all streams of the file, in order.
+	Streams []StreamInfo
+	// Chapters are the chapters of the file, in order.
+	Chapters []Chapter
+}
+
+// StreamInfo describes a single stream of a media file. Fields that do not
+// apply to the type of the stream or are unknown are empty.
+type StreamInfo struct {
+	// Index is the index of the stream in the file.
+	Index int
+	// Type is "video", "audio", "subtitle", "data" or "attachment".
+	Type string
+	// Codec is the short name of the codec, e.g. "h264" or "aac",
+	// CodecLongName its description. Profile is the codec profile, e.g.
+	// "High".
+	Codec         string
+	CodecLongName string
+	Profile       string
+	// BitRate is the bit rate of the stream in bits per second.
+	BitRate int
+	// Duration is the length of the stream.
+	Duration time.Duration
+	// Frames is the number of frames, or of packets for audio, as stored in
+	// the container.
+	Frames int
+	// Language is the language tag, e.g. "eng".
+	Language string
+	// Default reports whether players select the stream by default.
+	Default bool
+	// Tags is the metadata of the stream.
+	Tags map[string]string
+
+	// Width and Height are the size of video frames as stored, before the
+	// rotation.
+	Width, Height int
+	// PixelFormat is the pixel format of video frames, e.g. "yuv420p".
+	PixelFormat string
+	// FrameRate is the average frame rate as a fraction, e.g. "30000/1001".
+	FrameRate string
+	// ColorSpace, ColorRange, ColorPrimaries and ColorTransfer describe the
+	// colors of video frames, e.g. "bt709", "tv", "bt709" and "bt709".
+	ColorSpace     string
+	ColorRange     string
+	ColorPrimaries string
+	ColorTransfer  string
+	// FieldOrder is "progressive" or the field order of interlaced video,
+	// e.g. "tt".
+	FieldOrder string
+	// Rotation is the rotation of the display in degrees, e.g. 90 or -90.
+	Rotation int
+
+	// SampleRate is the sample rate of audio in Hz.
+	SampleRate int
+	// Channels is the number of audio channels, ChannelLayout their
+	// layout, e.g. "stereo" or "5.1".
+	Channels      int
+	ChannelLayout string
+	// SampleFormat is the format of audio samples, e.g. "fltp".
+	SampleFormat string
+}
+
+// Probe runs ffprobe on the file at path and returns what it reports about
+// the file, e.g. to check the streams of a file before loading it or to read
+// information the Video does not provide. For a loaded Video, Video.Probe
+// returns the same without running ffprobe again.
+func Probe(path string) (*ProbeResult, error) {
+	if err := checkFFprobe("cinema.Probe"); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.New("cinema.Probe: unable to read file: " +
+			err.Error())
+	}
+	out, err := exec.Command("ffprobe", probeArgs(path)...).Output()
+	if err != nil {
+		return nil, errors.New("cinema.Probe: ffprobe failed: " + err.Error())
+	}
+	p, err := parseProbe(out)
+	if err != nil {
+		return nil, errors.New("cinema.Probe: " + err.Error())
+	}
+	return p, nil
+}
+
+// Probe returns what ffprobe reported about the input when it was loaded. The
+// information describes the input file, it is not affected by the operations
+// of the Video. The result is a copy.
+func (v *Video) Probe() *ProbeResult {
+	if v.probe == nil {
+		return nil
+	}
+	p := *v.probe
+	p.Tags = copyTags(v.probe.Tags)
+	p.Streams = make([]StreamInfo, len(v.probe.Streams))
+	for i, s := range v.probe.Streams {
+		s.Tags = copyTags(s.Tags)
+		p.Streams[i] = s
+	}
+	p.Chapters = append([]Chapter(nil), v.probe.Chapters...)
+	return &p
+}
+
+// parseProbe parses the output of ffprobe run with probeArgs.
+func parseProbe(out []byte) (*ProbeResult, error) {
+	var desc struct {
+		Streams []struct {
+			Index          int               `json:"index"`
+			CodecType      string            `json:"codec_type"`
+			CodecName      string            `json:"codec_name"`
+			CodecLongName  string            `json:"codec_long_name"`
+			Profile        string            `json:"profile"`
+			BitRate        json.Number       `json:"bit_rate"`
+			DurationSec    json.Number       `json:"duration"`
+			NbFrames       json.Number       `json:"nb_frames"`
+			Width          int               `json:"width"`
+			Height         int               `json:"height"`
+			PixelFormat    string            `json:"pix_fmt"`
+			AvgFrameRate   string            `json:"avg_frame_rate"`
+			ColorSpace     string            `json:"color_space"`
+			ColorRange     string            `json:"color_range"`
+			ColorPrimaries string            `json:"color_primaries"`
+			ColorTransfer  string            `json:"color_transfer"`
+			FieldOrder     string            `json:"field_order"`
+			SampleRate     json.Number       `json:"sample_rate"`
+			Channels       int               `json:"channels"`
+			ChannelLayout  string            `json:"channel_layout"`
+			SampleFormat   string            `json:"sample_fmt"`
+			Disposition    map[string]int    `json:"disposition"`
+			Tags           map[string]string `json:"tags"`
+			SideData       []struct {
+				Rotation json.Number `json:"rotation"`
+			} `json:"side_data_list"`
+		} `json:"streams"`
+		Format struct {
+			FormatName     string            `json:"format_name"`
+			FormatLongName string            `json:"format_long_name"`
+			DurationSec    json.Number       `json:"duration"`
+			StartSec       json.Number       `json:"start_time"`
+			BitRate        json.Number       `json:"bit_rate"`
+			Size           json.Number       `json:"size"`
+			Tags           map[string]string `json:"tags"`
+		} `json:"format"`
+		Chapters []struct {
+			StartSec json.Number `json:"start_time"`
+			EndSec   json.Number `json:"end_time"`
+			Tags     struct {
+				Title string `json:"title"`
+			} `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return nil, errors.New("unable to parse JSON output from ffprobe: " +
+			err.Error())
+	}
+	invalid := func(what string, err error) error {
+		return errors.New("ffprobe returned invalid " + what + ": " +
+			err.Error())
+	}
+
+	f := desc.Format
+	p := &ProbeResult{
+		FormatName:     f.FormatName,
+		FormatLongName: f.FormatLongName,
+		Tags:           f.Tags,
+	}
+	var err error
+	if p.Duration, err = probeTime(f.DurationSec); err != nil {
+		return nil, invalid("duration", err)
+	}
+	if p.StartTime, err = probeTime(f.StartSec); err != nil {
+		return nil, invalid("start time", err)
+	}
+	if p.BitRate, err = probeInt(f.BitRate); err != nil {
+		return nil, invalid("bit rate", err)
+	}
+	if f.Size != "" {
+		if p.Size, err = f.Size.Int64(); err != nil {
+			return nil, invalid("size", err)
+		}
+	}
+
+	for _, s := range desc.Streams {
+		info := StreamInfo{
+			Index:          s.Index,
+			Type:           s.CodecType,
+			Codec:          s.CodecName,
+			CodecLongName:  s.CodecLongName,
+			Profile:        s.Profile,
+			Language:       s.Tags["language"],
+			Default:        s.Disposition["default"] == 1,
+			Tags:           s.Tags,
+			Width:          s.Width,
+			Height:         s.Height,
+			PixelFormat:    s.PixelFormat,
+			ColorSpace:     s.ColorSpace,
+			ColorRange:     s.ColorRange,
+			ColorPrimaries: s.ColorPrimaries,
+			ColorTransfer:  s.ColorTransfer,
+			FieldOrder:     s.FieldOrder,
+			Channels:       s.Channels,
+			ChannelLayout:  s.ChannelLayout,
+			SampleFormat:   s.SampleFormat,
+		}
+		if s.AvgFrameRate != "0/0" {
+			info.FrameRate = s.AvgFrameRate
+		}
+		if info.BitRate, err = probeInt(s.BitRate); err != nil {
+			return nil, invalid("stream bit rate", err)
+		}
+		if info.Duration, err = probeTime(s.DurationSec); err != nil {
+			return nil, invalid("stream duration", err)
+		}
+		if info.Frames, err = probeInt(s.NbFrames); err != nil {
+			return nil, invalid("frame count", err)
+		}
+		if info.SampleRate, err = probeInt(s.SampleRate); err != nil {
+			return nil, invalid("sample rate", err)
+		}
+		// Older versions of ffmpeg store the rotation as a tag, newer ones
+		// as side data with the opposite sign.
+		if rotate, ok := s.Tags["rotate"]; ok {
+			if info.Rotation, err = strconv.Atoi(rotate); err != nil {
+				return nil, invalid("rotation", err)
+			}
+		}
+		for _, d := range s.SideData {
+			if d.Rotation == "" {
+				continue
+			}
+			r, err := probeInt(d.Rotation)
+			if err != nil {
+				return nil, invalid("rotation", err)
+			}
+			info.Rotation = -r
+		}
+		p.Streams = append(p.Streams, info)
+	}
+
+	for _, c := range desc.Chapters {
+		start, err := probeTime(c.StartSec)
+		if err != nil {
+			return nil, invalid("chapter start", err)
+		}
+		end, err := probeTime(c.EndSec)
+		if err != nil {
+			return nil, invalid("chapter end", err)
+		}
+		p.Chapters = append(p.Chapters, Chapter{
+			Title: c.Tags.Title,
+			Start: start,
+			End:   end,
+		})
+	}
+	return p, nil
+}
+
+// probeInt parses an integer reported by ffprobe. It returns 0 if the value
+// is not set.
+func probeInt(n json.Number) (int, error) {
+	if n == "" || n == "N/A" {
+		return 0, nil
+	}
+	i, err := strconv.ParseInt(string(n), 10, 64)
+	if err != nil {
+		// Some values, e.g. rotations, can be written as floats.
+		f, ferr := n.Float64()
+		if ferr != nil {
+			return 0, err
+		}
+		return int(f), nil
+	}
+	return int(i), nil
+}
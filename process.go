@@ -0,0 +1,146 @@
+package cinema
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// processes are the ffmpeg processes that are currently running.
+var processes = struct {
+	sync.Mutex
+	cmds map[*exec.Cmd]*process
+}{cmds: make(map[*exec.Cmd]*process)}
+
+// process is the state of a running ffmpeg process.
+type process struct {
+	// memoryLimit is the limit set with SetMemoryLimit, 0 if there is none.
+	memoryLimit int64
+	// allocations watches the log for allocations that failed, nil if
+	// there is no memory limit.
+	allocations *allocationLog
+	// killed reports whether the process was killed by KillAll.
+	killed bool
+}
+
+// startProcess starts cmd in its own process group and registers it so that
+// KillAll can find it. If memoryLimit is greater than 0, the process may use
+// at most that many bytes of memory where the operating system supports it.
+func startProcess(cmd *exec.Cmd, memoryLimit int64) error {
+	p := &process{memoryLimit: memoryLimit}
+	if memoryLimit > 0 {
+		limitMemory(cmd, memoryLimit)
+		p.allocations = &allocationLog{}
+		if cmd.Stderr == nil {
+			cmd.Stderr = p.allocations
+		} else {
+			cmd.Stderr = io.MultiWriter(cmd.Stderr, p.allocations)
+		}
+	}
+	processes.Lock()
+	defer processes.Unlock()
+	if err := startCommand(cmd); err != nil {
+		return err
+	}
+	processes.cmds[cmd] = p
+	return nil
+}
+
+// waitProcess waits for cmd, which was started by startProcess, to exit. It
+// returns a *MemoryError if the process was killed by the operating system,
+// which usually happens when the system runs out of memory, or if ffmpeg
+// failed to allocate memory under a memory limit.
+func waitProcess(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	processes.Lock()
+	p := processes.cmds[cmd]
+	delete(processes.cmds, cmd)
+	processes.Unlock()
+	if err != nil && p != nil && !p.killed &&
+		(killedBySystem(err) || p.allocations.failed()) {
+		return &MemoryError{Limit: p.memoryLimit, Err: err}
+	}
+	return err
+}
+
+// allocationLog is a writer for the log output of ffmpeg that notices the
+// messages of failed memory allocations, which is how ffmpeg fails when it
+// reaches a memory limit.
+type allocationLog struct {
+	mu sync.Mutex
+	// tail is the end of the output so far, so that messages split over
+	// two writes are found.
+	tail    []byte
+	failure bool
+}
+
+// allocationMessages are the messages of ENOMEM, in lower case, of glibc and
+// musl.
+var allocationMessages = [][]byte{
+	[]byte("cannot allocate memory"),
+	[]byte("out of memory"),
+}
+
+func (l *allocationLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failure {
+		return len(p), nil
+	}
+	buf := bytes.ToLower(append(append([]byte(nil), l.tail...), p...))
+	for _, m := range allocationMessages {
+		l.failure = l.failure || bytes.Contains(buf, m)
+	}
+	if len(buf) > 32 {
+		buf = buf[len(buf)-32:]
+	}
+	l.tail = buf
+	return len(p), nil
+}
+
+// failed reports whether an allocation failed. It is false for a nil log.
+func (l *allocationLog) failed() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.failure
+}
+
+// killProcess kills cmd, which was started by startProcess, together with
+// all processes it started.
+func killProcess(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		killProcessGroup(cmd)
+	}
+}
+
+// cancelProcess kills cmd, which was started by startProcess, like KillAll.
+func cancelProcess(cmd *exec.Cmd) {
+	processes.Lock()
+	defer processes.Unlock()
+	if p, ok := processes.cmds[cmd]; ok {
+		p.killed = true
+		killProcess(cmd)
+	}
+}
+
+// KillAll kills all ffmpeg processes that were started by this package and
+// are still running, e.g. from a signal handler before the program exits.
+// The renders they belong to return an error.
+//
+// Every ffmpeg process runs in its own process group, so it does not receive
+// signals meant for this program, e.g. when Ctrl+C is pressed in a terminal,
+// and it can be killed together with any processes it started. On Linux, the
+// processes are also killed when this program dies without calling KillAll.
+// On other systems, call KillAll before exiting, or ffmpeg keeps running.
+func KillAll() {
+	processes.Lock()
+	defer processes.Unlock()
+	for cmd, p := range processes.cmds {
+		p.killed = true
+		killProcess(cmd)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build linux
+
+package cinema
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// setProcessGroup makes cmd run in its own process group. The kernel kills it
+// when this process dies, see startCommand.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+}
+
+// starter is the goroutine that starts all processes, see startCommand.
+var starter struct {
+	once     sync.Once
+	requests chan startRequest
+}
+
+// startRequest asks the starter to start cmd and to send the result to err.
+type startRequest struct {
+	cmd *exec.Cmd
+	err chan error
+}
+
+// startCommand starts cmd. The kernel sends the signal of Pdeathsig when the
+// thread that started the process exits, not when this process dies, and Go
+// ends a thread when a goroutine that is locked to it exits. So all processes
+// are started by a goroutine that is locked to its thread and never exits,
+// otherwise ffmpeg could be killed in the middle of a render.
+func startCommand(cmd *exec.Cmd) error {
+	starter.once.Do(func() {
+		starter.requests = make(chan startRequest)
+		go func() {
+			runtime.LockOSThread()
+			for r := range starter.requests {
+				r.err <- r.cmd.Start()
+			}
+		}()
+	})
+	err := make(chan error, 1)
+	starter.requests <- startRequest{cmd: cmd, err: err}
+	return <-err
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	// A negative pid signals the whole process group.
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// killedBySystem reports whether err says that the process was killed with
+// SIGKILL, which is what the out-of-memory killer sends.
+func killedBySystem(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// limitMemory makes cmd limit the address space of its process before it
+// runs. The limit has to be set before ffmpeg starts, so cmd is changed to
+// run a shell that sets it and then replaces itself with ffmpeg, which keeps
+// the process id. If the program of cmd was not found, cmd is left unchanged
+// so that starting it reports the error.
+func limitMemory(cmd *exec.Cmd, bytes int64) {
+	if !filepath.IsAbs(cmd.Path) {
+		return
+	}
+	// ulimit takes kibibytes.
+	kib := strconv.FormatInt((bytes+1023)/1024, 10)
+	cmd.Args = append([]string{
+		"sh", "-c", `ulimit -v ` + kib + ` && exec "$0" "$@"`, cmd.Path,
+	}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+}
@@ -0,0 +1,28 @@
+//go:build !unix && !windows
+
+package cinema
+
+import "os/exec"
+
+// setProcessGroup does nothing, process groups are not supported on this
+// operating system.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// startCommand starts cmd.
+func startCommand(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+// killedBySystem reports whether err says that the process was killed by the
+// operating system, which cannot be told apart from other failures here.
+func killedBySystem(err error) bool {
+	return false
+}
+
+// limitMemory does nothing, the memory of processes cannot be limited on
+// this operating system.
+func limitMemory(cmd *exec.Cmd, bytes int64) {}
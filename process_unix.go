@@ -0,0 +1,38 @@
+//go:build unix && !linux
+
+package cinema
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes cmd run in its own process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// startCommand starts cmd.
+func startCommand(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	// A negative pid signals the whole process group.
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// killedBySystem reports whether err says that the process was killed with
+// SIGKILL, which is what the out-of-memory killer sends.
+func killedBySystem(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// limitMemory does nothing, the memory of processes cannot be limited on
+// this operating system.
+func limitMemory(cmd *exec.Cmd, bytes int64) {}
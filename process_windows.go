@@ -0,0 +1,39 @@
+//go:build windows
+
+package cinema
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// createNewProcessGroup keeps Ctrl+C in the console from reaching the
+// process.
+const createNewProcessGroup = 0x00000200
+
+// setProcessGroup makes cmd run in its own process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: createNewProcessGroup,
+	}
+}
+
+// startCommand starts cmd.
+func startCommand(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	// ffmpeg does not start other processes, so killing it is enough.
+	cmd.Process.Kill()
+}
+
+// killedBySystem reports whether err says that the process was killed by the
+// operating system, which cannot be told apart from other failures here.
+func killedBySystem(err error) bool {
+	return false
+}
+
+// limitMemory does nothing, the memory of processes cannot be limited on
+// this operating system.
+func limitMemory(cmd *exec.Cmd, bytes int64) {}
@@ -0,0 +1,272 @@
+package cinema
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Profile tells how much work is needed to produce an output that meets an
+// OutputSpec. See SuggestProfile and RenderCompliant.
+type Profile int
+
+const (
+	// ProfileCopy means the input file already meets the spec as it is and
+	// can simply be copied.
+	ProfileCopy Profile = iota
+	// ProfileRemux means all streams are compliant, they only need to be
+	// copied into a new container, which is very fast and lossless.
+	ProfileRemux
+	// ProfileTranscodeAudio means the video stream can be copied but the
+	// audio has to be transcoded.
+	ProfileTranscodeAudio
+	// ProfileTranscode means the video has to be re-encoded.
+	ProfileTranscode
+)
+
+func (p Profile) String() string {
+	switch p {
+	case ProfileCopy:
+		return "copy"
+	case ProfileRemux:
+		return "remux"
+	case ProfileTranscodeAudio:
+		return "transcode audio"
+	case ProfileTranscode:
+		return "transcode"
+	}
+	return "unknown profile"
+}
+
+// OutputSpec describes the requirements an output file has to meet, e.g. for a
+// playback device or platform. Empty fields mean there is no requirement.
+type OutputSpec struct {
+	// Formats are the accepted container formats, as reported by ffprobe,
+	// e.g. "mp4" or "matroska".
+	Formats []string
+	// VideoCodecs are the accepted video codecs, as reported by ffprobe,
+	// e.g. "h264" or "hevc".
+	VideoCodecs []string
+	// PixelFormats are the accepted pixel formats, e.g. "yuv420p".
+	PixelFormats []string
+	// AudioCodecs are the accepted audio codecs, as reported by ffprobe,
+	// e.g. "aac" or "opus".
+	AudioCodecs []string
+	// MaxWidth and MaxHeight limit the resolution in pixels.
+	MaxWidth, MaxHeight int
+	// MaxBitrate limits the overall bit rate in bits per second.
+	MaxBitrate int
+
+	// VideoEncoder and AudioEncoder are the ffmpeg encoders used when the
+	// video or audio has to be transcoded, e.g. "libx264" and "aac". If
+	// empty, ffmpeg chooses the encoder based on the output file extension.
+	VideoEncoder, AudioEncoder string
+}
+
+// WebSpec is the OutputSpec for web playback: H.264 video with 8 bit 4:2:0
+// colors and AAC or MP3 audio in an MP4 container.
+var WebSpec = OutputSpec{
+	Formats:      []string{"mp4"},
+	VideoCodecs:  []string{"h264"},
+	PixelFormats: []string{"yuv420p"},
+	AudioCodecs:  []string{"aac", "mp3"},
+	VideoEncoder: "libx264",
+	AudioEncoder: "aac",
+}
+
+// SuggestProfile inspects the input file and the operations applied to the
+// Video and returns the least amount of work needed to produce a file that
+// meets WebSpec. Use it to avoid pointless full re-encodes of files that are
+// already compliant.
+func (v *Video) SuggestProfile() (Profile, error) {
+	if v.videoCodec == "" {
+		return ProfileTranscode, errors.New("cinema.Video.SuggestProfile: " +
+			"the input has no video stream")
+	}
+	return v.profile(WebSpec), nil
+}
+
+// RenderCompliant renders the Video to output doing as little work as
+// possible to meet spec: if the input already meets it, the file is copied or
+// remuxed instead of transcoded, see Profile. The Profile that was used is
+// returned.
+//
+// Copying and remuxing ignore the framerate set with SetFPS. When the trimmed
+// input is remuxed, the cuts happen at the nearest keyframes.
+func (v *Video) RenderCompliant(output string, spec OutputSpec) (Profile, error) {
+	profile := v.profile(spec)
+	if err := v.checkTrim("cinema.Video.RenderCompliant"); err != nil {
+		return profile, err
+	}
+	if profile == ProfileCopy &&
+		!strings.EqualFold(filepath.Ext(output), filepath.Ext(v.filepath)) {
+		profile = ProfileRemux
+	}
+
+	var err error
+	switch profile {
+	case ProfileCopy:
+		err = copyFile(v.filepath, output)
+	case ProfileRemux, ProfileTranscodeAudio:
+		line := []string{
+			"ffmpeg",
+			"-y",
+			"-i", v.filepath,
+		}
+		line = append(line, v.trimArgs(v.start, v.end)...)
+		line = append(line, "-c", "copy")
+		// The subtitle codec of the selection has to come after -c.
+		if v.streams.selected() {
+			line = append(line, v.streamArgs(nil, output)...)
+		} else {
+			line = append(line, "-map", "0")
+		}
+		line = append(line, copyArgs()...)
+		if profile == ProfileTranscodeAudio {
+			line = append(line, v.audioArgs()...)
+			line = append(line, "-c:a", orDefault(spec.AudioEncoder, "aac"))
+		}
+		err = v.runFFmpeg(append(line, output))
+	default:
+		video := v.snapshot()
+		if spec.MaxWidth > 0 || spec.MaxHeight > 0 {
+			video.fitInto(spec.MaxWidth, spec.MaxHeight)
+		}
+		var args []string
+		if spec.VideoEncoder != "" {
+			args = append(args, "-c:v", spec.VideoEncoder)
+		}
+		if spec.AudioEncoder != "" {
+			args = append(args, "-c:a", spec.AudioEncoder)
+		}
+		if len(spec.PixelFormats) > 0 {
+			args = append(args, "-pix_fmt", spec.PixelFormats[0])
+		}
+		if spec.MaxBitrate > 0 {
+			rate := strconv.Itoa(spec.MaxBitrate)
+			args = append(args,
+				"-maxrate", rate,
+				"-bufsize", strconv.Itoa(2*spec.MaxBitrate),
+			)
+		}
+		var cleanup func()
+		if cleanup, err = video.prepareRender(); err != nil {
+			break
+		}
+		err = video.runFFmpeg(video.commandLine(output, args...))
+		cleanup()
+	}
+	if m, ok := err.(*MemoryError); ok {
+		m.Op = "cinema.Video.RenderCompliant"
+		return profile, m
+	}
+	if err != nil {
+		return profile, errors.New("cinema.Video.RenderCompliant: unable to " +
+			profile.String() + ": " + err.Error())
+	}
+	return profile, nil
+}
+
+// profile returns the least amount of work needed to meet spec.
+func (v *Video) profile(spec OutputSpec) Profile {
+	width, height := v.width, v.height
+	videoOK := !v.videoModified() &&
+		accepts(spec.VideoCodecs, v.videoCodec) &&
+		accepts(spec.PixelFormats, v.pixelFormat) &&
+		(spec.MaxWidth <= 0 || width <= spec.MaxWidth) &&
+		(spec.MaxHeight <= 0 || height <= spec.MaxHeight) &&
+		(spec.MaxBitrate <= 0 || (v.bitrate > 0 && v.bitrate <= spec.MaxBitrate))
+	if !videoOK {
+		return ProfileTranscode
+	}
+
+	audioOK := v.audioCodec == "" ||
+		(len(v.audioFilters) == 0 && accepts(spec.AudioCodecs, v.audioCodec))
+	if !audioOK {
+		return ProfileTranscodeAudio
+	}
+
+	formatOK := len(spec.Formats) == 0
+	for _, f := range strings.Split(v.formatName, ",") {
+		formatOK = formatOK || accepts(spec.Formats, f)
+	}
+	if formatOK && v.start == 0 && v.end == v.duration && !v.streams.selected() {
+		return ProfileCopy
+	}
+	return ProfileRemux
+}
+
+// videoModified reports whether any operation changes the video frames or
+// adds to the output what copying and remuxing the input leave out, e.g.
+// subtitle tracks. The final fps filter is not considered, so an unchanged
+// Video is assumed to keep its framerate.
+func (v *Video) videoModified() bool {
+	return len(v.filters) > 0 || v.timecode != nil || v.guides != NoGuides ||
+		v.forensic != nil || len(v.ramp) > 0 || v.speedFilter() != "" ||
+		v.reversed != nil || v.fadeIn > 0 || v.fadeOut > 0 ||
+		v.stabilizer != nil || v.colorFilter() != "" || v.cfr ||
+		len(v.subtitles) > 0 || v.writeChapters || v.sanitize ||
+		v.metadata != nil
+}
+
+// fitInto scales the output down so it fits into maxWidth x maxHeight, keeping
+// the aspect ratio. A limit of 0 means no limit.
+func (v *Video) fitInto(maxWidth, maxHeight int) {
+	width, height := v.OutputWidth(), v.OutputHeight()
+	if width <= 0 || height <= 0 {
+		return
+	}
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && float64(height)*scale > float64(maxHeight) {
+		scale = float64(maxHeight) / float64(height)
+	}
+	if scale < 1 {
+		// Most encoders need even dimensions.
+		v.SetSize(int(float64(width)*scale)/2*2, int(float64(height)*scale)/2*2)
+	}
+}
+
+// accepts reports whether value is in the list of accepted values. An empty
+// list accepts everything.
+func accepts(accepted []string, value string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	for _, a := range accepted {
+		if a == value {
+			return true
+		}
+	}
+	return false
+}
+
+// orDefault returns s or def if s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
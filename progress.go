@@ -0,0 +1,192 @@
+package cinema
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress is a progress report of a running render, see RenderJob.
+type Progress struct {
+	// Frame is the number of frames written so far.
+	Frame int
+	// Time is the duration of the output written so far.
+	Time time.Duration
+	// Percent is how much of the output is written, from 0 to 100. It is 0
+	// if the length of the output is not known, e.g. when following a
+	// growing input.
+	Percent float64
+	// Speed is how fast the render runs relative to playback speed, e.g. 2
+	// means that a minute of output takes 30 seconds to render.
+	Speed float64
+}
+
+// RenderJob is a render running in the background, see StartRender.
+type RenderJob struct {
+	progress chan Progress
+	done     chan struct{}
+	err      error
+	warnings *warningLog
+
+	mu        sync.Mutex
+	callbacks []func(Progress)
+	cancel    func()
+}
+
+// StartRender starts rendering the Video to output like Render but returns
+// right away. Use the returned RenderJob to follow the progress of the render
+// and to wait for it to finish.
+//
+// ffmpeg writes its progress reports to its standard output, so the command
+// line has an additional -progress option compared to CommandLine.
+func (v *Video) StartRender(output string) (*RenderJob, error) {
+	if err := v.checkTrim("cinema.Video.StartRender"); err != nil {
+		return nil, err
+	}
+	// The render runs on a copy, so the Video can be changed while it runs.
+	clip := v.snapshot()
+	cleanup, err := clip.prepareRender()
+	if err != nil {
+		return nil, errors.New("cinema.Video.StartRender: " + err.Error())
+	}
+	line := clip.CommandLine(output)
+	line = append([]string{line[0], "-progress", "pipe:1"}, line[1:]...)
+	cmd := v.command(line)
+	warnings := &warningLog{}
+	cmd.Stderr = io.MultiWriter(cmd.Stderr, warnings)
+	cmd.Stdout = nil
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cleanup()
+		return nil, errors.New("cinema.Video.StartRender: unable to read " +
+			"progress: " + err.Error())
+	}
+	if err := startProcess(cmd, v.memoryLimit); err != nil {
+		cleanup()
+		return nil, errors.New("cinema.Video.StartRender: unable to start " +
+			"ffmpeg: " + err.Error())
+	}
+
+	j := &RenderJob{
+		progress: make(chan Progress, 1),
+		done:     make(chan struct{}),
+		warnings: warnings,
+		cancel:   func() { cancelProcess(cmd) },
+	}
+	length := v.outputTime(v.end) - v.outputTime(v.start)
+	if v.following() {
+		length = 0
+	}
+	go func() {
+		// ffmpeg writes a block of key=value lines for each report, which
+		// ends with progress=continue or progress=end.
+		var p Progress
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			kv := strings.SplitN(scanner.Text(), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			key, value := kv[0], strings.TrimSpace(kv[1])
+			switch key {
+			case "frame":
+				p.Frame, _ = strconv.Atoi(value)
+			case "out_time_us":
+				if us, err := strconv.ParseInt(value, 10, 64); err == nil {
+					p.Time = time.Duration(us) * time.Microsecond
+				}
+			case "drop_frames":
+				if drops, err := strconv.Atoi(value); err == nil {
+					warnings.droppedFrames(drops)
+				}
+			case "speed":
+				p.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+			case "progress":
+				if length > 0 {
+					p.Percent = 100 * float64(p.Time) / float64(length)
+					if p.Percent > 100 {
+						p.Percent = 100
+					}
+				}
+				j.report(p)
+			}
+		}
+		err := waitProcess(cmd)
+		cleanup()
+		if err != nil {
+			j.err = ffmpegFailed("cinema.Video.StartRender", err)
+		}
+		close(j.progress)
+		close(j.done)
+	}()
+	return j, nil
+}
+
+// report passes p to the callbacks and the progress channel.
+func (j *RenderJob) report(p Progress) {
+	j.mu.Lock()
+	callbacks := j.callbacks
+	j.mu.Unlock()
+	for _, f := range callbacks {
+		f(p)
+	}
+
+	// The channel holds only the latest report, so a slow reader never
+	// blocks the render. This is the only sender, so after draining the
+	// channel the send cannot block.
+	select {
+	case j.progress <- p:
+	default:
+		select {
+		case <-j.progress:
+		default:
+		}
+		j.progress <- p
+	}
+}
+
+// OnProgress registers f to be called with each progress report, about every
+// half second. f is called from a separate goroutine and must not block, or
+// the render waits for it.
+func (j *RenderJob) OnProgress(f func(Progress)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.callbacks = append(j.callbacks, f)
+}
+
+// ProgressChan returns a channel that receives the progress reports of the
+// render, e.g. for use in a select statement. It is closed when the render
+// ends. Reports that are not received before the next one arrives are
+// dropped, so the channel always holds the latest report.
+func (j *RenderJob) ProgressChan() <-chan Progress {
+	return j.progress
+}
+
+// Cancel stops the render. Wait returns an error afterwards.
+func (j *RenderJob) Cancel() {
+	j.cancel()
+}
+
+// Done returns a channel that is closed when the render ends.
+func (j *RenderJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for the render to end and returns its error, if any.
+func (j *RenderJob) Wait() error {
+	<-j.done
+	return j.err
+}
+
+// Warnings returns the problems ffmpeg reported so far that did not make the
+// render fail, e.g. to flag a degraded output after Wait returned nil. Each
+// kind of problem is listed once, in the order they first occurred. Only
+// problems that ffmpeg logs at the level set with SetFFmpegLogLevel are
+// detected.
+func (j *RenderJob) Warnings() []Warning {
+	return j.warnings.list()
+}
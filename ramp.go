@@ -0,0 +1,222 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SpeedKeyframe sets the playback speed at a point in time, see SpeedRamp.
+type SpeedKeyframe struct {
+	// At is the time in the input video.
+	At time.Duration
+	// Speed is the playback speed at that time, e.g. 0.5 for half speed or
+	// 2 for double speed.
+	Speed float64
+}
+
+// rampStep is the interval in which the audio tempo follows a speed ramp.
+const rampStep = 100 * time.Millisecond
+
+// SpeedRamp changes the playback speed over time. Between two keyframes the
+// speed changes linearly, before the first and after the last keyframe it
+// stays constant. This makes it possible to e.g. slow down a single moment of
+// an otherwise real-time clip. Calling SpeedRamp again replaces the previous
+// ramp, calling it without keyframes removes it.
+//
+// The audio tempo follows the speed in steps of 100ms. Audio pitch is always
+// preserved, PreservePitch does not apply to speed ramps. For audio speeds
+// outside of 0.25 to 4 the audio is clamped to that range and gets out of sync.
+//
+// An error is returned if a speed is not positive.
+func (v *Video) SpeedRamp(points []SpeedKeyframe) error {
+	for _, p := range points {
+		if p.Speed <= 0 {
+			return errors.New("cinema.Video.SpeedRamp: speed must be positive")
+		}
+	}
+	v.record("SpeedRamp", points)
+	ramp := append([]SpeedKeyframe(nil), points...)
+	sort.SliceStable(ramp, func(i, j int) bool {
+		return ramp[i].At < ramp[j].At
+	})
+	v.ramp = ramp
+	return nil
+}
+
+// rampSegment is a section of the timeline in which the speed changes
+// linearly from startSpeed to endSpeed.
+type rampSegment struct {
+	start, end           float64 // input time in seconds
+	startSpeed, endSpeed float64
+	offset               float64 // output time at start in seconds
+}
+
+// segments splits the ramp into linear sections covering the whole timeline.
+// The first one starts at 0 and the last one has no end (+Inf).
+func (v *Video) segments() []rampSegment {
+	if len(v.ramp) == 0 {
+		return nil
+	}
+	first := v.ramp[0]
+	segments := []rampSegment{{
+		start:      0,
+		end:        first.At.Seconds(),
+		startSpeed: first.Speed,
+		endSpeed:   first.Speed,
+	}}
+	for i := 1; i < len(v.ramp); i++ {
+		a, b := v.ramp[i-1], v.ramp[i]
+		segments = append(segments, rampSegment{
+			start:      a.At.Seconds(),
+			end:        b.At.Seconds(),
+			startSpeed: a.Speed,
+			endSpeed:   b.Speed,
+		})
+	}
+	last := v.ramp[len(v.ramp)-1]
+	segments = append(segments, rampSegment{
+		start:      last.At.Seconds(),
+		end:        math.Inf(1),
+		startSpeed: last.Speed,
+		endSpeed:   last.Speed,
+	})
+	for i := 1; i < len(segments); i++ {
+		prev := segments[i-1]
+		segments[i].offset = prev.offset + prev.outputTime(prev.end)
+	}
+	return segments
+}
+
+// slope returns how much the speed changes per second of input.
+func (s rampSegment) slope() float64 {
+	if s.end <= s.start || math.IsInf(s.end, 1) {
+		return 0
+	}
+	return (s.endSpeed - s.startSpeed) / (s.end - s.start)
+}
+
+// outputTime returns how long it takes to play the segment from its start up
+// to the input time t, which is the integral of 1/speed.
+func (s rampSegment) outputTime(t float64) float64 {
+	if k := s.slope(); k != 0 {
+		return math.Log((s.startSpeed+k*(t-s.start))/s.startSpeed) / k
+	}
+	return (t - s.start) / s.startSpeed
+}
+
+// inputTime returns the input time at which the segment has played for the
+// output duration o, the inverse of outputTime.
+func (s rampSegment) inputTime(o float64) float64 {
+	if k := s.slope(); k != 0 {
+		return s.start + s.startSpeed*(math.Exp(k*o)-1)/k
+	}
+	return s.start + o*s.startSpeed
+}
+
+// speedAt returns the speed at input time t.
+func (s rampSegment) speedAt(t float64) float64 {
+	return s.startSpeed + s.slope()*(t-s.start)
+}
+
+// expr returns the ffmpeg expression for the output time of input time T,
+// see outputTime.
+func (s rampSegment) expr() string {
+	d := "(T-" + formatFloat(s.start) + ")"
+	if k := s.slope(); k != 0 {
+		slope := "(" + formatFloat(k) + ")"
+		return formatFloat(s.offset) + "+log((" + formatFloat(s.startSpeed) +
+			"+" + slope + "*" + d + ")/" +
+			formatFloat(s.startSpeed) + ")/" + slope
+	}
+	return formatFloat(s.offset) + "+" + d + "/" + formatFloat(s.startSpeed)
+}
+
+// outputTime converts a time in the input video to the corresponding time in
+// the output video, taking speed changes into account.
+func (v *Video) outputTime(t time.Duration) time.Duration {
+	secs := t.Seconds()
+	for _, s := range v.segments() {
+		if secs < s.end {
+			out := s.offset + s.outputTime(secs)
+			return v.speedOutputTime(time.Duration(out*float64(time.Second) + 0.5))
+		}
+	}
+	return v.speedOutputTime(t)
+}
+
+// inputTime converts a time in the output video to the corresponding time in
+// the input video, the inverse of outputTime.
+func (v *Video) inputTime(t time.Duration) time.Duration {
+	t = v.speedInputTime(t)
+	secs := t.Seconds()
+	for _, s := range v.segments() {
+		if s.end == math.Inf(1) || secs < s.offset+s.outputTime(s.end) {
+			in := s.inputTime(secs - s.offset)
+			return time.Duration(math.Round(in * float64(time.Second)))
+		}
+	}
+	return t
+}
+
+// rampFilter returns the setpts filter for the speed ramp or the empty string
+// if there is no ramp.
+func (v *Video) rampFilter() string {
+	segments := v.segments()
+	if len(segments) == 0 {
+		return ""
+	}
+	expr := segments[len(segments)-1].expr()
+	for i := len(segments) - 2; i >= 0; i-- {
+		s := segments[i]
+		expr = "if(lt(T," + formatFloat(s.end) + ")," + s.expr() + "," +
+			expr + ")"
+	}
+	return "setpts=" + filterValue("("+expr+")/TB")
+}
+
+// rampAudioFilters returns the audio filters that make the audio follow the
+// speed ramp. Two atempo filters, each changing the tempo by the square root
+// of the speed, are controlled by asendcmd so that the speed can go from 0.25
+// to 4.
+func (v *Video) rampAudioFilters() []string {
+	segments := v.segments()
+	if len(segments) == 0 {
+		return nil
+	}
+
+	var commands []string
+	command := func(t, speed float64) {
+		tempo := rampTempo(speed)
+		commands = append(commands, formatFloat(t)+
+			" atempo@ramp0 tempo "+tempo+", atempo@ramp1 tempo "+tempo)
+	}
+	for _, s := range segments {
+		if s.slope() == 0 {
+			command(s.start, s.startSpeed)
+			continue
+		}
+		step := rampStep.Seconds()
+		steps := int(math.Ceil((s.end - s.start) / step))
+		for i := 0; i < steps; i++ {
+			t := s.start + float64(i)*step
+			next := math.Min(t+step, s.end)
+			command(t, s.speedAt((t+next)/2))
+		}
+	}
+
+	first := rampTempo(segments[0].startSpeed)
+	return []string{
+		"asendcmd=c=" + filterValue(strings.Join(commands, ";")),
+		"atempo@ramp0=" + first,
+		"atempo@ramp1=" + first,
+	}
+}
+
+// rampTempo returns the tempo for each of the two atempo filters of a speed
+// ramp at the given speed.
+func rampTempo(speed float64) string {
+	return formatFloat(math.Sqrt(math.Max(0.25, math.Min(4, speed))))
+}
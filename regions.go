@@ -0,0 +1,107 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Rect is a rectangle in a video frame, in pixels from the top left corner.
+type Rect struct {
+	X, Y          int
+	Width, Height int
+}
+
+// ExportRegions renders one output file per region of the frame, e.g. the
+// tiles of the individual speakers of a gallery view meeting recording. The
+// regions are in the frame of the Video after all other operations, see
+// OutputWidth and OutputHeight. Each output has the size of its region and
+// the audio of the Video.
+//
+// All outputs are written by a single ffmpeg process, which decodes the input
+// and applies the other operations only once. The output file names are
+// created from pattern by replacing {n} with the number of the region,
+// starting at 1 and zero padded to the same width for all regions. The names
+// of the files are returned. An error is returned if there are no regions or
+// a region is not inside the frame.
+func (v *Video) ExportRegions(regions []Rect, pattern string) ([]string, error) {
+	if len(regions) == 0 {
+		return nil, errors.New("cinema.Video.ExportRegions: no regions")
+	}
+	width, height := v.OutputWidth(), v.OutputHeight()
+	for i, r := range regions {
+		if r.Width <= 0 || r.Height <= 0 || r.X < 0 || r.Y < 0 ||
+			r.X+r.Width > width || r.Y+r.Height > height {
+			return nil, errors.New("cinema.Video.ExportRegions: region " +
+				strconv.Itoa(i+1) + " is not inside the " + strconv.Itoa(width) +
+				"x" + strconv.Itoa(height) + " frame")
+		}
+	}
+	if err := v.checkTrim("cinema.Video.ExportRegions"); err != nil {
+		return nil, err
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return nil, errors.New("cinema.Video.ExportRegions: " + err.Error())
+	}
+	defer cleanup()
+
+	digits := len(strconv.Itoa(len(regions)))
+	var outputs []string
+	for i := range regions {
+		n := strconv.Itoa(i + 1)
+		n = strings.Repeat("0", digits-len(n)) + n
+		outputs = append(outputs, strings.Replace(pattern, "{n}", n, -1))
+	}
+
+	// The filter chain of the Video is applied once and its result is split
+	// into the regions, which needs the frames on the CPU.
+	clip := v.snapshot()
+	clip.hardware = NoHardware
+	inputArgs, graph := clip.filterGraph(clip.chain())
+	var pads, crops []string
+	for i, r := range regions {
+		n := strconv.Itoa(i)
+		pads = append(pads, "[s"+n+"]")
+		crops = append(crops, "[s"+n+"]crop="+strconv.Itoa(r.Width)+":"+
+			strconv.Itoa(r.Height)+":"+strconv.Itoa(r.X)+":"+strconv.Itoa(r.Y)+
+			"[r"+n+"]")
+	}
+	graph += ";[vout]split=" + strconv.Itoa(len(regions)) +
+		strings.Join(pads, "") + ";" + strings.Join(crops, ";")
+
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, clip.threadGlobalArgs()...)
+	line = append(line, clip.input()...)
+	line = append(line, inputArgs...)
+	line = append(line, "-filter_complex", graph)
+	for i, output := range outputs {
+		line = append(line, clip.branchOutputArgs("[r"+strconv.Itoa(i)+"]", output)...)
+	}
+	if err := clip.runFFmpeg(line); err != nil {
+		return nil, ffmpegFailed("cinema.Video.ExportRegions", err)
+	}
+	return outputs, nil
+}
+
+// branchOutputArgs returns the output options and the name of an output that
+// is written from the pad of a -filter_complex graph and the audio of the
+// input. Output options only apply to the next output, so they are needed
+// for each output of a command line with several.
+func (v *Video) branchOutputArgs(pad, output string) []string {
+	args := []string{"-map", pad}
+	if v.audioChannels > 0 {
+		args = append(args, v.audioMapArgs("0:a:0")...)
+		args = append(args, v.audioArgs()...)
+		args = append(args, v.resamplerArgs()...)
+	}
+	args = append(args, v.trimArgs(v.outputTime(v.start), v.outputTime(v.end))...)
+	args = append(args, v.strictArgs()...)
+	args = append(args, v.audioEncoderArgs(output, v.outputArgs)...)
+	args = append(args, v.encoderArgs()...)
+	args = append(args, v.threadOutputArgs()...)
+	args = append(args, v.cfrArgs()...)
+	args = append(args, v.metadataPolicyArgs(output)...)
+	args = append(args, v.outputArgs...)
+	return append(args, output)
+}
@@ -0,0 +1,109 @@
+package cinema
+
+import (
+	"errors"
+	"strings"
+)
+
+// Remux is like Render but copies the streams into output without decoding
+// and encoding them, which takes seconds even for long videos and loses no
+// quality. Use it to trim a video or to change its container, e.g. from MKV
+// to MP4; the output format must support the codecs of the input. Stream
+// selections, subtitle files, chapters and metadata are written as by
+// Render.
+//
+// Since the frames are not decoded, the cuts of Trim happen at the key frame
+// right before the start, so the output can start a little early. The
+// settings of the encoders, e.g. SetBitrate or SetVideoCodec, are ignored. An
+// error is returned if an operation was applied that changes the frames or
+// the audio samples, like Crop, DrawText, SetSpeed, ForceCFR or an audio
+// filter, since those need re-encoding.
+func (v *Video) Remux(output string) error {
+	if reasons := v.reencodeReasons(); len(reasons) > 0 {
+		return errors.New("cinema.Video.Remux: the streams can not be " +
+			"copied, re-encoding is needed for the " +
+			strings.Join(reasons, ", "))
+	}
+	if err := v.checkTrim("cinema.Video.Remux"); err != nil {
+		return err
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.Remux: " + err.Error())
+	}
+	defer cleanup()
+
+	// Without decoding there is nothing to accelerate.
+	clip := v.snapshot()
+	clip.hardware = NoHardware
+	// A trim of the output would drop the copied packets up to the key frame
+	// after the start, seeking in the inputs starts at the key frame before
+	// it. The seek of the seek modes is replaced by this one.
+	clip.seekMode = SeekAccurate
+	var seek []string
+	if clip.start > 0 {
+		seek = []string{"-ss", clip.formatTime(clip.start)}
+	}
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, seekInputs(clip.input(), seek)...)
+	line = append(line, seekInputs(clip.subtitleInputArgs(), seek)...)
+	chapterInput, chapterOutput := clip.chapterArgs(1 + len(clip.subtitles))
+	line = append(line, chapterInput...)
+	if !clip.following() {
+		// The timestamps of the output start at the seek, so the end
+		// is given as a length.
+		line = append(line, "-t", clip.formatTime(clip.end-clip.start))
+	}
+	line = append(line, "-c", "copy")
+	// The subtitle codecs have to come after -c.
+	maps := clip.streamArgs(nil, output)
+	if len(maps) == 0 && len(clip.subtitles) == 0 {
+		maps = []string{"-map", "0"}
+	}
+	line = append(line, maps...)
+	line = append(line, clip.subtitleOutputArgs(1, maps, output)...)
+	line = append(line, clip.metadataPolicyArgs(output)...)
+	line = append(line, chapterOutput...)
+	line = append(line, copyArgs()...)
+	if err := clip.runFFmpeg(append(line, output)); err != nil {
+		return ffmpegFailed("cinema.Video.Remux", err)
+	}
+	return nil
+}
+
+// reencodeReasons returns the operations applied to the Video that change the
+// frames or the audio samples, empty if the streams can be copied.
+func (v *Video) reencodeReasons() []string {
+	var reasons []string
+	add := func(applied bool, reason string) {
+		if applied {
+			reasons = append(reasons, reason)
+		}
+	}
+	add(len(v.filters) > 0, "video filters")
+	add(v.colorFilter() != "", "color adjustments")
+	add(v.stabilizer != nil, "stabilization")
+	add(v.timecode != nil, "timecode")
+	add(v.guides != NoGuides, "guides")
+	add(v.forensic != nil, "forensic watermark")
+	add(len(v.ramp) > 0 || v.speedFilter() != "", "speed change")
+	add(v.reversed != nil, "reversal")
+	add(v.fadeIn > 0 || v.fadeOut > 0, "fades")
+	add(len(v.audioFilters) > 0, "audio filters")
+	add(v.resampler != nil, "resampling")
+	add(v.sanitize, "timestamp sanitizing")
+	add(v.cfr, "constant frame rate")
+	return reasons
+}
+
+// seekInputs inserts the input options seek before each input of args.
+func seekInputs(args, seek []string) []string {
+	var out []string
+	for _, arg := range args {
+		if arg == "-i" {
+			out = append(out, seek...)
+		}
+		out = append(out, arg)
+	}
+	return out
+}
@@ -0,0 +1,234 @@
+package cinema
+
+import (
+	"errors"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportOptions configures WriteReport. Zero values select the defaults.
+type ReportOptions struct {
+	// Title is the title of the report. It defaults to "Batch report".
+	Title string
+	// Thumbnails is the number of thumbnails per file, spread evenly over
+	// the file. It defaults to 6.
+	Thumbnails int
+	// Width is the width of the thumbnails in pixels. It defaults to 240.
+	Width int
+	// Target is the device the files are meant for, e.g. &DeviceWeb. If it
+	// is set, the problems CheckCompatibility finds are flagged.
+	Target *DeviceProfile
+}
+
+// WriteReport writes an HTML contact sheet of the files at paths to output,
+// e.g. to review the results of a night's batch at a glance: for each file
+// its thumbnails, a summary of its format and streams and the problems found
+// by a few quick checks, such as a missing audio stream, a variable frame
+// rate, interlacing or an audio offset. Files with problems are highlighted.
+//
+// The thumbnails are written as JPEG images to a directory next to output,
+// named like output with the extension replaced by "_files", so the report
+// can be moved together with that directory. Files that cannot be read are
+// listed with the error instead of failing the report. An error is returned
+// if there are no paths or the report cannot be written.
+func WriteReport(paths []string, output string, opts ReportOptions) error {
+	if len(paths) == 0 {
+		return errors.New("cinema.WriteReport: no files given")
+	}
+	if opts.Title == "" {
+		opts.Title = "Batch report"
+	}
+	if opts.Thumbnails <= 0 {
+		opts.Thumbnails = 6
+	}
+	if opts.Width <= 0 {
+		opts.Width = 240
+	}
+	dirName := strings.TrimSuffix(filepath.Base(output), filepath.Ext(output)) + "_files"
+	dir := filepath.Join(filepath.Dir(output), dirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.New("cinema.WriteReport: unable to create thumbnail " +
+			"directory: " + err.Error())
+	}
+
+	report := reportPage{Title: opts.Title, Generated: time.Now().Format(time.RFC1123)}
+	for i, path := range paths {
+		entry := reportEntry{Path: path}
+		v, err := Load(path)
+		if err != nil {
+			entry.Flags = append(entry.Flags, err.Error())
+		} else {
+			entry.Summary = reportSummary(v)
+			entry.Flags = reportFlags(v, opts.Target)
+			for k := 0; k < opts.Thumbnails && v.videoStreams > 0; k++ {
+				name := strconv.Itoa(i+1) + "-" + strconv.Itoa(k+1) + ".jpg"
+				at := time.Duration((float64(k) + 0.5) / float64(opts.Thumbnails) *
+					float64(v.duration))
+				clip := v.snapshot()
+				clip.fitInto(opts.Width, 0)
+				if err := clip.Screenshot(at, filepath.Join(dir, name)); err != nil {
+					entry.Flags = append(entry.Flags, "thumbnail at "+
+						at.Round(time.Second).String()+" failed: "+err.Error())
+					break
+				}
+				entry.Thumbnails = append(entry.Thumbnails, dirName+"/"+name)
+			}
+		}
+		if len(entry.Flags) > 0 {
+			report.Flagged++
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return errors.New("cinema.WriteReport: unable to create report: " +
+			err.Error())
+	}
+	if err := reportTemplate.Execute(f, report); err != nil {
+		f.Close()
+		return errors.New("cinema.WriteReport: unable to write report: " +
+			err.Error())
+	}
+	if err := f.Close(); err != nil {
+		return errors.New("cinema.WriteReport: unable to write report: " +
+			err.Error())
+	}
+	return nil
+}
+
+// reportPage is the data of the report template.
+type reportPage struct {
+	Title     string
+	Generated string
+	Entries   []reportEntry
+	// Flagged is the number of entries with flags.
+	Flagged int
+}
+
+// reportEntry is a single file of the report.
+type reportEntry struct {
+	Path string
+	// Summary are lines describing the format and the streams.
+	Summary []string
+	// Flags are the problems found.
+	Flags []string
+	// Thumbnails are the paths of the thumbnails, relative to the report.
+	Thumbnails []string
+}
+
+// reportSummary describes the format and the streams of the input.
+func reportSummary(v *Video) []string {
+	p := v.Probe()
+	format := p.FormatName + ", " + p.Duration.Round(time.Millisecond).String()
+	if p.Size > 0 {
+		format += ", " + formatFloat(math.Round(float64(p.Size)/1e5)/10) + " MB"
+	}
+	if p.BitRate > 0 {
+		format += ", " + strconv.Itoa(p.BitRate/1000) + " kb/s"
+	}
+	lines := []string{format}
+	for _, s := range p.Streams {
+		parts := []string{"#" + strconv.Itoa(s.Index), s.Type, s.Codec}
+		switch s.Type {
+		case "video":
+			parts = append(parts, strconv.Itoa(s.Width)+"x"+strconv.Itoa(s.Height))
+			if rate := parseRate(s.FrameRate); rate > 0 {
+				parts = append(parts, formatFloat(math.Round(rate*100)/100)+" fps")
+			}
+			parts = append(parts, s.PixelFormat, s.ColorSpace)
+		case "audio":
+			if s.SampleRate > 0 {
+				parts = append(parts, strconv.Itoa(s.SampleRate)+" Hz")
+			}
+			parts = append(parts, s.ChannelLayout)
+		}
+		if s.BitRate > 0 {
+			parts = append(parts, strconv.Itoa(s.BitRate/1000)+" kb/s")
+		}
+		parts = append(parts, s.Language)
+		var nonEmpty []string
+		for _, part := range parts {
+			if part != "" {
+				nonEmpty = append(nonEmpty, part)
+			}
+		}
+		lines = append(lines, strings.Join(nonEmpty, " "))
+	}
+	return lines
+}
+
+// reportFlags returns the problems of the input found by quick checks that
+// only read the start of the file, and the compatibility issues with target
+// if it is not nil.
+func reportFlags(v *Video, target *DeviceProfile) []string {
+	var flags []string
+	if v.videoStreams == 0 {
+		flags = append(flags, "no video stream")
+	}
+	if v.audioStreams == 0 {
+		flags = append(flags, "no audio stream")
+	}
+	if v.duration <= 0 {
+		flags = append(flags, "unknown duration")
+	}
+	if v.vfr {
+		flags = append(flags, "variable frame rate")
+	}
+	for _, s := range v.probe.Streams {
+		if s.Type == "video" && s.FieldOrder != "" &&
+			s.FieldOrder != "progressive" && s.FieldOrder != "unknown" {
+			flags = append(flags, "interlaced video ("+s.FieldOrder+")")
+			break
+		}
+	}
+	if v.videoStreams > 0 && v.audioStreams > 0 {
+		// Offsets above about 45 milliseconds are noticeable.
+		offset, err := v.CheckAVSync()
+		if err == nil && (offset > 45*time.Millisecond ||
+			offset < -45*time.Millisecond) {
+			flags = append(flags, "audio offset of "+
+				offset.Round(time.Millisecond).String())
+		}
+	}
+	if target != nil {
+		for _, issue := range v.CheckCompatibility(*target) {
+			flags = append(flags, target.Name+": "+issue.Message)
+		}
+	}
+	return flags
+}
+
+// reportTemplate is the HTML of WriteReport.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+.file { border: 1px solid #ccc; border-left: 6px solid #4a4; margin: 1em 0; padding: 0.5em 1em; }
+.file.flagged { border-left-color: #d33; }
+.path { font-weight: bold; word-break: break-all; }
+.summary { font-family: monospace; color: #555; margin: 0.5em 0; }
+.flags { color: #d33; margin: 0.5em 0; }
+.thumbs img { margin: 2px; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{len .Entries}} files, {{.Flagged}} with problems. Generated {{.Generated}}.</p>
+{{range .Entries}}<div class="file{{if .Flags}} flagged{{end}}">
+<div class="path">{{.Path}}</div>
+<div class="summary">{{range .Summary}}{{.}}<br>{{end}}</div>
+{{if .Flags}}<ul class="flags">{{range .Flags}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<div class="thumbs">{{range .Thumbnails}}<img src="{{.}}" alt="">{{end}}</div>
+</div>
+{{end}}</body>
+</html>
+`))
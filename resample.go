@@ -0,0 +1,124 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Resampler is an audio resampling engine, see SetResampler.
+type Resampler int
+
+const (
+	// SWR is ffmpeg's own resampler, the default. It is fast but its default
+	// settings audibly degrade music when downsampling.
+	SWR Resampler = iota
+	// SoXR is the resampler of the SoX project, which has a much higher
+	// quality. It requires an ffmpeg built with libsoxr.
+	SoXR
+)
+
+// ResamplerOptions configures SetResampler. Zero values select the defaults.
+type ResamplerOptions struct {
+	// Engine is the resampler used for all sample rate conversions.
+	Engine Resampler
+	// Precision is the precision of SoXR in bits, from 15 to 33. It defaults
+	// to 28, which is transparent for 24 bit audio. Only used with SoXR.
+	Precision int
+	// Dither is the dither method applied when reducing the bit depth, e.g.
+	// "triangular" or the noise shaping "shibata". Empty disables dithering.
+	Dither string
+	// SampleRate is the sample rate of the output in Hz. 0 keeps the rate of
+	// the input, unless the output format requires a different one.
+	SampleRate int
+}
+
+// ditherMethods are the dither methods of ffmpeg's resampler.
+var ditherMethods = map[string]bool{
+	"rectangular":         true,
+	"triangular":          true,
+	"triangular_hp":       true,
+	"lipshitz":            true,
+	"shibata":             true,
+	"low_shibata":         true,
+	"high_shibata":        true,
+	"f_weighted":          true,
+	"e_weighted":          true,
+	"modified_e_weighted": true,
+}
+
+// SetResampler selects the resampler and its quality for all sample rate
+// conversions of the audio: those of speed changes and timestamp
+// sanitization, and the conversion to the sample rate of the output. An
+// error is returned if an option is out of range.
+func (v *Video) SetResampler(opts ResamplerOptions) error {
+	if opts.Engine == SoXR && opts.Precision == 0 {
+		opts.Precision = 28
+	}
+	switch {
+	case opts.Engine != SWR && opts.Engine != SoXR:
+		return errors.New("cinema.Video.SetResampler: unknown engine " +
+			strconv.Itoa(int(opts.Engine)))
+	case opts.Engine == SoXR && (opts.Precision < 15 || opts.Precision > 33):
+		return errors.New("cinema.Video.SetResampler: precision must be " +
+			"between 15 and 33 bits")
+	case opts.Dither != "" && !ditherMethods[opts.Dither]:
+		return errors.New("cinema.Video.SetResampler: unknown dither method " +
+			opts.Dither)
+	case opts.SampleRate < 0:
+		return errors.New("cinema.Video.SetResampler: sample rate must not " +
+			"be negative")
+	}
+	v.record("SetResampler", opts)
+	v.resampler = &opts
+	return nil
+}
+
+// resamplerOptions returns the options of the resampler as key=value pairs.
+func (v *Video) resamplerOptions() [][2]string {
+	if v.resampler == nil {
+		return nil
+	}
+	var opts [][2]string
+	if v.resampler.Engine == SoXR {
+		opts = append(opts,
+			[2]string{"resampler", "soxr"},
+			[2]string{"precision", strconv.Itoa(v.resampler.Precision)},
+		)
+	}
+	if v.resampler.Dither != "" {
+		opts = append(opts, [2]string{"dither_method", v.resampler.Dither})
+	}
+	return opts
+}
+
+// aresample returns an aresample filter with the options opts that converts
+// to the sample rate with the selected resampler. A rate of 0 keeps the
+// sample rate.
+func (v *Video) aresample(rate int, opts ...string) string {
+	var parts []string
+	if rate > 0 {
+		parts = append(parts, strconv.Itoa(rate))
+	}
+	parts = append(parts, opts...)
+	for _, o := range v.resamplerOptions() {
+		parts = append(parts, o[0]+"="+o[1])
+	}
+	return "aresample=" + strings.Join(parts, ":")
+}
+
+// resamplerArgs returns the output options that apply the resampler to the
+// conversion to the output sample rate, which ffmpeg inserts on its own.
+func (v *Video) resamplerArgs() []string {
+	if v.resampler == nil {
+		return nil
+	}
+	var args []string
+	if v.resampler.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(v.resampler.SampleRate))
+	}
+	for _, o := range v.resamplerOptions() {
+		args = append(args, "-"+o[0], o[1])
+	}
+	return args
+}
@@ -0,0 +1,85 @@
+package cinema
+
+import (
+	"strconv"
+	"time"
+)
+
+// reverseMemoryWarning is the memory use of Reverse above which Warnings
+// reports a problem, unless there is a memory limit.
+const reverseMemoryWarning = 2 << 30
+
+// ReverseOptions configures Reverse. Zero values select the defaults.
+type ReverseOptions struct {
+	// VideoOnly reverses only the video and keeps the audio playing forward,
+	// e.g. for a rewind effect with music.
+	VideoOnly bool
+}
+
+// Reverse plays the trimmed video backwards, along with the audio unless
+// opts.VideoOnly is set. Calling Reverse again replaces the options.
+//
+// ffmpeg has to keep all frames of the trimmed video in memory to reverse
+// them, which takes about 5 GB per minute of 1080p video at 30 frames per
+// second. Trim the video to the part that should be reversed first. Warnings
+// reports when the memory use is likely too high, and a memory limit set with
+// SetMemoryLimit makes the render fail with a *MemoryError instead of
+// exhausting the memory of the system.
+func (v *Video) Reverse(opts ReverseOptions) {
+	v.record("Reverse", opts)
+	v.reversed = &opts
+}
+
+// reverseFilters returns the video filters that come before all others to
+// reverse the trimmed range. The trim keeps the timestamps, so the trim of
+// the output still selects the reversed range.
+func (v *Video) reverseFilters() []filter {
+	if v.reversed == nil {
+		return nil
+	}
+	return []filter{{stage: StageTrim, expr: "trim=" + v.trimRange() + ",reverse"}}
+}
+
+// reverseAudioFilters is like reverseFilters for the audio.
+func (v *Video) reverseAudioFilters() []string {
+	if v.reversed == nil || v.reversed.VideoOnly {
+		return nil
+	}
+	return []string{"atrim=" + v.trimRange() + ",areverse"}
+}
+
+// trimRange returns the start and end options of the trim and atrim filters
+// for the trimmed range of the input.
+func (v *Video) trimRange() string {
+	return "start=" + formatFloat(v.start.Seconds()) +
+		":end=" + formatFloat(v.end.Seconds())
+}
+
+// reverseMemory returns an estimate of the memory in bytes that reversing the
+// trimmed video takes, with the frames stored as 8 bit 4:2:0.
+func (v *Video) reverseMemory() int64 {
+	rate := parseRate(v.frameRate)
+	if rate <= 0 {
+		rate = 30
+	}
+	frames := (v.end - v.start).Seconds() * rate
+	return int64(frames * float64(v.width*v.height) * 1.5)
+}
+
+// reverseWarnings returns the warnings about the memory use of Reverse.
+func (v *Video) reverseWarnings() []string {
+	if v.reversed == nil {
+		return nil
+	}
+	limit := int64(reverseMemoryWarning)
+	if v.memoryLimit > 0 {
+		limit = v.memoryLimit
+	}
+	need := v.reverseMemory()
+	if need <= limit {
+		return nil
+	}
+	return []string{"reversing the " + (v.end - v.start).Round(time.Second).String() +
+		" long video needs about " + strconv.FormatInt(need>>20, 10) +
+		" MB of memory, trim it to a shorter range"}
+}
@@ -0,0 +1,189 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScreenshotsAt saves the frames at the given times of the input video as
+// images, using a single ffmpeg process. pattern is the file name of the
+// images with a printf-like number, e.g. "shot-%03d.png" creates shot-001.png,
+// shot-002.png and so on, numbered in the order of the times. The image format
+// is selected by the file extension.
+//
+// For each time, the first frame at or after it is saved. If several times
+// fall onto the same frame, it is saved only once. The crop, scale and effect
+// operations of the Video are applied to the images.
+func (v *Video) ScreenshotsAt(times []time.Duration, pattern string) error {
+	if len(times) == 0 {
+		return errors.New("cinema.Video.ScreenshotsAt: no times given")
+	}
+
+	removeScripts, err := v.writeScripts()
+	if err != nil {
+		return errors.New("cinema.Video.ScreenshotsAt: " + err.Error())
+	}
+	defer removeScripts()
+
+	chain := []filter{{
+		stage: StageTrim,
+		expr:  "select=" + filterValue(selectTimes(times)),
+	}}
+	chain = append(chain, v.pipeline()...)
+
+	inputArgs, filterArgs := v.filterArgs(chain)
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, v.threadGlobalArgs()...)
+	// The times can be before the trimmed start, so the input is read from
+	// its start whatever the seek mode.
+	line = append(line, v.inputWith(nil)...)
+	line = append(line, inputArgs...)
+	line = append(line, filterArgs...)
+	line = append(line,
+		"-an",
+		"-vsync", "vfr",
+		"-frames:v", strconv.Itoa(len(times)),
+	)
+	line = append(line, v.imageQualityArgs(pattern)...)
+	line = append(line, pattern)
+
+	if err := v.runFFmpeg(line); err != nil {
+		return ffmpegFailed("cinema.Video.ScreenshotsAt", err)
+	}
+	return nil
+}
+
+// Screenshot saves the frame at the given time of the input video as an
+// image, e.g. a PNG or JPEG file, selected by the extension of output. The
+// first frame at or after the time is saved. The crop, scale and effect
+// operations of the Video are applied to the image. Only the part of the
+// input around the time is read, so this is fast even for long videos.
+func (v *Video) Screenshot(at time.Duration, output string) error {
+	if at < 0 || at >= v.duration {
+		return errors.New("cinema.Video.Screenshot: time " + at.String() +
+			" is outside of the video")
+	}
+	removeScripts, err := v.writeScripts()
+	if err != nil {
+		return errors.New("cinema.Video.Screenshot: " + err.Error())
+	}
+	defer removeScripts()
+
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, v.threadGlobalArgs()...)
+	seek := append([]string{"-ss", formatFloat(at.Seconds())}, v.copytsArgs()...)
+	line = append(line, v.inputWith(seek)...)
+	inputArgs, filterArgs := v.filterArgs(v.pipeline())
+	line = append(line, inputArgs...)
+	line = append(line, filterArgs...)
+	line = append(line, "-an", "-frames:v", "1", "-update", "1")
+	line = append(line, v.imageQualityArgs(output)...)
+	line = append(line, output)
+
+	if err := v.runFFmpeg(line); err != nil {
+		return ffmpegFailed("cinema.Video.Screenshot", err)
+	}
+	return nil
+}
+
+// Screenshots saves a frame every interval of the trimmed video as images,
+// starting with the first frame. pattern is the file name of the images with
+// a printf-like number, e.g. "shot-%04d.jpg" creates shot-0001.jpg,
+// shot-0002.jpg and so on. The image format is selected by the file
+// extension. The crop, scale and effect operations of the Video are applied
+// to the images.
+func (v *Video) Screenshots(interval time.Duration, pattern string) error {
+	if interval <= 0 {
+		return errors.New("cinema.Video.Screenshots: interval must be " +
+			"positive")
+	}
+	if err := v.checkTrim("cinema.Video.Screenshots"); err != nil {
+		return err
+	}
+	removeScripts, err := v.writeScripts()
+	if err != nil {
+		return errors.New("cinema.Video.Screenshots: " + err.Error())
+	}
+	defer removeScripts()
+
+	// A frame is selected whenever the timestamp enters the next interval.
+	start := formatFloat(v.start.Seconds())
+	step := formatFloat(interval.Seconds())
+	slot := func(t string) string {
+		return "floor((" + t + "-" + start + ")/" + step + ")"
+	}
+	chain := []filter{{
+		stage: StageTrim,
+		expr: "select=" + filterValue(
+			"isnan(prev_t)+gt("+slot("t")+","+slot("prev_t")+")"),
+	}}
+	chain = append(chain, v.pipeline()...)
+
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, v.threadGlobalArgs()...)
+	seek := []string{
+		"-ss", start,
+		"-t", formatFloat((v.end - v.start).Seconds()),
+	}
+	line = append(line, v.inputWith(append(seek, v.copytsArgs()...))...)
+	inputArgs, filterArgs := v.filterArgs(chain)
+	line = append(line, inputArgs...)
+	line = append(line, filterArgs...)
+	line = append(line, "-an", "-vsync", "vfr")
+	line = append(line, v.imageQualityArgs(pattern)...)
+	line = append(line, pattern)
+
+	if err := v.runFFmpeg(line); err != nil {
+		return ffmpegFailed("cinema.Video.Screenshots", err)
+	}
+	return nil
+}
+
+// SetImageQuality sets the quality of JPEG and WebP images created by
+// Screenshot, Screenshots and ScreenshotsAt, from 1 (smallest files) to 100
+// (best quality). PNG images are lossless and not affected. By default
+// ffmpeg's defaults are used, which give rather low quality JPEG images. An
+// error is returned if quality is out of range.
+func (v *Video) SetImageQuality(quality int) error {
+	if quality < 1 || quality > 100 {
+		return errors.New("cinema.Video.SetImageQuality: quality must be " +
+			"between 1 and 100")
+	}
+	v.record("SetImageQuality", quality)
+	v.imageQuality = quality
+	return nil
+}
+
+// imageQualityArgs returns the output options that set the image quality for
+// the format of output.
+func (v *Video) imageQualityArgs(output string) []string {
+	if v.imageQuality == 0 {
+		return nil
+	}
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".jpg", ".jpeg":
+		// The JPEG encoder takes a quantizer from 2 (best) to 31.
+		q := 31 - int(math.Round(float64(v.imageQuality-1)*29/99))
+		return []string{"-q:v", strconv.Itoa(q)}
+	case ".webp":
+		return []string{"-quality", strconv.Itoa(v.imageQuality)}
+	}
+	return nil
+}
+
+// selectTimes returns a select filter expression that selects the first frame
+// whose timestamp reaches each of the times.
+func selectTimes(times []time.Duration) string {
+	// prev_t is not a number for the first frame.
+	var terms []string
+	for _, t := range times {
+		at := formatFloat(t.Seconds())
+		terms = append(terms,
+			"(isnan(prev_t)+lt(prev_t,"+at+"))*gte(t,"+at+")")
+	}
+	return strings.Join(terms, "+")
+}
@@ -0,0 +1,79 @@
+package cinema
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// scriptFile is a temporary file a filter reads, e.g. the commands of
+// DrawTimedText or the captions of BurnASS. Its name is fixed when the filter
+// is added, but the file only exists while ffmpeg runs with the filter. It is shared by the copies of a
+// Video, so it is written by the first of them and removed by the last.
+type scriptFile struct {
+	path    string
+	content string
+
+	mu    sync.Mutex
+	users int
+}
+
+// newScriptFile reserves the name of a temporary file for content. pattern is
+// the file name like in ioutil.TempFile.
+func newScriptFile(pattern, content string) (*scriptFile, error) {
+	f, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return &scriptFile{path: f.Name(), content: content}, nil
+}
+
+// open writes the file unless it already exists for another user.
+func (s *scriptFile) open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users == 0 {
+		if err := ioutil.WriteFile(s.path, []byte(s.content), 0600); err != nil {
+			os.Remove(s.path)
+			return err
+		}
+	}
+	s.users++
+	return nil
+}
+
+// close removes the file once it has no more users.
+func (s *scriptFile) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users--
+	if s.users == 0 {
+		os.Remove(s.path)
+	}
+}
+
+// writeScripts writes the script files of the filters of the Video. Call
+// remove once ffmpeg exited to remove them.
+func (v *Video) writeScripts() (remove func(), err error) {
+	var written []*scriptFile
+	remove = func() {
+		for _, s := range written {
+			s.close()
+		}
+	}
+	for _, f := range v.filters {
+		if f.script == nil {
+			continue
+		}
+		if err := f.script.open(); err != nil {
+			remove()
+			return nil, errors.New("unable to write filter script: " +
+				err.Error())
+		}
+		written = append(written, f.script)
+	}
+	return remove, nil
+}
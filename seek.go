@@ -0,0 +1,146 @@
+package cinema
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SeekMode selects how ffmpeg gets to the start of the trimmed range, see
+// SetSeekMode.
+type SeekMode int
+
+const (
+	// SeekAccurate decodes the input from its start and drops the frames
+	// before the trimmed range. It is the slowest mode, but works with
+	// every input, even with broken indexes or timestamps. This is the
+	// default.
+	SeekAccurate SeekMode = iota
+	// SeekFast jumps to the key frame before the start of the trimmed range
+	// and only decodes from there, so trimming the end of a long file is
+	// fast. The output is the same as with SeekAccurate for almost all
+	// inputs.
+	SeekFast
+	// SeekKeyframe is like SeekFast but moves the start of the trimmed
+	// range to the key frame at or before it, so no frames have to be
+	// decoded and dropped at all. The output can start a little earlier
+	// than requested.
+	SeekKeyframe
+)
+
+// keyframeIndex caches the key frames found before times of the input, it is
+// shared by the copies of a Video.
+type keyframeIndex struct {
+	mu     sync.Mutex
+	before map[time.Duration]time.Duration
+}
+
+// SetSeekMode sets how ffmpeg gets to the start of the trimmed range. With
+// SeekKeyframe, the start set with Trim or SetStart, before or after calling
+// SetSeekMode, is moved to the key frame at or before it; Start returns the
+// moved start. If the key frame cannot be found, e.g. because it is more than
+// a minute before the start, the start is kept.
+//
+// Seeking is always accurate for inputs read with LoadReader or followed with
+// Follow, and for Videos that are stabilized or whose timestamps are
+// sanitized, since those need the frames from the start of the input.
+func (v *Video) SetSeekMode(mode SeekMode) {
+	v.record("SetSeekMode", mode)
+	v.seekMode = mode
+	v.setStart(v.start)
+}
+
+// seekArgs returns the input options that seek to the start of the trimmed
+// range. ffmpeg makes the timestamps start at the seek, the same offset moves
+// them back, so the filters see the same times as without seeking, the trim
+// of the output still applies and the output starts at 0. -copyts is not
+// used since it would keep the timestamps of the input in the output.
+func (v *Video) seekArgs() []string {
+	if v.seekMode == SeekAccurate || v.start == 0 || v.following() ||
+		v.source != nil || v.stabilizer != nil || v.sanitize ||
+		strings.HasPrefix(v.filepath, "pipe:") {
+		return nil
+	}
+	at := v.formatTime(v.start)
+	return []string{"-ss", at, "-itsoffset", at}
+}
+
+// snapStart returns the start of the trimmed range for t: the key frame at or
+// before t with SeekKeyframe, t otherwise.
+func (v *Video) snapStart(t time.Duration) time.Duration {
+	if v.seekMode != SeekKeyframe || t == 0 || v.source != nil ||
+		strings.HasPrefix(v.filepath, "pipe:") {
+		return t
+	}
+	if v.keyframes == nil {
+		v.keyframes = &keyframeIndex{before: map[time.Duration]time.Duration{}}
+	}
+	k := v.keyframes
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if before, ok := k.before[t]; ok {
+		return before
+	}
+	before, ok := v.keyframeBefore(t)
+	if !ok {
+		before = t
+	}
+	k.before[t] = before
+	return before
+}
+
+// keyframeBefore finds the last key frame of the first video stream at or
+// before t, up to a minute before it, by reading the packets without decoding
+// them.
+func (v *Video) keyframeBefore(t time.Duration) (time.Duration, bool) {
+	from := v.startTime + t - time.Minute
+	if from < v.startTime {
+		from = v.startTime
+	}
+	out, err := exec.Command(
+		"ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-select_streams", "v:0",
+		"-read_intervals", formatFloat(from.Seconds())+"%"+
+			formatFloat((v.startTime+t+time.Millisecond).Seconds()),
+		"-show_entries", "packet=pts_time,flags",
+		v.filepath,
+	).Output()
+	if err != nil {
+		return 0, false
+	}
+	var desc struct {
+		Packets []struct {
+			PTSTime json.Number `json:"pts_time"`
+			Flags   string      `json:"flags"`
+		} `json:"packets"`
+	}
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return 0, false
+	}
+	var (
+		best  time.Duration
+		found bool
+	)
+	for _, p := range desc.Packets {
+		if !strings.Contains(p.Flags, "K") || p.PTSTime == "" ||
+			p.PTSTime == "N/A" {
+			continue
+		}
+		pts, err := probeTime(p.PTSTime)
+		if err != nil {
+			continue
+		}
+		pts -= v.startTime
+		if pts <= t && (!found || pts > best) {
+			best, found = pts, true
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	return best, found
+}
@@ -0,0 +1,290 @@
+package cinema
+
+import (
+	"errors"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RegionProvider finds the subject in frames of a video, e.g. with a face
+// detector, so that SmartCrop can follow it.
+type RegionProvider interface {
+	// Region returns the region of the subject in frame, which is shown at
+	// time at of the input video. ok is false if the frame has no subject,
+	// then the crop keeps its position. An error stops SmartCrop.
+	Region(frame image.Image, at time.Duration) (r Rect, ok bool, err error)
+}
+
+// RegionFunc adapts a function to the RegionProvider interface.
+type RegionFunc func(frame image.Image, at time.Duration) (Rect, bool, error)
+
+// Region implements RegionProvider.
+func (f RegionFunc) Region(frame image.Image, at time.Duration) (Rect, bool, error) {
+	return f(frame, at)
+}
+
+// SmartCropOptions configures SmartCrop. Zero values select the defaults.
+type SmartCropOptions struct {
+	// Width and Height are the aspect ratio of the crop, e.g. 9 and 16 to
+	// turn a landscape video into a portrait one. The crop is as large as
+	// fits into the frame. They are required.
+	Width, Height int
+	// Interval is the time between the frames passed to the provider. It
+	// defaults to half a second.
+	Interval time.Duration
+	// Smoothing is the time before and after each frame over which the
+	// positions of the subject are averaged, so that the crop moves calmly
+	// instead of following every small movement. It defaults to 1 second,
+	// use a negative value to disable it.
+	Smoothing time.Duration
+}
+
+// SmartCrop crops the video to an aspect ratio around a subject that moves
+// over time, e.g. to make a portrait video for phones from a landscape
+// recording that follows the speaker. The trimmed range of the video is
+// decoded at regular intervals with the operations so far applied, and the
+// frames are passed to p. The crop follows the center of the regions it
+// returns, moving smoothly between them, and is centered on the frame if the
+// subject is never found.
+//
+// The positions are fixed when SmartCrop is called, so operations that change
+// the frame before the crop, and trimming the video to a range outside of the
+// analyzed one, should be done first. An error is returned if the options are
+// invalid, if ffmpeg fails or if p returns one.
+func (v *Video) SmartCrop(p RegionProvider, opts SmartCropOptions) error {
+	if p == nil {
+		return errors.New("cinema.Video.SmartCrop: no region provider")
+	}
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return errors.New("cinema.Video.SmartCrop: aspect ratio must be " +
+			"positive")
+	}
+	if opts.Interval == 0 {
+		opts.Interval = 500 * time.Millisecond
+	}
+	if opts.Interval < 0 {
+		return errors.New("cinema.Video.SmartCrop: interval must be positive")
+	}
+	if opts.Smoothing == 0 {
+		opts.Smoothing = time.Second
+	}
+	if err := v.checkTrim("cinema.Video.SmartCrop"); err != nil {
+		return err
+	}
+
+	pipeline := v.pipeline()
+	width, height := v.walkGeometry(pipeline, nil)
+	if width <= 0 || height <= 0 {
+		return errors.New("cinema.Video.SmartCrop: unknown frame size")
+	}
+	cropWidth, cropHeight := width, height
+	aspect := float64(opts.Width) / float64(opts.Height)
+	if float64(width)/float64(height) > aspect {
+		cropWidth = int(float64(height)*aspect) &^ 1
+	} else {
+		cropHeight = int(float64(width)/aspect) &^ 1
+	}
+
+	times, centers, err := v.trackSubject(p, pipeline, width, height, opts.Interval)
+	if err != nil {
+		return errors.New("cinema.Video.SmartCrop: " + err.Error())
+	}
+	centers = smoothCenters(centers, int(opts.Smoothing/opts.Interval))
+	xs := make([]int, len(centers))
+	ys := make([]int, len(centers))
+	for i, c := range centers {
+		xs[i] = clamp(int(math.Round(c.X))-cropWidth/2, 0, width-cropWidth)
+		ys[i] = clamp(int(math.Round(c.Y))-cropHeight/2, 0, height-cropHeight)
+	}
+
+	v.record("SmartCrop", p, opts)
+	v.filters = append(v.filters, filter{
+		stage: StageCrop,
+		kind:  cropFilter,
+		expr: "crop=" + strconv.Itoa(cropWidth) + ":" + strconv.Itoa(cropHeight) +
+			":x='" + pathExpr(times, xs) + "':y='" + pathExpr(times, ys) + "'",
+		width:  cropWidth,
+		height: cropHeight,
+	})
+	return nil
+}
+
+// center is the center of a region in pixels.
+type center struct {
+	X, Y float64
+}
+
+// trackSubject decodes the trimmed range after the filters in pipeline every
+// interval and returns the times of the frames in the input video and the
+// center of the subject p finds in them. Frames without a subject get the
+// center of the closest frame with one, or of the frame if there is none.
+func (v *Video) trackSubject(p RegionProvider, pipeline []filter, width, height int, interval time.Duration) ([]time.Duration, []center, error) {
+	count := int((v.end - v.start + interval - 1) / interval)
+	if count < 1 {
+		count = 1
+	}
+	chain := append(pipeline, filter{
+		stage: StageFPS,
+		expr: "fps=fps=1/" + formatFloat(interval.Seconds()) +
+			":start_time=" + formatFloat(v.start.Seconds()),
+	})
+	removeScripts, err := v.writeScripts()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer removeScripts()
+
+	line := []string{"ffmpeg"}
+	line = append(line, v.threadGlobalArgs()...)
+	seek := append([]string{"-ss", formatFloat(v.start.Seconds())},
+		v.copytsArgs()...)
+	line = append(line, v.inputWith(seek)...)
+	inputArgs, filterArgs := v.filterArgs(chain)
+	line = append(line, inputArgs...)
+	line = append(line, filterArgs...)
+	line = append(line,
+		"-an",
+		"-frames:v", strconv.Itoa(count),
+		"-pix_fmt", "rgba",
+		"-f", "rawvideo",
+		"pipe:1",
+	)
+
+	cmd := v.command(line)
+	cmd.Stdout = nil
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, errors.New("unable to read frames: " + err.Error())
+	}
+	if err := startProcess(cmd, v.memoryLimit); err != nil {
+		return nil, nil, errors.New("unable to start ffmpeg: " + err.Error())
+	}
+	var (
+		times   []time.Duration
+		centers []center
+		found   []bool
+	)
+	for {
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		if _, err := io.ReadFull(stdout, frame.Pix); err != nil {
+			break
+		}
+		at := v.start + time.Duration(len(times))*interval
+		r, ok, err := p.Region(frame, at)
+		if err != nil {
+			killProcess(cmd)
+			waitProcess(cmd)
+			return nil, nil, err
+		}
+		ok = ok && r.Width > 0 && r.Height > 0
+		times = append(times, at)
+		centers = append(centers, center{
+			X: float64(r.X) + float64(r.Width)/2,
+			Y: float64(r.Y) + float64(r.Height)/2,
+		})
+		found = append(found, ok)
+	}
+	if err := waitProcess(cmd); err != nil {
+		return nil, nil, errors.New("ffmpeg failed: " + err.Error())
+	}
+	if len(times) == 0 {
+		return nil, nil, errors.New("no frames decoded")
+	}
+
+	// Fill the gaps with the previous subject, or the next one at the
+	// start.
+	last := -1
+	for i := range centers {
+		if found[i] {
+			last = i
+		} else if last >= 0 {
+			centers[i] = centers[last]
+		}
+	}
+	if last < 0 {
+		for i := range centers {
+			centers[i] = center{float64(width) / 2, float64(height) / 2}
+		}
+		return times, centers, nil
+	}
+	for i := range centers {
+		if found[i] {
+			for j := 0; j < i; j++ {
+				centers[j] = centers[i]
+			}
+			break
+		}
+	}
+	return times, centers, nil
+}
+
+// smoothCenters returns the moving average of the centers over window
+// centers before and after each one.
+func smoothCenters(centers []center, window int) []center {
+	if window <= 0 {
+		return centers
+	}
+	smoothed := make([]center, len(centers))
+	for i := range centers {
+		var sum center
+		n := 0
+		for j := i - window; j <= i+window; j++ {
+			if j >= 0 && j < len(centers) {
+				sum.X += centers[j].X
+				sum.Y += centers[j].Y
+				n++
+			}
+		}
+		smoothed[i] = center{sum.X / float64(n), sum.Y / float64(n)}
+	}
+	return smoothed
+}
+
+// pathExpr returns an ffmpeg expression of t that interpolates linearly
+// between the values at the times. Points on a straight line are left out to
+// keep the expression short.
+func pathExpr(times []time.Duration, values []int) string {
+	var ts []float64
+	var vs []int
+	for i := range times {
+		if i > 0 && i < len(times)-1 &&
+			values[i]-values[i-1] == values[i+1]-values[i] {
+			continue
+		}
+		ts = append(ts, times[i].Seconds())
+		vs = append(vs, values[i])
+	}
+	if len(ts) == 1 {
+		return strconv.Itoa(vs[0])
+	}
+	terms := []string{strconv.Itoa(vs[0]) + "*lt(t," + formatFloat(ts[0]) + ")"}
+	for i := 0; i+1 < len(ts); i++ {
+		t0, t1 := formatFloat(ts[i]), formatFloat(ts[i+1])
+		term := "gte(t," + t0 + ")*lt(t," + t1 + ")*"
+		if vs[i] == vs[i+1] {
+			term += strconv.Itoa(vs[i])
+		} else {
+			term += "(" + strconv.Itoa(vs[i]) + "+" + strconv.Itoa(vs[i+1]-vs[i]) +
+				"*(t-" + t0 + ")/" + formatFloat(ts[i+1]-ts[i]) + ")"
+		}
+		terms = append(terms, term)
+	}
+	last := len(ts) - 1
+	terms = append(terms, strconv.Itoa(vs[last])+"*gte(t,"+formatFloat(ts[last])+")")
+	return strings.Join(terms, "+")
+}
+
+// clamp limits n to the range from min to max.
+func clamp(n, min, max int) int {
+	if n > max {
+		n = max
+	}
+	if n < min {
+		n = min
+	}
+	return n
+}
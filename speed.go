@@ -0,0 +1,90 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// SetSpeed changes the playback speed of the whole video by factor, e.g. 0.5
+// for slow motion at half speed or 4 for a time-lapse. The audio tempo is
+// changed along with the video so both stay in sync, see PreservePitch. The
+// trim times stay relative to the input video. Together with SpeedRamp, the
+// speeds multiply. An error is returned if factor is not positive.
+func (v *Video) SetSpeed(factor float64) error {
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return errors.New("cinema.Video.SetSpeed: factor must be positive")
+	}
+	v.record("SetSpeed", factor)
+	v.speed = factor
+	return nil
+}
+
+// speedFilter returns the setpts filter for SetSpeed or the empty string if
+// the speed is not changed.
+func (v *Video) speedFilter() string {
+	if v.speed <= 0 || v.speed == 1 {
+		return ""
+	}
+	return "setpts=PTS/" + formatFloat(v.speed)
+}
+
+// speedOutputTime converts a time before the speed change of SetSpeed to the
+// time after it.
+func (v *Video) speedOutputTime(t time.Duration) time.Duration {
+	if v.speed <= 0 || v.speed == 1 {
+		return t
+	}
+	return time.Duration(math.Round(float64(t) / v.speed))
+}
+
+// speedInputTime is the inverse of speedOutputTime.
+func (v *Video) speedInputTime(t time.Duration) time.Duration {
+	if v.speed <= 0 || v.speed == 1 {
+		return t
+	}
+	return time.Duration(math.Round(float64(t) * v.speed))
+}
+
+// PreservePitch selects how the audio follows speed changes of the video.
+// With pitch preservation, which is the default, the audio tempo is changed
+// without affecting the pitch so sped up speech does not sound like chipmunks.
+// Without it, the audio is played faster or slower like a tape, which raises
+// or lowers the pitch along with the speed.
+func (v *Video) PreservePitch(preserve bool) {
+	v.record("PreservePitch", preserve)
+	v.changePitch = !preserve
+}
+
+// tempoFilters returns the audio filters that play the audio factor times as
+// fast, taking PreservePitch into account.
+func (v *Video) tempoFilters(factor float64) []string {
+	if factor == 1 || factor <= 0 {
+		return nil
+	}
+
+	if v.changePitch && v.sampleRate > 0 {
+		// Pretending the samples have a different rate plays them faster or
+		// slower, resampling then brings back the original rate.
+		return []string{
+			"asetrate=" + formatFloat(float64(v.sampleRate)*factor),
+			v.aresample(v.sampleRate),
+		}
+	}
+
+	// atempo only supports factors from 0.5 to 2 in older ffmpeg versions,
+	// larger changes are split into a chain of atempo filters.
+	var filters []string
+	for factor > 2 {
+		filters = append(filters, "atempo=2")
+		factor /= 2
+	}
+	for factor < 0.5 {
+		filters = append(filters, "atempo=0.5")
+		factor /= 0.5
+	}
+	if factor != 1 {
+		filters = append(filters, "atempo="+formatFloat(factor))
+	}
+	return filters
+}
@@ -0,0 +1,123 @@
+package cinema
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// StabilizeOptions configures Stabilize. Zero values select the defaults.
+type StabilizeOptions struct {
+	// Shakiness is how shaky the video is, from 1 (a little) to 10 (very).
+	// It defaults to 5.
+	Shakiness int
+	// Smoothing is the number of frames before and after each frame that
+	// the camera motion is averaged over. Larger values give a calmer
+	// picture but also remove intended pans. It defaults to 10, 0 is
+	// replaced by the default, use a negative value for a static camera.
+	Smoothing int
+}
+
+// stabilizer holds the state of Stabilize.
+type stabilizer struct {
+	options StabilizeOptions
+}
+
+// Stabilize removes camera shake from the video, e.g. of handheld phone
+// footage. The camera motion is detected in a first pass over the input,
+// which runs with every render. The second pass moves each frame against the
+// shake and zooms in slightly so that the moving edges stay hidden.
+// Stabilization happens before all other filters, on the frames of the input.
+// Like reversing, it only applies to Render and the other operations that
+// render the whole video, not to screenshots and thumbnails.
+//
+// The motion is written to a temporary file that is removed after the render.
+// Calling Stabilize again replaces the options. ffmpeg must be built with
+// libvidstab. An error is returned if the options are out of range or if the
+// Video was loaded with LoadReader or is a stage of a Chain, whose input can
+// only be read once.
+func (v *Video) Stabilize(opts StabilizeOptions) error {
+	if opts.Shakiness == 0 {
+		opts.Shakiness = 5
+	}
+	if opts.Shakiness < 1 || opts.Shakiness > 10 {
+		return errors.New("cinema.Video.Stabilize: shakiness must be between " +
+			"1 and 10")
+	}
+	if opts.Smoothing == 0 {
+		opts.Smoothing = 10
+	}
+	if opts.Smoothing < 0 {
+		opts.Smoothing = 0
+	}
+	if v.source != nil || strings.HasPrefix(v.filepath, "pipe:") {
+		return errors.New("cinema.Video.Stabilize: the input of a Video " +
+			"loaded with LoadReader or of a Chain stage can only be read once")
+	}
+	v.record("Stabilize", opts)
+	v.stabilizer = &stabilizer{options: opts}
+	return nil
+}
+
+// stabilizeFilters returns the filters that stabilize the video with the
+// motion found by detectMotion.
+func (v *Video) stabilizeFilters() []filter {
+	if v.stabilizer == nil {
+		return nil
+	}
+	return []filter{{
+		stage: StageFX,
+		expr: "vidstabtransform=input=" + filterValue(v.transformsFile) +
+			":smoothing=" + strconv.Itoa(v.stabilizer.options.Smoothing) +
+			":optzoom=1:interpol=bicubic," +
+			// The transform softens the frames a little.
+			"unsharp=5:5:0.8:3:3:0.4",
+	}}
+}
+
+// detectMotion runs the first pass of Stabilize, which writes the camera
+// motion to a new temporary transforms file that is removed by
+// removeTransformsFile.
+func (v *Video) detectMotion() error {
+	s := v.stabilizer
+	if s == nil {
+		return nil
+	}
+	f, err := ioutil.TempFile("", "cinema-stabilize-*.trf")
+	if err != nil {
+		return errors.New("unable to create transforms file: " + err.Error())
+	}
+	f.Close()
+	v.transformsFile = f.Name()
+
+	// The transform pass sees the frames from the start of the input, so
+	// the detection pass has to see the same frames.
+	var exprs []string
+	for _, f := range append(v.sanitizeFilters(), v.reverseFilters()...) {
+		exprs = append(exprs, f.expr)
+	}
+	exprs = append(exprs, "vidstabdetect=result="+filterValue(v.transformsFile)+
+		":shakiness="+strconv.Itoa(s.options.Shakiness))
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, v.threadGlobalArgs()...)
+	line = append(line, v.input()...)
+	if v.reversed == nil && !v.following() {
+		line = append(line, "-to", v.formatTime(v.end))
+	}
+	line = append(line, "-vf", strings.Join(exprs, ","), "-an", "-f", "null", "-")
+
+	if err := v.runFFmpeg(line); err != nil {
+		return errors.New("unable to detect the camera motion: " + err.Error())
+	}
+	return nil
+}
+
+// removeTransformsFile removes the transforms file written by detectMotion.
+func (v *Video) removeTransformsFile() {
+	if v.transformsFile != "" {
+		os.Remove(v.transformsFile)
+		v.transformsFile = ""
+	}
+}
@@ -0,0 +1,36 @@
+package cinema
+
+import "time"
+
+// StartTimeOffset returns the timestamp at which the input starts. It is not
+// 0 for many files, e.g. MPEG-TS recordings of a broadcast or files cut from
+// a longer stream without resetting their timestamps.
+//
+// All times of the Video, e.g. of Trim, ShowBetween or ProbeFrames, are
+// relative to the start of the input and do not include this offset. Outputs
+// start at 0, stream copies are shifted with -avoid_negative_ts so that they
+// start at 0 as well.
+func (v *Video) StartTimeOffset() time.Duration {
+	return v.startTime
+}
+
+// copytsArgs returns the input options that make the timestamps of an input
+// read with -copyts start at 0 like in a normal render, so that times of the
+// Video can be used in filters and in the log output.
+func (v *Video) copytsArgs() []string {
+	if v.startTime == 0 {
+		return []string{"-copyts"}
+	}
+	return []string{
+		"-copyts",
+		"-itsoffset", formatFloat(-v.startTime.Seconds()),
+	}
+}
+
+// copyArgs returns the output options that shift the timestamps of copied
+// streams to start at 0. Without them, a stream copy keeps the start time
+// offset of the input and seeking to a trimmed start, which cuts at the
+// previous key frame, results in negative timestamps.
+func copyArgs() []string {
+	return []string{"-avoid_negative_ts", "make_zero"}
+}
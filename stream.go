@@ -0,0 +1,117 @@
+package cinema
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readerProbeSize is the number of bytes LoadReader reads from the reader to
+// describe the input. They are kept in memory until the Video is rendered.
+const readerProbeSize = 8 << 20
+
+// readerSource is the input of a Video loaded with LoadReader.
+type readerSource struct {
+	mu   sync.Mutex
+	head []byte
+	rest io.Reader
+	used bool
+}
+
+// LoadReader is like Load but reads the input from r instead of a file, e.g.
+// from an HTTP upload, without writing it to disk. ffmpeg reads the stream
+// from its standard input, so the input can only be read once: a Video loaded
+// with LoadReader can be rendered only once. Operations that have to read the
+// input separately, like ProbeFrames, do not work with it.
+//
+// The input must be readable sequentially, e.g. MP4 files must have their
+// index at the start ("faststart"). An error is returned if the description
+// of the input, including its duration, is not found in the first megabytes.
+func LoadReader(r io.Reader) (*Video, error) {
+	if err := checkFFprobe("cinema.LoadReader"); err != nil {
+		return nil, err
+	}
+	head, err := ioutil.ReadAll(io.LimitReader(r, readerProbeSize))
+	if err != nil {
+		return nil, errors.New("cinema.LoadReader: unable to read input: " +
+			err.Error())
+	}
+
+	cmd := exec.Command("ffprobe", probeArgs("pipe:0")...)
+	cmd.Stdin = bytes.NewReader(head)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.New("cinema.LoadReader: ffprobe failed: " +
+			err.Error())
+	}
+	v, err := describe("cinema.LoadReader", "pipe:0", out,
+		func() (time.Duration, error) {
+			return 0, errors.New("the input does not store its duration at " +
+				"the start")
+		})
+	if err != nil {
+		return nil, err
+	}
+	v.source = &readerSource{head: head, rest: r}
+	return v, nil
+}
+
+// RenderTo is like Render but writes the output to w instead of a file, e.g.
+// to an upload to object storage. format is the ffmpeg output format, e.g.
+// "mp4", "matroska" or "webm", which is needed because there is no file
+// extension to choose it from. MP4 and MOV are written as fragmented files,
+// which can be written sequentially.
+func (v *Video) RenderTo(w io.Writer, format string) error {
+	if err := v.checkTrim("cinema.Video.RenderTo"); err != nil {
+		return err
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderTo: " + err.Error())
+	}
+	defer cleanup()
+	args := []string{"-f", format}
+	switch strings.ToLower(format) {
+	case "mp4", "mov", "ipod", "ismv":
+		args = append(args, "-movflags", "frag_keyframe+empty_moov")
+	}
+	cmd := v.command(v.commandLine("pipe:1", args...))
+	cmd.Stdout = w
+	if err := startProcess(cmd, v.memoryLimit); err != nil {
+		return ffmpegFailed("cinema.Video.RenderTo", err)
+	}
+	if err := waitProcess(cmd); err != nil {
+		return ffmpegFailed("cinema.Video.RenderTo", err)
+	}
+	return nil
+}
+
+// stdin returns the standard input of ffmpeg for a Video loaded with
+// LoadReader. Reading it fails if the input was already read by an earlier
+// render.
+func (s *readerSource) stdin() io.Reader {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.used {
+		return errorReader{errors.New("the input of the video was already " +
+			"read by an earlier render")}
+	}
+	s.used = true
+	r := io.MultiReader(bytes.NewReader(s.head), s.rest)
+	s.head, s.rest = nil, nil
+	return r
+}
+
+// errorReader is an io.Reader that always fails with err.
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
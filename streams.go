@@ -0,0 +1,135 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// streamSelection are the streams of the input that are written to the
+// output. The zero value leaves the choice to ffmpeg, which takes one video
+// and one audio stream.
+type streamSelection struct {
+	// video is the index of the video stream among the video streams of the
+	// input, only used if videoSet is set.
+	video    int
+	videoSet bool
+	// audio are the indexes of the audio streams among the audio streams of
+	// the input, nil for the default.
+	audio []int
+	// maps are the additional stream specifiers set with Map.
+	maps []string
+}
+
+// selected reports whether the selection differs from ffmpeg's default.
+func (s streamSelection) selected() bool {
+	return s.videoSet || s.audio != nil || len(s.maps) > 0
+}
+
+// SelectVideoStream selects the video stream of the input that is rendered,
+// e.g. the second angle of a multi-angle recording. index counts the video
+// streams of the input only, starting at 0, so 1 is the second video stream
+// no matter how many audio streams come before it. By default ffmpeg takes
+// the first one. An error is returned if the input has no such stream.
+func (v *Video) SelectVideoStream(index int) error {
+	if index < 0 || index >= v.videoStreams {
+		return errors.New("cinema.Video.SelectVideoStream: the input has no " +
+			"video stream " + strconv.Itoa(index) + ", it has " +
+			strconv.Itoa(v.videoStreams))
+	}
+	v.record("SelectVideoStream", index)
+	v.streams.video, v.streams.videoSet = index, true
+	return nil
+}
+
+// SelectAudioStreams selects the audio streams of the input that are written
+// to the output, in order, e.g. the tracks of some of the languages of a
+// movie. indexes count the audio streams of the input only, starting at 0.
+// The audio operations apply to each of them. By default ffmpeg takes a
+// single audio stream; calling SelectAudioStreams without indexes restores
+// that. An error is returned if the input has no such stream.
+func (v *Video) SelectAudioStreams(indexes ...int) error {
+	for _, i := range indexes {
+		if i < 0 || i >= v.audioStreams {
+			return errors.New("cinema.Video.SelectAudioStreams: the input " +
+				"has no audio stream " + strconv.Itoa(i) + ", it has " +
+				strconv.Itoa(v.audioStreams))
+		}
+	}
+	v.record("SelectAudioStreams", indexes)
+	v.streams.audio = nil
+	if len(indexes) > 0 {
+		v.streams.audio = append([]int(nil), indexes...)
+	}
+	return nil
+}
+
+// Map adds streams to the output with ffmpeg's -map option, in addition to
+// the selected video and audio streams, e.g. "0:s" for all subtitle streams
+// of the input, "0:s:1" for the second one or "0:t?" for the attachments, like
+// the fonts of Matroska files, if there are any. The input video is input 0.
+// A specifier starting with "-" removes streams that would be mapped
+// otherwise, e.g. "-0:a:2". See the ffmpeg documentation of -map for the
+// syntax. Subtitle streams are converted to mov_text for MP4 and MOV outputs
+// and to WebVTT for WebM outputs. An error is returned if a specifier does
+// not refer to input 0.
+func (v *Video) Map(specs ...string) error {
+	for _, s := range specs {
+		spec := strings.TrimPrefix(s, "-")
+		if spec != "0" && !strings.HasPrefix(spec, "0:") {
+			return errors.New("cinema.Video.Map: invalid stream specifier " +
+				strconv.Quote(s) + ", it must refer to input 0")
+		}
+	}
+	v.record("Map", specs)
+	v.streams.maps = append(append([]string(nil), v.streams.maps...), specs...)
+	return nil
+}
+
+// videoStream returns the stream specifier of the selected video stream, or
+// fallback if none was selected.
+func (v *Video) videoStream(fallback string) string {
+	if !v.streams.videoSet {
+		return fallback
+	}
+	return "0:v:" + strconv.Itoa(v.streams.video)
+}
+
+// audioMapArgs returns the -map options for the selected audio streams, or
+// for fallback if none were selected.
+func (v *Video) audioMapArgs(fallback string) []string {
+	if v.streams.audio == nil {
+		return []string{"-map", fallback}
+	}
+	var args []string
+	for _, i := range v.streams.audio {
+		args = append(args, "-map", "0:a:"+strconv.Itoa(i))
+	}
+	return args
+}
+
+// streamArgs returns the -map options for the stream selection when rendering
+// to output. filterArgs are the output options of the video filters; if they
+// do not map the streams themselves, the video and audio are mapped here
+// since any -map turns off ffmpeg's automatic stream selection.
+func (v *Video) streamArgs(filterArgs []string, output string) []string {
+	s := v.streams
+	if !s.selected() {
+		return nil
+	}
+	var args []string
+	if !contains(filterArgs, "-map") {
+		// Like ffmpeg's automatic selection, one stream of each kind if
+		// there is one.
+		args = append(args, "-map", v.videoStream("0:v:0?"))
+		args = append(args, v.audioMapArgs("0:a:0?")...)
+	}
+	for _, m := range s.maps {
+		args = append(args, "-map", m)
+	}
+	// Subtitle files set the codec for all subtitle streams themselves.
+	if len(s.maps) > 0 && len(v.subtitles) == 0 {
+		args = append(args, subtitleCodecArgs(output)...)
+	}
+	return args
+}
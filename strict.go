@@ -0,0 +1,82 @@
+package cinema
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Strictness is how strictly ffmpeg follows the specifications of the codecs
+// and formats, see SetStrictness.
+type Strictness string
+
+const (
+	// StrictVery follows the specifications more strictly than required,
+	// e.g. for conformance testing.
+	StrictVery Strictness = "very"
+	// StrictStrict follows the specifications, rejecting extensions and
+	// workarounds.
+	StrictStrict Strictness = "strict"
+	// StrictNormal is ffmpeg's default.
+	StrictNormal Strictness = "normal"
+	// StrictUnofficial allows unofficial extensions, e.g. codecs in
+	// containers that do not officially support them.
+	StrictUnofficial Strictness = "unofficial"
+	// StrictExperimental additionally allows experimental encoders and
+	// features, which may produce files that other programs cannot read.
+	StrictExperimental Strictness = "experimental"
+)
+
+// SetStrictness sets how strictly ffmpeg follows the specifications when
+// rendering. By default ffmpeg's own default is used, which is right for
+// almost all uses; StrictExperimental is only needed for experimental
+// encoders or unusual codec and container combinations. An error is returned
+// if strictness is unknown.
+func (v *Video) SetStrictness(strictness Strictness) error {
+	switch strictness {
+	case StrictVery, StrictStrict, StrictNormal, StrictUnofficial,
+		StrictExperimental:
+	default:
+		return errors.New("cinema.Video.SetStrictness: unknown strictness " +
+			string(strictness))
+	}
+	v.record("SetStrictness", strictness)
+	v.strictness = strictness
+	return nil
+}
+
+// strictArgs returns the output options that set the strictness.
+func (v *Video) strictArgs() []string {
+	if v.strictness == "" {
+		return nil
+	}
+	return []string{"-strict", string(v.strictness)}
+}
+
+// aacContainers are the file extensions of containers whose usual audio
+// codec is AAC.
+var aacContainers = map[string]bool{
+	".mp4": true,
+	".m4v": true,
+	".m4a": true,
+	".mov": true,
+	".3gp": true,
+}
+
+// audioEncoderArgs selects ffmpeg's native AAC encoder for outputs in MP4-like
+// containers, unless an audio encoder is already set in outputArgs. ffmpeg
+// builds with other AAC encoders could otherwise pick one that is not
+// available or needs special options.
+func (v *Video) audioEncoderArgs(output string, outputArgs []string) []string {
+	if v.audioChannels == 0 ||
+		!aacContainers[strings.ToLower(filepath.Ext(output))] {
+		return nil
+	}
+	for _, arg := range outputArgs {
+		switch arg {
+		case "-c:a", "-codec:a", "-acodec", "-an", "-c", "-codec":
+			return nil
+		}
+	}
+	return []string{"-c:a", "aac"}
+}
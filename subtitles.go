@@ -0,0 +1,231 @@
+package cinema
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SubtitleOptions configures ConvertSubtitles. Zero values select the
+// defaults.
+type SubtitleOptions struct {
+	// Charset is the character encoding of a text subtitle input, e.g.
+	// "CP1252" or "ISO-8859-2". If empty, UTF-8 and UTF-16 are detected and
+	// other inputs are read as FallbackCharset.
+	Charset string
+	// FallbackCharset is the encoding of inputs that are neither UTF-8 nor
+	// UTF-16. It defaults to "CP1252", the most common encoding of old SRT
+	// files.
+	FallbackCharset string
+	// Offset delays all subtitles by this duration, a negative offset shows
+	// them earlier.
+	Offset time.Duration
+	// Scale multiplies all times by this factor, after applying Offset. 0
+	// does not change them.
+	Scale float64
+}
+
+// subtitleFormats maps file extensions to the ffmpeg muxer and subtitle
+// encoder for them.
+var subtitleFormats = map[string][2]string{
+	".srt": {"srt", "srt"},
+	".ass": {"ass", "ass"},
+	".ssa": {"ass", "ass"},
+	".vtt": {"webvtt", "webvtt"},
+	".mp4": {"mp4", "mov_text"},
+	".m4v": {"mp4", "mov_text"},
+	".mov": {"mov", "mov_text"},
+}
+
+// ConvertSubtitles converts the subtitles in the file in to the format of out,
+// which is selected by its extension: SubRip (.srt), ASS (.ass, .ssa),
+// WebVTT (.vtt) or MP4 timed text (.mp4, .m4v, .mov). The input may also be a
+// video file, its first subtitle stream is converted. Styling that the output
+// format does not support is lost, e.g. converting ASS to SRT keeps only the
+// text.
+//
+// The output is always UTF-8. Use opts to set the encoding of the input; a nil
+// opts detects it.
+func ConvertSubtitles(in, out string, opts *SubtitleOptions) error {
+	var o SubtitleOptions
+	if opts != nil {
+		o = *opts
+	}
+	format, ok := subtitleFormats[strings.ToLower(filepath.Ext(out))]
+	if !ok {
+		return errors.New("cinema.ConvertSubtitles: unsupported output " +
+			"format: " + out)
+	}
+	charset := o.Charset
+	if charset == "" {
+		var err error
+		charset, err = detectCharset(in, orDefault(o.FallbackCharset, "CP1252"))
+		if err != nil {
+			return errors.New("cinema.ConvertSubtitles: unable to read " +
+				"input: " + err.Error())
+		}
+	}
+
+	line := []string{"ffmpeg", "-y"}
+	if charset != "" {
+		line = append(line, "-sub_charenc", charset)
+	}
+	line = append(line, subtitleTiming(o.Offset, o.Scale)...)
+	line = append(line,
+		"-i", in,
+		"-map", "0:s:0",
+		"-c:s", format[1],
+		"-f", format[0],
+		out,
+	)
+	if err := run(line); err != nil {
+		return errors.New("cinema.ConvertSubtitles: ffmpeg failed: " +
+			err.Error())
+	}
+	return nil
+}
+
+// detectCharset returns the -sub_charenc value for the subtitle file at path:
+// the empty string for containers and for files ffmpeg decodes on its own,
+// i.e. UTF-8 and UTF-16 with a byte order mark, and fallback for everything
+// else.
+func detectCharset(path, fallback string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt", ".ass", ".ssa", ".vtt", ".sub", ".txt":
+	default:
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.HasPrefix(data, []byte{0xff, 0xfe}) ||
+		bytes.HasPrefix(data, []byte{0xfe, 0xff}) ||
+		utf8.Valid(data) {
+		return "", nil
+	}
+	return fallback, nil
+}
+
+// subtitleTrack is a subtitle file muxed into the output, see AddSubtitles.
+type subtitleTrack struct {
+	path     string
+	language string
+}
+
+// AddSubtitles adds the subtitles in the file at path to the output as a
+// separate track that players can turn on and off. language is the ISO 639-2
+// code of the language of the subtitles, e.g. "eng", or empty if unknown.
+//
+// The subtitles are cut together with the video, so they stay in sync after
+// trimming. Speed changes are not applied to them; use ScaleSubtitleTiming to
+// compensate for a constant speed change. The subtitle format is converted to
+// the one the output container supports: MP4 timed text for MP4 and MOV
+// outputs, WebVTT for WebM outputs.
+func (v *Video) AddSubtitles(path, language string) {
+	v.record("AddSubtitles", path, language)
+	v.subtitles = append(v.subtitles, subtitleTrack{path, language})
+}
+
+// ShiftSubtitles delays all subtitles added with AddSubtitles by offset
+// relative to the input video. A negative offset shows them earlier.
+func (v *Video) ShiftSubtitles(offset time.Duration) {
+	v.record("ShiftSubtitles", offset)
+	v.subtitleOffset = offset
+}
+
+// ScaleSubtitleTiming multiplies all times of the subtitles added with
+// AddSubtitles by factor, after the shift set with ShiftSubtitles. E.g. after
+// doubling the speed of the video, a factor of 0.5 keeps the subtitles in
+// sync. It also fixes subtitles made for a video with a different frame rate,
+// e.g. 25/23.976 for subtitles timed for a PAL release.
+func (v *Video) ScaleSubtitleTiming(factor float64) error {
+	if factor <= 0 {
+		return errors.New("cinema.Video.ScaleSubtitleTiming: factor must be " +
+			"greater than 0: " + formatFloat(factor))
+	}
+	v.record("ScaleSubtitleTiming", factor)
+	v.subtitleScale = factor
+	return nil
+}
+
+// subtitleInputArgs returns the input arguments for the subtitle files.
+func (v *Video) subtitleInputArgs() []string {
+	var args []string
+	for _, s := range v.subtitles {
+		args = append(args, subtitleTiming(v.subtitleOffset, v.subtitleScale)...)
+		args = append(args, "-i", s.path)
+	}
+	return args
+}
+
+// subtitleOutputArgs returns the output arguments that map the subtitle files
+// to the output. first is the index of the first subtitle input. filterArgs
+// are the output options of the video filters; if they do not map the
+// streams, the video and audio are mapped as well since mapping the subtitles
+// turns off ffmpeg's automatic stream selection.
+func (v *Video) subtitleOutputArgs(first int, filterArgs []string, output string) []string {
+	if len(v.subtitles) == 0 {
+		return nil
+	}
+	var args []string
+	mapped := false
+	for _, arg := range filterArgs {
+		if arg == "-map" {
+			mapped = true
+		}
+	}
+	if !mapped {
+		args = append(args, "-map", v.videoStream("0:v:0"))
+		args = append(args, v.audioMapArgs("0:a?")...)
+	}
+	for i, s := range v.subtitles {
+		args = append(args, "-map", strconv.Itoa(first+i)+":s:0")
+		if s.language != "" {
+			args = append(args,
+				"-metadata:s:s:"+strconv.Itoa(i), "language="+s.language)
+		}
+	}
+	return append(args, subtitleCodecArgs(output)...)
+}
+
+// subtitleCodecArgs returns the subtitle codec for output if its format does
+// not support the subtitle formats of the inputs.
+func subtitleCodecArgs(output string) []string {
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".mp4", ".m4v", ".mov":
+		return []string{"-c:s", "mov_text"}
+	case ".webm":
+		return []string{"-c:s", "webvtt"}
+	}
+	return nil
+}
+
+// subtitleTiming returns the input options that shift subtitle timestamps by
+// offset and scale them by factor. A factor of 0 does not scale them.
+func subtitleTiming(offset time.Duration, factor float64) []string {
+	var args []string
+	if offset != 0 {
+		args = append(args, "-itsoffset", formatFloat(offset.Seconds()))
+	}
+	if factor != 0 && factor != 1 {
+		args = append(args, "-itsscale", formatFloat(factor))
+	}
+	return args
+}
+
+// countInputs returns the number of inputs in the ffmpeg arguments args.
+func countInputs(args []string) int {
+	n := 0
+	for _, arg := range args {
+		if arg == "-i" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,192 @@
+package cinema
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"time"
+)
+
+// syncSampleRate is the sample rate SyncByAudio analyzes the audio at, and
+// syncHop the number of samples per analysis frame, 5 ms.
+const (
+	syncSampleRate = 8000
+	syncHop        = 40
+)
+
+// SyncByAudio finds the offsets between recordings of the same event by
+// different cameras from their audio, e.g. to align the angles of a
+// multi-camera shoot before stacking or switching between them. The audio of
+// the trimmed clips is compared, with their audio operations applied; the
+// cameras can be far apart as long as they hear the same sounds.
+//
+// The result has one offset per clip: the time of the start of the clip on
+// the timeline of the first clip, which is 0 for the first one and negative
+// for clips that start before it. A sound at time t of clip i is at time
+// t+offsets[i] of the first clip. The offsets are accurate to about 5 ms. An
+// error is returned if there are fewer than two clips, a clip has no audio or
+// is silent, or ffmpeg fails.
+func SyncByAudio(clips []*Video) ([]time.Duration, error) {
+	if len(clips) < 2 {
+		return nil, errors.New("cinema.SyncByAudio: at least two clips are " +
+			"needed")
+	}
+	envelopes := make([][]float64, len(clips))
+	for i, c := range clips {
+		n := strconv.Itoa(i + 1)
+		if c.audioChannels == 0 {
+			return nil, errors.New("cinema.SyncByAudio: clip " + n +
+				" has no audio")
+		}
+		if err := c.checkTrim("cinema.SyncByAudio"); err != nil {
+			return nil, err
+		}
+		env, err := c.onsetEnvelope()
+		if err != nil {
+			return nil, errors.New("cinema.SyncByAudio: unable to analyze " +
+				"clip " + n + ": " + err.Error())
+		}
+		if env == nil {
+			return nil, errors.New("cinema.SyncByAudio: clip " + n +
+				" is silent")
+		}
+		envelopes[i] = env
+	}
+
+	offsets := []time.Duration{0}
+	for _, env := range envelopes[1:] {
+		lag := bestLag(envelopes[0], env)
+		offsets = append(offsets,
+			time.Duration(lag*syncHop/syncSampleRate*float64(time.Second)))
+	}
+	return offsets, nil
+}
+
+// onsetEnvelope returns the rise of the loudness of the output from one
+// analysis frame to the next, normalized to a mean of 0 and a standard
+// deviation of 1. The rises are the same for cameras with different
+// microphones and gains, unlike the loudness itself. nil is returned for
+// silent audio.
+func (v *Video) onsetEnvelope() ([]float64, error) {
+	var (
+		env       []float64
+		energy    float64
+		prevLevel = -1.0
+		n         int
+	)
+	args := append(v.audioArgs(), "-ar", strconv.Itoa(syncSampleRate))
+	err := v.decodeAudio(args, func(s float32) {
+		energy += float64(s) * float64(s)
+		n++
+		if n < syncHop {
+			return
+		}
+		level := math.Log1p(1000 * energy / syncHop)
+		if prevLevel >= 0 {
+			env = append(env, math.Max(0, level-prevLevel))
+		}
+		prevLevel = level
+		energy, n = 0, 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(env) == 0 {
+		return nil, nil
+	}
+	var mean, variance float64
+	for _, e := range env {
+		mean += e
+	}
+	mean /= float64(len(env))
+	for _, e := range env {
+		variance += (e - mean) * (e - mean)
+	}
+	if variance == 0 {
+		return nil, nil
+	}
+	deviation := math.Sqrt(variance / float64(len(env)))
+	for i := range env {
+		env[i] = (env[i] - mean) / deviation
+	}
+	return env, nil
+}
+
+// bestLag returns the shift of b against a in frames, with a fraction, at
+// which they match best: frame i of b matches frame i+lag of a. The cross
+// correlation is computed with FFTs, so long recordings are fast.
+func bestLag(a, b []float64) float64 {
+	size := 1
+	for size < len(a)+len(b) {
+		size *= 2
+	}
+	fa := make([]complex128, size)
+	fb := make([]complex128, size)
+	for i, x := range a {
+		fa[i] = complex(x, 0)
+	}
+	for i, x := range b {
+		fb[i] = complex(x, 0)
+	}
+	fft(fa, false)
+	fft(fb, false)
+	for i := range fa {
+		fa[i] *= cmplx.Conj(fb[i])
+	}
+	fft(fa, true)
+
+	// Index k holds lag k, the indexes from the end the negative lags.
+	corr := func(lag int) float64 {
+		return real(fa[(lag%size+size)%size])
+	}
+	best := -len(b) + 1
+	for lag := best; lag < len(a); lag++ {
+		if corr(lag) > corr(best) {
+			best = lag
+		}
+	}
+	// Refine the peak with a parabola through its neighbors.
+	l, c, r := corr(best-1), corr(best), corr(best+1)
+	shift := 0.0
+	if d := l - 2*c + r; d < 0 {
+		shift = math.Max(-0.5, math.Min(0.5, (l-r)/(2*d)))
+	}
+	return float64(best) + shift
+}
+
+// fft transforms x in place with the radix-2 fast Fourier transform, or its
+// inverse if invert is set. The length of x must be a power of two.
+func fft(x []complex128, invert bool) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for length := 2; length <= n; length <<= 1 {
+		angle := 2 * math.Pi / float64(length)
+		if invert {
+			angle = -angle
+		}
+		step := cmplx.Rect(1, -angle)
+		for i := 0; i < n; i += length {
+			w := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				u, t := x[i+k], x[i+k+length/2]*w
+				x[i+k], x[i+k+length/2] = u+t, u-t
+				w *= step
+			}
+		}
+	}
+	if invert {
+		for i := range x {
+			x[i] /= complex(float64(n), 0)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimedText is a text that is shown from a time of the input video on, e.g.
+// a reading of a sensor, see DrawTimedText.
+type TimedText struct {
+	At   time.Duration
+	Text string
+}
+
+// DrawTimedText draws text that changes over time onto the video, like
+// DrawText, e.g. the speed and heart rate recorded alongside an action camera
+// video or live captions. Each value is shown from its time in the input video
+// until the time of the next one, the last one until the end. Before the first
+// value, the first one is shown. The values must be in order.
+//
+// A single drawtext filter is used whose text is changed by sendcmd, so even
+// thousands of values render quickly. The commands are written to a temporary
+// file while ffmpeg runs. An error is returned if there are no values, if they
+// are not in order, if an option is out of range or if the name of the file
+// cannot be reserved.
+func (v *Video) DrawTimedText(values []TimedText, x, y int, opts DrawTextOptions) error {
+	if len(values) == 0 {
+		return errors.New("cinema.Video.DrawTimedText: no values")
+	}
+	for i, value := range values {
+		if value.At < 0 || (i > 0 && value.At < values[i-1].At) {
+			return errors.New("cinema.Video.DrawTimedText: values must be " +
+				"in order and not negative")
+		}
+	}
+	f, err := v.textFilter(values[0].Text, x, y, opts)
+	if err != nil {
+		return errors.New("cinema.Video.DrawTimedText: " + err.Error())
+	}
+
+	// The drawtext filter needs a name that is unique in the chain to be
+	// addressed by sendcmd.
+	target := "drawtext@timedtext" + strconv.Itoa(len(v.filters))
+	var b strings.Builder
+	for _, value := range values {
+		b.WriteString(formatFloat(value.At.Seconds()) + " " + target + " reinit " +
+			escapeCommand("text="+escapeOption(value.Text)) + ";\n")
+	}
+	script, err := newScriptFile("cinema-timedtext-*.txt", b.String())
+	if err != nil {
+		return errors.New("cinema.Video.DrawTimedText: unable to create " +
+			"command file: " + err.Error())
+	}
+
+	v.record("DrawTimedText", values, x, y, opts)
+	f.expr = "sendcmd=f=" + filterValue(script.path) + "," +
+		strings.Replace(f.expr, "drawtext=", target+"=", 1)
+	f.script = script
+	v.filters = append(v.filters, f)
+	return nil
+}
+
+// escapeCommand escapes s so that sendcmd reads it as a single argument.
+func escapeCommand(s string) string {
+	return commandEscaper.Replace(s)
+}
+
+var commandEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	" ", `\ `,
+	"\t", "\\\t",
+	"\n", "\\\n",
+	",", `\,`,
+	";", `\;`,
+	"[", `\[`,
+	"]", `\]`,
+)
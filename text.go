@@ -0,0 +1,147 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// escapeOption escapes s so it can be used as the value of a filter option,
+// e.g. the text of a drawtext filter. The result still has to be escaped with
+// escapeGraph before it is put into a filter chain, use filterValue to do
+// both.
+func escapeOption(s string) string {
+	return optionEscaper.Replace(s)
+}
+
+var optionEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`:`, `\:`,
+)
+
+// escapeGraph escapes s so it can be put into a filtergraph description
+// without its characters being interpreted as filter or chain separators.
+func escapeGraph(s string) string {
+	return graphEscaper.Replace(s)
+}
+
+var graphEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`,`, `\,`,
+	`;`, `\;`,
+)
+
+// filterValue escapes s for use as a filter option value inside a filter
+// chain, see https://ffmpeg.org/ffmpeg-filters.html#Notes-on-filtergraph-escaping
+func filterValue(s string) string {
+	return escapeGraph(escapeOption(s))
+}
+
+// DrawTextOptions configures DrawText. Zero values select the defaults.
+type DrawTextOptions struct {
+	// FontFile is the path to the font file to use. If it is empty, ffmpeg
+	// uses its default font, which requires ffmpeg to be built with
+	// fontconfig.
+	FontFile string
+	// FontSize is the text height in pixels. It defaults to a twentieth of
+	// the output height.
+	FontSize int
+	// FontColor is the text color, e.g. "white" or "#FF0000@0.5" for half
+	// transparent red. It defaults to white.
+	FontColor string
+	// BoxColor is the color of a box drawn behind the text to keep it
+	// readable, e.g. "black@0.5". Empty draws no box.
+	BoxColor string
+	// Position is what the x and y of DrawText are relative to. It defaults
+	// to TopLeft.
+	Position Position
+	// Start and End limit the text to this range of the output, like
+	// ShowBetween. An End of 0 shows the text until the end.
+	Start, End time.Duration
+}
+
+// DrawText draws text onto the video, x and y pixels away from the corner or
+// center selected by opts.Position. The text is shown as is: all characters
+// that have a special meaning in ffmpeg filters, like colons, quotes and
+// percent signs, are escaped. An error is returned if an option is out of
+// range.
+func (v *Video) DrawText(text string, x, y int, opts DrawTextOptions) error {
+	f, err := v.textFilter(text, x, y, opts)
+	if err != nil {
+		return errors.New("cinema.Video.DrawText: " + err.Error())
+	}
+	v.record("DrawText", text, x, y, opts)
+	v.filters = append(v.filters, f)
+	return nil
+}
+
+// textFilter returns the drawtext filter for DrawText.
+func (v *Video) textFilter(text string, x, y int, opts DrawTextOptions) (filter, error) {
+	if opts.FontColor == "" {
+		opts.FontColor = "white"
+	}
+	if opts.FontSize == 0 {
+		opts.FontSize = v.OutputHeight() / 20
+		if opts.FontSize < 1 {
+			opts.FontSize = 1
+		}
+	}
+	switch {
+	case opts.Position < TopLeft || opts.Position > Center:
+		return filter{}, errors.New("unknown position " +
+			strconv.Itoa(int(opts.Position)))
+	case opts.FontSize < 0:
+		return filter{}, errors.New("font size must not be negative")
+	case opts.Start < 0 || (opts.End != 0 && opts.End <= opts.Start):
+		return filter{}, errors.New("invalid time range " +
+			opts.Start.String() + " to " + opts.End.String())
+	}
+
+	// expansion=none keeps percent signs, which would start a text
+	// expansion sequence otherwise.
+	expr := "drawtext=text=" + filterValue(text) +
+		":expansion=none" +
+		":fontsize=" + strconv.Itoa(opts.FontSize) +
+		":fontcolor=" + filterValue(opts.FontColor) +
+		":" + textPosition(opts.Position, x, y)
+	if opts.FontFile != "" {
+		expr += ":fontfile=" + filterValue(opts.FontFile)
+	}
+	if opts.BoxColor != "" {
+		expr += ":box=1:boxcolor=" + filterValue(opts.BoxColor) +
+			":boxborderw=" + strconv.Itoa(opts.FontSize/4)
+	}
+	f := filter{
+		stage: StageFX,
+		expr:  expr,
+		text:  true,
+	}
+	if opts.Start != 0 || opts.End != 0 {
+		f.window = &timeWindow{opts.Start, opts.End}
+	}
+	return f, nil
+}
+
+// textPosition returns the x and y options of the drawtext filter that place
+// the text at (x,y) relative to position.
+func textPosition(position Position, x, y int) string {
+	left, top := strconv.Itoa(x), strconv.Itoa(y)
+	right := "w-text_w-" + strconv.Itoa(x)
+	bottom := "h-text_h-" + strconv.Itoa(y)
+	switch position {
+	case TopRight:
+		return "x=" + right + ":y=" + top
+	case BottomLeft:
+		return "x=" + left + ":y=" + bottom
+	case BottomRight:
+		return "x=" + right + ":y=" + bottom
+	case Center:
+		return "x=(w-text_w)/2+" + left + ":y=(h-text_h)/2+" + top
+	}
+	return "x=" + left + ":y=" + top
+}
@@ -0,0 +1,86 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ThreadOptions configures how many threads ffmpeg uses, see SetThreads. A
+// zero value leaves the choice to ffmpeg, which usually starts about one
+// thread per CPU core for each decoder, encoder and filtergraph.
+type ThreadOptions struct {
+	// Decoder is the number of threads used to decode the input.
+	Decoder int
+	// VideoEncoder and AudioEncoder are the number of threads used by the
+	// video and the audio encoder. Not all encoders are multithreaded, e.g.
+	// most audio encoders ignore it.
+	VideoEncoder int
+	AudioEncoder int
+	// Filter is the number of threads used by simple filtergraphs (-vf and
+	// -af).
+	Filter int
+	// FilterComplex is the number of threads used by complex filtergraphs,
+	// which are needed for overlays and previews.
+	FilterComplex int
+}
+
+// SetThreads sets the number of threads ffmpeg uses when rendering. The
+// default threading is a poor fit when many small renders run at the same
+// time: every ffmpeg process starts threads for all CPU cores and they
+// compete with each other. In that case, use 1 or 2 threads per render and
+// run several renders in parallel instead. Some codecs also scale badly with
+// many threads, e.g. libvpx.
+//
+// Call SetThreads with a nil opts to use ffmpeg's defaults again.
+func (v *Video) SetThreads(opts *ThreadOptions) error {
+	if opts == nil {
+		v.record("SetThreads", opts)
+		v.threads = nil
+		return nil
+	}
+	o := *opts
+	for _, n := range []int{o.Decoder, o.VideoEncoder, o.AudioEncoder, o.Filter, o.FilterComplex} {
+		if n < 0 {
+			return errors.New("cinema.Video.SetThreads: thread count must not be negative: " + strconv.Itoa(n))
+		}
+	}
+	v.record("SetThreads", opts)
+	v.threads = &o
+	return nil
+}
+
+// threadGlobalArgs returns the global ffmpeg options for the filter threads.
+func (v *Video) threadGlobalArgs() []string {
+	if v.threads == nil {
+		return nil
+	}
+	var args []string
+	args = appendThreads(args, "-filter_threads", v.threads.Filter)
+	return appendThreads(args, "-filter_complex_threads", v.threads.FilterComplex)
+}
+
+// threadInputArgs returns the input options for the decoder threads.
+func (v *Video) threadInputArgs() []string {
+	if v.threads == nil {
+		return nil
+	}
+	return appendThreads(nil, "-threads", v.threads.Decoder)
+}
+
+// threadOutputArgs returns the output options for the encoder threads.
+func (v *Video) threadOutputArgs() []string {
+	if v.threads == nil {
+		return nil
+	}
+	args := appendThreads(nil, "-threads:v", v.threads.VideoEncoder)
+	return appendThreads(args, "-threads:a", v.threads.AudioEncoder)
+}
+
+// appendThreads appends the option with the thread count n to args unless n
+// is 0.
+func appendThreads(args []string, option string, n int) []string {
+	if n == 0 {
+		return args
+	}
+	return append(args, option, strconv.Itoa(n))
+}
@@ -0,0 +1,200 @@
+package cinema
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// ThumbnailOptions configures Thumbnails and Sprite. Zero values select the
+// defaults.
+type ThumbnailOptions struct {
+	// Interval is the time between two thumbnails. It defaults to 10
+	// seconds.
+	Interval time.Duration
+	// Width is the width of the thumbnails in pixels. It defaults to 160.
+	Width int
+	// Workers is the number of ffmpeg processes that run in parallel. It
+	// defaults to the number of CPUs.
+	Workers int
+	// Columns is the number of thumbnails per row of a sprite. It defaults
+	// to 10.
+	Columns int
+}
+
+func (opts ThumbnailOptions) withDefaults() ThumbnailOptions {
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.Width <= 0 {
+		opts.Width = 160
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.Columns <= 0 {
+		opts.Columns = 10
+	}
+	return opts
+}
+
+// Thumbnails saves a thumbnail every opts.Interval of the trimmed Video as
+// JPEG images in dir and returns their paths in order. The images are named
+// thumb-000001.jpg, thumb-000002.jpg and so on. The crop, scale and effect
+// operations of the Video are applied to the thumbnails.
+//
+// The timeline is split into opts.Workers parts that are processed by
+// separate ffmpeg processes in parallel. Each process seeks to the start of
+// its part, so for long inputs this is a lot faster than decoding the whole
+// video in a single process, e.g. with GenerateAssets.
+func (v *Video) Thumbnails(dir string, opts ThumbnailOptions) ([]string, error) {
+	opts = opts.withDefaults()
+	if v.end <= v.start {
+		return nil, errors.New("cinema.Video.Thumbnails: the trimmed video " +
+			"is empty")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.New("cinema.Video.Thumbnails: unable to create " +
+			"output directory: " + err.Error())
+	}
+	removeScripts, err := v.writeScripts()
+	if err != nil {
+		return nil, errors.New("cinema.Video.Thumbnails: " + err.Error())
+	}
+	defer removeScripts()
+	if err := v.thumbnails(dir, opts); err != nil {
+		return nil, ffmpegFailed("cinema.Video.Thumbnails", err)
+	}
+	var paths []string
+	for i := range v.thumbnailTimes(opts.Interval) {
+		paths = append(paths, filepath.Join(dir, thumbnailName(i+1)))
+	}
+	return paths, nil
+}
+
+// Sprite saves thumbnails like Thumbnails and merges them into a single image,
+// opts.Columns thumbnails per row. Sprites are used by video players to show
+// previews when seeking. The image format is selected by the file extension
+// of output.
+func (v *Video) Sprite(output string, opts ThumbnailOptions) error {
+	opts = opts.withDefaults()
+	if v.end <= v.start {
+		return errors.New("cinema.Video.Sprite: the trimmed video is empty")
+	}
+	dir, err := ioutil.TempDir("", "cinema-sprite")
+	if err != nil {
+		return errors.New("cinema.Video.Sprite: unable to create temporary " +
+			"directory: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+	removeScripts, err := v.writeScripts()
+	if err != nil {
+		return errors.New("cinema.Video.Sprite: " + err.Error())
+	}
+	defer removeScripts()
+	if err := v.thumbnails(dir, opts); err != nil {
+		return ffmpegFailed("cinema.Video.Sprite", err)
+	}
+
+	count := len(v.thumbnailTimes(opts.Interval))
+	columns := opts.Columns
+	if count < columns {
+		columns = count
+	}
+	rows := (count + columns - 1) / columns
+	err = v.runFFmpeg([]string{
+		"ffmpeg",
+		"-y",
+		"-i", filepath.Join(dir, thumbnailPattern),
+		"-vf", "tile=" + strconv.Itoa(columns) + "x" + strconv.Itoa(rows),
+		"-frames:v", "1",
+		output,
+	})
+	if err != nil {
+		return ffmpegFailed("cinema.Video.Sprite", err)
+	}
+	return nil
+}
+
+// thumbnails saves the thumbnails into dir using opts.Workers parallel ffmpeg
+// processes. The trimmed Video must not be empty.
+func (v *Video) thumbnails(dir string, opts ThumbnailOptions) error {
+	times := v.thumbnailTimes(opts.Interval)
+	workers := opts.Workers
+	if workers > len(times) {
+		workers = len(times)
+	}
+
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		// Worker w saves the thumbnails first to last-1.
+		first := w * len(times) / workers
+		last := (w + 1) * len(times) / workers
+		line := v.thumbnailLine(times[first:last], first+1, dir, opts.Width)
+		go func() {
+			errs <- v.runFFmpeg(line)
+		}()
+	}
+	var err error
+	for w := 0; w < workers; w++ {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// thumbnailLine returns the command line that saves the thumbnails at the
+// given times of the input into dir, numbered from number.
+func (v *Video) thumbnailLine(times []time.Duration, number int, dir string, width int) []string {
+	chain := []filter{{
+		stage: StageTrim,
+		expr:  "select=" + filterValue(selectTimes(times)),
+	}}
+	chain = append(chain, v.pipeline()...)
+	chain = append(chain, filter{
+		stage: StageScale,
+		expr:  "scale=" + strconv.Itoa(width) + ":-2",
+	})
+
+	// -copyts keeps the timestamps of the input after seeking, so the times
+	// can be selected and time-based effects stay in place.
+	line := []string{"ffmpeg", "-y"}
+	line = append(line, v.threadGlobalArgs()...)
+	seek := append([]string{"-ss", formatFloat(times[0].Seconds())},
+		v.copytsArgs()...)
+	line = append(line, v.inputWith(seek)...)
+	inputArgs, filterArgs := v.filterArgs(chain)
+	line = append(line, inputArgs...)
+	line = append(line, filterArgs...)
+	return append(line,
+		"-an",
+		"-vsync", "vfr",
+		"-frames:v", strconv.Itoa(len(times)),
+		"-start_number", strconv.Itoa(number),
+		filepath.Join(dir, thumbnailPattern),
+	)
+}
+
+// thumbnailTimes returns the times of the input at which thumbnails are
+// taken.
+func (v *Video) thumbnailTimes(interval time.Duration) []time.Duration {
+	var times []time.Duration
+	for t := v.start; t < v.end; t += interval {
+		times = append(times, t)
+	}
+	return times
+}
+
+// thumbnailPattern is the file name pattern of thumbnails.
+const thumbnailPattern = "thumb-%06d.jpg"
+
+// thumbnailName returns the file name of the n-th thumbnail.
+func thumbnailName(n int) string {
+	return fmt.Sprintf(thumbnailPattern, n)
+}
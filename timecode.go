@@ -0,0 +1,86 @@
+package cinema
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TimecodeOptions configures the timecode drawn by BurnTimecode.
+type TimecodeOptions struct {
+	// OutputRelative makes the timecode start at 00:00:00:00 on the first
+	// frame of the output video. By default the timecode shows the position
+	// in the input video, which makes it easy to find a frame of a review
+	// copy in the original.
+	OutputRelative bool
+	// FrameNumber adds the running frame number after the timecode.
+	FrameNumber bool
+	// FontFile is the path to the font file to use. If it is empty, ffmpeg
+	// uses its default font, which requires ffmpeg to be built with
+	// fontconfig.
+	FontFile string
+	// FontSize is the text height in pixels, it defaults to 24.
+	FontSize int
+	// FontColor is the text color, e.g. "yellow" or "#FFFFFF". It defaults
+	// to white.
+	FontColor string
+}
+
+// BurnTimecode draws a running timecode in the format HH:MM:SS:FF at the bottom
+// center of the output video, on a semi-transparent box. The frame part FF is
+// based on the output framerate, see SetFPS.
+func (v *Video) BurnTimecode(opts TimecodeOptions) {
+	v.record("BurnTimecode", opts)
+	v.timecode = &opts
+}
+
+// timecodeFilter returns the drawtext filter for the timecode or the empty
+// string if no timecode is burned in.
+func (v *Video) timecodeFilter() string {
+	if v.timecode == nil {
+		return ""
+	}
+	opts := *v.timecode
+	if opts.FontSize <= 0 {
+		opts.FontSize = 24
+	}
+	if opts.FontColor == "" {
+		opts.FontColor = "white"
+	}
+
+	// Filters see the timestamps of the input video, so for an output
+	// relative timecode the start of the output has to be subtracted.
+	t := "t"
+	if opts.OutputRelative {
+		t = "(t-" + formatFloat(v.start.Seconds()) + ")"
+	}
+	fps := strconv.Itoa(v.fps)
+	frames := "round(" + t + "*" + fps + ")"
+	text := strings.Join([]string{
+		evalInt("floor("+frames+"/("+fps+"*3600))", 2),
+		evalInt("mod(floor("+frames+"/("+fps+"*60)),60)", 2),
+		evalInt("mod(floor("+frames+"/"+fps+"),60)", 2),
+		evalInt("mod("+frames+","+fps+")", 2),
+	}, ":")
+	if opts.FrameNumber {
+		text += "  #" + evalInt(frames, 0)
+	}
+
+	filter := "drawtext=text=" + filterValue(text) +
+		":fontsize=" + strconv.Itoa(opts.FontSize) +
+		":fontcolor=" + filterValue(opts.FontColor) +
+		":box=1:boxcolor=black@0.5:boxborderw=6" +
+		":x=(w-text_w)/2:y=h-text_h-20"
+	if opts.FontFile != "" {
+		filter += ":fontfile=" + filterValue(opts.FontFile)
+	}
+	return filter
+}
+
+// evalInt returns a drawtext expansion that evaluates expr and prints it as an
+// integer, zero padded to the given number of digits.
+func evalInt(expr string, digits int) string {
+	if digits > 0 {
+		return "%{eif:" + expr + ":d:" + strconv.Itoa(digits) + "}"
+	}
+	return "%{eif:" + expr + ":d}"
+}
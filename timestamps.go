@@ -0,0 +1,43 @@
+package cinema
+
+// SanitizeTimestamps repairs broken timestamps of the input when rendering.
+// Screen recordings and files captured from streams often have missing or
+// non-monotonic timestamps, gaps, or audio and video streams that start at
+// different times, which shows as stutter and growing desync after joining
+// or streaming them.
+//
+// Missing timestamps are generated, both streams are moved to start at 0,
+// and audio is stretched or padded with silence to follow the video
+// timestamps. Note that this drops any intended offset between the start of
+// the audio and the video, see CheckAVSync.
+func (v *Video) SanitizeTimestamps() {
+	v.record("SanitizeTimestamps")
+	v.sanitize = true
+}
+
+// sanitizeInputArgs returns the input options that generate missing
+// timestamps, see SanitizeTimestamps.
+func (v *Video) sanitizeInputArgs() []string {
+	if !v.sanitize {
+		return nil
+	}
+	return []string{"-fflags", "+genpts"}
+}
+
+// sanitizeFilters returns the video filters that come before all others to
+// sanitize the timestamps.
+func (v *Video) sanitizeFilters() []filter {
+	if !v.sanitize {
+		return nil
+	}
+	return []filter{{stage: StageTrim, expr: "setpts=PTS-STARTPTS"}}
+}
+
+// sanitizeAudioFilters returns the audio filters that come before all others
+// to sanitize the timestamps.
+func (v *Video) sanitizeAudioFilters() []string {
+	if !v.sanitize {
+		return nil
+	}
+	return []string{v.aresample(0, "async=1", "first_pts=0")}
+}
@@ -0,0 +1,104 @@
+package cinema
+
+import (
+	"errors"
+	"time"
+)
+
+// timeWindow is a range of the output video, relative to its start. An end of
+// 0 extends the range to the end of the output.
+type timeWindow struct {
+	start, end time.Duration
+}
+
+// ShowBetween limits the last overlay or text operation to the range from
+// start to end of the output video. An end of 0 shows it until the end. It
+// works with Overlay, DrawText, TileWatermark, TextWatermark,
+// AnimatedWatermark and BurnSubtitles.
+//
+// The times are relative to the start of the output, not the input, and they
+// stay correct when the Video is trimmed or its speed is changed afterwards.
+// E.g. ShowBetween(0, 5*time.Second) always shows the overlay during the first
+// five seconds of the output.
+func (v *Video) ShowBetween(start, end time.Duration) error {
+	if start < 0 || (end != 0 && end <= start) {
+		return errors.New("cinema.Video.ShowBetween: invalid time range " +
+			start.String() + " to " + end.String())
+	}
+	i := len(v.filters) - 1
+	if i < 0 || !v.filters[i].timed() {
+		return errors.New("cinema.Video.ShowBetween: the last operation is " +
+			"not an overlay or text")
+	}
+	v.record("ShowBetween", start, end)
+	v.filters[i].window = &timeWindow{start, end}
+	return nil
+}
+
+// timed reports whether the filter can be limited to a time range, see
+// ShowBetween.
+func (f filter) timed() bool {
+	return f.overlay != nil || f.outputRelative || f.text
+}
+
+// BurnSubtitles draws the subtitles in the file at path onto the video. ASS
+// subtitles keep their styling. The subtitle times are relative to the start
+// of the output, so subtitles made for the trimmed video stay in sync however
+// it is trimmed. Speed changes are not applied to the subtitles.
+//
+// Drawing subtitles requires ffmpeg to be built with libass.
+func (v *Video) BurnSubtitles(path string) {
+	v.record("BurnSubtitles", path)
+	v.filters = append(v.filters, subtitlesFilter(path))
+}
+
+// subtitlesFilter returns the filter that draws the subtitles in the file at
+// path.
+func subtitlesFilter(path string) filter {
+	return filter{
+		stage:          StageFX,
+		expr:           "subtitles=" + filterValue(path),
+		outputRelative: true,
+	}
+}
+
+// resolveTiming converts the times of the filters from output time to the
+// timestamps the filters see, which are those of the input video: the range
+// set with ShowBetween becomes an enable expression and output relative
+// filters are wrapped in setpts filters that shift the timestamps.
+func (v *Video) resolveTiming(filters []filter) []filter {
+	var resolved []filter
+	// start is the input time of the first output frame.
+	start := v.start.Seconds()
+	for _, f := range filters {
+		if f.window != nil {
+			from, to := v.windowTime(f.window.start), v.windowTime(f.window.end)
+			if f.outputRelative {
+				from -= start
+				to -= start
+			}
+			enable := "gte(t," + formatFloat(from) + ")"
+			if f.window.end != 0 {
+				enable = "between(t," + formatFloat(from) + "," +
+					formatFloat(to) + ")"
+			}
+			f.expr += ":enable=" + filterValue(enable)
+		}
+		if !f.outputRelative {
+			resolved = append(resolved, f)
+			continue
+		}
+		resolved = append(resolved,
+			filter{stage: f.stage, expr: "setpts=PTS-" + formatFloat(start) + "/TB"},
+			f,
+			filter{stage: f.stage, expr: "setpts=PTS+" + formatFloat(start) + "/TB"},
+		)
+	}
+	return resolved
+}
+
+// windowTime returns the input time in seconds of the output time t, relative
+// to the start of the output.
+func (v *Video) windowTime(t time.Duration) float64 {
+	return v.inputTime(v.outputTime(v.start) + t).Seconds()
+}
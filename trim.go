@@ -0,0 +1,166 @@
+package cinema
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TrimMode selects how the end of the trimmed range is passed to ffmpeg, see
+// SetTrimMode.
+type TrimMode int
+
+const (
+	// TrimDuration passes the length of the range with -t. This is the
+	// default.
+	TrimDuration TrimMode = iota
+	// TrimTo passes the end of the range with -to. ffmpeg then computes the
+	// length itself in its own time base, which avoids rounding errors of
+	// the length when the start and end are not exact in seconds.
+	TrimTo
+)
+
+// SetTrimMode sets how the trimmed range is passed to ffmpeg.
+func (v *Video) SetTrimMode(mode TrimMode) {
+	v.record("SetTrimMode", mode)
+	v.trimMode = mode
+}
+
+// RangeError is returned by renders when the trimmed range of the Video
+// cannot be rendered because it is empty or shorter than a single frame.
+type RangeError struct {
+	// Op is the operation that failed, e.g. "cinema.Video.Render".
+	Op string
+	// Start and End are the trimmed range in the output.
+	Start time.Duration
+	End   time.Duration
+	// Frame is the duration of a single output frame.
+	Frame time.Duration
+}
+
+// Error implements the error interface.
+func (e *RangeError) Error() string {
+	if e.Empty() {
+		return e.Op + ": the range from " + e.Start.String() + " to " +
+			e.End.String() + " is empty"
+	}
+	return e.Op + ": the range from " + e.Start.String() + " to " +
+		e.End.String() + " is shorter than a frame (" + e.Frame.String() + ")"
+}
+
+// Empty reports whether the range is empty, i.e. its end is not after its
+// start. Otherwise it is too short to contain a frame.
+func (e *RangeError) Empty() bool {
+	return e.End <= e.Start
+}
+
+// checkTrim returns a *RangeError for op if the trimmed range of the output
+// would not contain any frames.
+func (v *Video) checkTrim(op string) error {
+	if v.following() {
+		// The end is not known yet.
+		return nil
+	}
+	start, end := v.outputTime(v.start), v.outputTime(v.end)
+	frame := v.frameDuration()
+	if end <= start || end-start < frame {
+		return &RangeError{Op: op, Start: start, End: end, Frame: frame}
+	}
+	return nil
+}
+
+// frameDuration returns the duration of a single output frame, 0 if the frame
+// rate is unknown.
+func (v *Video) frameDuration() time.Duration {
+	rate := float64(v.fps)
+	if v.fpsRate != "" {
+		rate = parseRate(v.fpsRate)
+	}
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// trimArgs returns the output options that cut the range from start to end.
+// The end is omitted if the input is followed until it stops growing.
+func (v *Video) trimArgs(start, end time.Duration) []string {
+	args := []string{"-ss", v.formatTime(start)}
+	if v.following() {
+		return args
+	}
+	if v.trimMode == TrimTo {
+		return append(args, "-to", v.formatTime(end))
+	}
+	// checkTrim rejects empty ranges, but an invalid length must never reach
+	// ffmpeg, which treats a negative -t as an error.
+	length := end - start
+	if length < 0 {
+		length = 0
+	}
+	return append(args, "-t", v.formatTime(length))
+}
+
+// TimeFormat selects how times are written on the ffmpeg command line, see
+// SetTimeFormat.
+type TimeFormat int
+
+const (
+	// SecondsFormat writes times in seconds, e.g. "3723.5". This is the
+	// default.
+	SecondsFormat TimeFormat = iota
+	// ClockFormat writes times as hours, minutes and seconds, e.g.
+	// "01:02:03.5", which is easier to read in logs.
+	ClockFormat
+)
+
+// SetTimeFormat sets how the trimmed range is written on the ffmpeg command
+// line. Times are rounded to a multiple of precision, e.g. time.Millisecond;
+// a precision of 0 keeps microseconds, the most ffmpeg reads. Times are
+// formatted from their integer representation, so there is no loss of
+// precision even for inputs that are many hours long.
+func (v *Video) SetTimeFormat(format TimeFormat, precision time.Duration) {
+	v.record("SetTimeFormat", format, precision)
+	v.timeFormat = format
+	v.timePrecision = precision
+}
+
+// formatTime formats t for the ffmpeg command line according to the format
+// set with SetTimeFormat.
+func (v *Video) formatTime(t time.Duration) string {
+	precision := v.timePrecision
+	if precision < time.Microsecond {
+		precision = time.Microsecond
+	}
+	t = t.Round(precision)
+	sign := ""
+	if t < 0 {
+		sign = "-"
+		t = -t
+	}
+
+	secs := t / time.Second
+	var s string
+	if v.timeFormat == ClockFormat {
+		s = pad2(int64(secs/3600)) + ":" + pad2(int64(secs/60%60)) + ":" +
+			pad2(int64(secs%60))
+	} else {
+		s = strconv.FormatInt(int64(secs), 10)
+	}
+
+	// The fraction is written with nanosecond digits and trailing zeros
+	// removed, it has at most as many digits as precision needs.
+	if frac := int64(t % time.Second); frac != 0 {
+		digits := strconv.FormatInt(frac+int64(time.Second), 10)[1:]
+		s += "." + strings.TrimRight(digits, "0")
+	}
+	return sign + s
+}
+
+// pad2 formats n with at least two digits.
+func pad2(n int64) string {
+	if n < 10 {
+		return "0" + strconv.FormatInt(n, 10)
+	}
+	return strconv.FormatInt(n, 10)
+}
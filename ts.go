@@ -0,0 +1,126 @@
+package cinema
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// TSOptions configures RenderTS. Zero values select the defaults of ffmpeg's
+// MPEG-TS muxer.
+type TSOptions struct {
+	// ServiceName and ServiceProvider are written into the service
+	// description table, where receivers show them as the channel name.
+	ServiceName     string
+	ServiceProvider string
+	// ServiceID is the program number of the single program in the stream.
+	// It defaults to 1.
+	ServiceID int
+	// TransportStreamID identifies the stream in the network. It defaults
+	// to 1.
+	TransportStreamID int
+	// PMTPID is the PID of the program map table. It defaults to 0x1000.
+	PMTPID int
+	// VideoPID and AudioPID are the PIDs of the video and audio streams.
+	// They default to 0x100 and the next free PID.
+	VideoPID int
+	AudioPID int
+	// PCRPeriod is the interval between program clock references. Many
+	// receivers require at most 40ms. It defaults to 20ms for video.
+	PCRPeriod time.Duration
+	// MuxRate is the constant bitrate of the stream in bits per second. The
+	// stream is padded with null packets to reach it, as required for most
+	// broadcast and IPTV systems. It must be higher than the bitrate of all
+	// streams together, so use it with a constant video bitrate. 0 writes a
+	// stream with a variable bitrate.
+	MuxRate int
+}
+
+// RenderTS renders the Video as an MPEG transport stream with the given
+// program structure, for systems that expect specific PIDs and service
+// information. The codecs are the defaults of ffmpeg for transport streams
+// unless set with the output options of the Video. An error is returned if
+// an option is out of range.
+func (v *Video) RenderTS(output string, opts TSOptions) error {
+	if err := opts.validate(); err != nil {
+		return errors.New("cinema.Video.RenderTS: " + err.Error())
+	}
+	if err := v.checkTrim("cinema.Video.RenderTS"); err != nil {
+		return err
+	}
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderTS: " + err.Error())
+	}
+	defer cleanup()
+	err = v.runFFmpeg(v.commandLine(output, v.tsArgs(opts)...))
+	if err != nil {
+		return ffmpegFailed("cinema.Video.RenderTS", err)
+	}
+	return nil
+}
+
+// validate checks that the ids and PIDs are in the range allowed by MPEG-TS.
+func (opts TSOptions) validate() error {
+	switch {
+	case opts.ServiceID < 0 || opts.ServiceID > 0xffff:
+		return errors.New("service id must be between 1 and 65535")
+	case opts.TransportStreamID < 0 || opts.TransportStreamID > 0xffff:
+		return errors.New("transport stream id must be between 1 and 65535")
+	case opts.PCRPeriod < 0:
+		return errors.New("PCR period must not be negative")
+	case opts.MuxRate < 0:
+		return errors.New("mux rate must not be negative")
+	}
+	// PIDs below 0x10 and 0x1fff are reserved.
+	pids := map[int]bool{}
+	for _, pid := range []int{opts.PMTPID, opts.VideoPID, opts.AudioPID} {
+		if pid == 0 {
+			continue
+		}
+		if pid < 0x10 || pid > 0x1ffe {
+			return errors.New("PID " + strconv.Itoa(pid) + " is reserved")
+		}
+		if pids[pid] {
+			return errors.New("PID " + strconv.Itoa(pid) + " is used twice")
+		}
+		pids[pid] = true
+	}
+	return nil
+}
+
+// tsArgs returns the output options for the MPEG-TS muxer.
+func (v *Video) tsArgs(opts TSOptions) []string {
+	args := []string{"-f", "mpegts"}
+	if opts.ServiceName != "" {
+		args = append(args, "-metadata", "service_name="+opts.ServiceName)
+	}
+	if opts.ServiceProvider != "" {
+		args = append(args, "-metadata", "service_provider="+opts.ServiceProvider)
+	}
+	if opts.ServiceID > 0 {
+		args = append(args, "-mpegts_service_id", strconv.Itoa(opts.ServiceID))
+	}
+	if opts.TransportStreamID > 0 {
+		args = append(args, "-mpegts_transport_stream_id",
+			strconv.Itoa(opts.TransportStreamID))
+	}
+	if opts.PMTPID > 0 {
+		args = append(args, "-mpegts_pmt_start_pid", strconv.Itoa(opts.PMTPID))
+	}
+	// The video is the first output stream and the audio the second.
+	if opts.VideoPID > 0 {
+		args = append(args, "-streamid", "0:"+strconv.Itoa(opts.VideoPID))
+	}
+	if opts.AudioPID > 0 && v.audioChannels > 0 {
+		args = append(args, "-streamid", "1:"+strconv.Itoa(opts.AudioPID))
+	}
+	if opts.PCRPeriod > 0 {
+		args = append(args, "-pcr_period",
+			strconv.FormatInt(opts.PCRPeriod.Milliseconds(), 10))
+	}
+	if opts.MuxRate > 0 {
+		args = append(args, "-muxrate", strconv.Itoa(opts.MuxRate))
+	}
+	return args
+}
@@ -0,0 +1,79 @@
+package cinema
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// RenderTwoPass is like Render but encodes the video in two passes, which
+// hits the bitrate set with SetBitrate much more precisely and distributes it
+// better over the video than a single pass: the first pass analyzes the video
+// and the second one encodes it. It takes about twice as long. The video is
+// encoded with H.264 unless set with SetVideoCodec, on the CPU even if
+// SetHardware was used. The statistics of the first pass are written to a
+// temporary directory, which is removed afterwards. An error is returned if
+// no bitrate was set.
+func (v *Video) RenderTwoPass(output string) error {
+	if v.encoder.bitrate <= 0 {
+		return errors.New("cinema.Video.RenderTwoPass: two-pass encoding " +
+			"needs a bitrate, see SetBitrate")
+	}
+	if err := v.checkTrim("cinema.Video.RenderTwoPass"); err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "cinema-2pass-")
+	if err != nil {
+		return errors.New("cinema.Video.RenderTwoPass: unable to create " +
+			"temporary directory: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+	cleanup, err := v.prepareRender()
+	if err != nil {
+		return errors.New("cinema.Video.RenderTwoPass: " + err.Error())
+	}
+	defer cleanup()
+
+	log := filepath.Join(dir, "ffmpeg2pass")
+	for pass := 1; pass <= 2; pass++ {
+		clip := v.passClip(pass, log)
+		args := []string{"-pass", strconv.Itoa(pass), "-passlogfile", log}
+		var line []string
+		if pass == 1 {
+			// The first pass only needs the video and writes nothing.
+			line = clip.commandLine("-", append(args, "-an", "-f", "null")...)
+		} else {
+			line = clip.commandLine(output, args...)
+		}
+		if err := clip.runFFmpeg(line); err != nil {
+			return ffmpegFailed("cinema.Video.RenderTwoPass", err)
+		}
+	}
+	return nil
+}
+
+// passClip returns a copy of the Video that encodes the pass of a two-pass
+// encode with the statistics file log.
+func (v *Video) passClip(pass int, log string) Video {
+	clip := v.snapshot()
+	clip.hardware = NoHardware
+	if clip.encoder.codec == "" {
+		clip.encoder.codec = "libx264"
+	}
+	if clip.encoder.codec == "libx265" {
+		// libx265 ignores -passlogfile and takes the file in its params.
+		params := map[string]string{
+			"pass":  strconv.Itoa(pass),
+			"stats": escapeOption(log + ".log"),
+		}
+		for name, value := range clip.encoder.params {
+			if _, ok := params[name]; !ok {
+				params[name] = value
+			}
+		}
+		clip.encoder.params = params
+	}
+	return clip
+}
@@ -0,0 +1,125 @@
+package cinema
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WarningKind is the kind of problem a Warning reports.
+type WarningKind string
+
+const (
+	// WarningNonMonotonicDTS means that the decoding timestamps of a stream
+	// went backwards, usually because of a broken input. ffmpeg fixes them
+	// up, which can cause stutter or desync; see SanitizeTimestamps.
+	WarningNonMonotonicDTS WarningKind = "non-monotonic DTS"
+	// WarningDroppedFrames means that frames were dropped to keep the output
+	// frame rate, e.g. because of timestamp gaps in the input.
+	WarningDroppedFrames WarningKind = "dropped frames"
+	// WarningClipping means that the audio exceeded full scale and was
+	// clipped, which is audible as distortion.
+	WarningClipping WarningKind = "clipping"
+	// WarningPastDuration means that frames arrived later than their
+	// duration allows, often together with dropped frames.
+	WarningPastDuration WarningKind = "past duration"
+	// WarningDecodeError means that parts of the input could not be decoded
+	// and are missing or damaged in the output.
+	WarningDecodeError WarningKind = "decode error"
+)
+
+// Warning is a problem ffmpeg reported during a render that did not make the
+// render fail, but may have degraded the output. See RenderJob.Warnings.
+type Warning struct {
+	Kind WarningKind
+	// Message is the first line ffmpeg wrote about the problem, or a summary
+	// for dropped frames.
+	Message string
+	// Count is how often ffmpeg reported the problem. For dropped frames it
+	// is the number of frames.
+	Count int
+}
+
+// warningPatterns match the log lines of ffmpeg that report warnings.
+var warningPatterns = []struct {
+	kind    WarningKind
+	pattern *regexp.Regexp
+}{
+	{WarningNonMonotonicDTS, regexp.MustCompile(`(?i)non-monoton(ous|ic) DTS`)},
+	{WarningClipping, regexp.MustCompile(`(?i)clipping`)},
+	{WarningPastDuration, regexp.MustCompile(`Past duration .* too large`)},
+	{WarningDecodeError, regexp.MustCompile(`(?i)error while decoding|` +
+		`corrupt decoded frame|concealing [0-9]+ .*errors`)},
+}
+
+// warningLog collects the warnings from ffmpeg's standard error, which is
+// written to it.
+type warningLog struct {
+	mu       sync.Mutex
+	partial  []byte
+	warnings []Warning
+}
+
+// Write implements io.Writer. It parses all complete lines in p.
+func (l *warningLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.partial = append(l.partial, p...)
+	for {
+		// The progress line is terminated by carriage returns.
+		end := strings.IndexAny(string(l.partial), "\r\n")
+		if end < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(l.partial[:end]))
+		l.partial = l.partial[end+1:]
+		for _, w := range warningPatterns {
+			if w.pattern.MatchString(line) {
+				l.add(w.kind, line, 1)
+				break
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// add adds count occurrences of the warning. l.mu must be held.
+func (l *warningLog) add(kind WarningKind, message string, count int) {
+	for i := range l.warnings {
+		if l.warnings[i].Kind == kind {
+			l.warnings[i].Count += count
+			return
+		}
+	}
+	l.warnings = append(l.warnings, Warning{
+		Kind:    kind,
+		Message: message,
+		Count:   count,
+	})
+}
+
+// droppedFrames records the total number of dropped frames, which ffmpeg
+// reports in its progress rather than in the log.
+func (l *warningLog) droppedFrames(count int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	message := "dropped " + strconv.Itoa(count) + " frames"
+	for i := range l.warnings {
+		if l.warnings[i].Kind == WarningDroppedFrames {
+			l.warnings[i].Message = message
+			l.warnings[i].Count = count
+			return
+		}
+	}
+	if count > 0 {
+		l.add(WarningDroppedFrames, message, count)
+	}
+}
+
+// list returns a copy of the warnings, in the order they first occurred.
+func (l *warningLog) list() []Warning {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Warning(nil), l.warnings...)
+}
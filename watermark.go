@@ -0,0 +1,175 @@
+package cinema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TileOptions configures TileWatermark.
+type TileOptions struct {
+	// Columns and Rows define the grid the watermark is repeated in. They
+	// default to 3 columns and 3 rows.
+	Columns, Rows int
+	// Opacity of the watermark from 0 (invisible) to 1 (opaque). It defaults
+	// to 0.3.
+	Opacity float64
+	// Scale is the size of the watermark relative to its grid cell. It
+	// defaults to 0.5, i.e. the watermark covers half a cell.
+	Scale float64
+}
+
+// TextWatermarkOptions configures TextWatermark.
+type TextWatermarkOptions struct {
+	// Angle is the counter-clockwise rotation of the text in degrees. It
+	// defaults to 30. Use a negative value to rotate clockwise.
+	Angle float64
+	// Horizontal draws the text without rotating it, Angle is ignored. It is
+	// needed since an Angle of 0 selects the default.
+	Horizontal bool
+	// Opacity of the text from 0 (invisible) to 1 (opaque). It defaults to
+	// 0.3.
+	Opacity float64
+	// FontFile is the path to the font file to use. If it is empty, ffmpeg
+	// uses its default font, which requires ffmpeg to be built with
+	// fontconfig.
+	FontFile string
+	// FontSize is the text height in pixels. It defaults to a tenth of the
+	// video height.
+	FontSize int
+	// FontColor is the text color, e.g. "white" or "#FF0000". It defaults
+	// to white.
+	FontColor string
+}
+
+// TileWatermark repeats the image at path in a grid covering the whole video.
+// The image may be animated (GIF or APNG), animations are looped for the whole
+// length of the video.
+func (v *Video) TileWatermark(path string, opts TileOptions) {
+	v.record("TileWatermark", path, opts)
+	if opts.Columns <= 0 {
+		opts.Columns = 3
+	}
+	if opts.Rows <= 0 {
+		opts.Rows = 3
+	}
+	if opts.Opacity <= 0 {
+		opts.Opacity = 0.3
+	}
+	if opts.Scale <= 0 {
+		opts.Scale = 0.5
+	}
+	v.filters = append(v.filters, filter{
+		stage: StageFX,
+		expr:  "overlay=0:0:shortest=1",
+		overlay: &overlaySource{
+			path:         path,
+			inputOptions: loopInput,
+			graph: func(in, out string, width, height int) string {
+				return tileGraph(in, out, width, height, opts)
+			},
+		},
+	})
+}
+
+// TextWatermark draws the text diagonally across the center of the video,
+// semi-transparent so the video stays visible. Set Horizontal in opts to draw
+// it horizontally.
+func (v *Video) TextWatermark(text string, opts TextWatermarkOptions) {
+	v.record("TextWatermark", text, opts)
+	if opts.Horizontal {
+		opts.Angle = 0
+	} else if opts.Angle == 0 {
+		opts.Angle = 30
+	}
+	if opts.Opacity <= 0 {
+		opts.Opacity = 0.3
+	}
+	if opts.FontColor == "" {
+		opts.FontColor = "white"
+	}
+	v.filters = append(v.filters, filter{
+		stage: StageFX,
+		expr:  "overlay=0:0:shortest=1",
+		overlay: &overlaySource{
+			graph: func(_, out string, width, height int) string {
+				fontSize := opts.FontSize
+				if fontSize <= 0 {
+					fontSize = height / 10
+				}
+				text := "drawtext=text=" + filterValue(text) +
+					":fontsize=" + strconv.Itoa(fontSize) +
+					":fontcolor=" + filterValue(opts.FontColor) +
+					"@" + formatFloat(opts.Opacity) +
+					":x=(w-text_w)/2:y=(h-text_h)/2"
+				if opts.FontFile != "" {
+					text += ":fontfile=" + filterValue(opts.FontFile)
+				}
+				return fmt.Sprintf(
+					"color=c=black@0:s=%dx%d,format=rgba,%s,rotate=a=%s:c=none%s",
+					width, height, text,
+					formatFloat(-opts.Angle*math.Pi/180), out,
+				)
+			},
+		},
+	})
+}
+
+// AnimatedWatermark draws the animated image (GIF or APNG) at path with its
+// top-left corner at (x,y) of the video. The animation is looped for the whole
+// length of the video. opacity goes from 0 (invisible) to 1 (opaque).
+func (v *Video) AnimatedWatermark(path string, x, y int, opacity float64) {
+	v.record("AnimatedWatermark", path, x, y, opacity)
+	v.filters = append(v.filters, filter{
+		stage: StageFX,
+		expr:  fmt.Sprintf("overlay=%d:%d:shortest=1", x, y),
+		overlay: &overlaySource{
+			path:         path,
+			inputOptions: loopInput,
+			graph: func(in, out string, _, _ int) string {
+				return in + "format=rgba,colorchannelmixer=aa=" +
+					formatFloat(opacity) + out
+			},
+		},
+	})
+}
+
+// loopInput are the input options that repeat an image or animation forever.
+// The overlay filters use shortest=1 to end with the video.
+var loopInput = []string{"-stream_loop", "-1"}
+
+// tileGraph returns the filtergraph that repeats in in a grid covering a
+// width x height frame.
+func tileGraph(in, out string, width, height int, opts TileOptions) string {
+	cellWidth := width / opts.Columns
+	cellHeight := height / opts.Rows
+	graph := fmt.Sprintf(
+		"%sformat=rgba,colorchannelmixer=aa=%s,"+
+			"scale=%d:%d:force_original_aspect_ratio=decrease,"+
+			"pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=black@0",
+		in, formatFloat(opts.Opacity),
+		int(float64(cellWidth)*opts.Scale), int(float64(cellHeight)*opts.Scale),
+		cellWidth, cellHeight,
+	)
+	graph += stack(out, "c", "hstack", opts.Columns)
+	graph += stack(out, "r", "vstack", opts.Rows)
+	return graph + out
+}
+
+// stack returns a chain continuation that splits the current stream into n
+// copies and puts them next to each other with the given stack filter
+// (hstack or vstack). The labels it creates are made unique with the pad name
+// out and prefix.
+func stack(out, prefix, stackFilter string, n int) string {
+	if n < 2 {
+		return ""
+	}
+	name := strings.Trim(out, "[]")
+	var pads string
+	for i := 0; i < n; i++ {
+		pads += "[" + name + prefix + strconv.Itoa(i) + "]"
+	}
+	return ",split=" + strconv.Itoa(n) + pads + ";" +
+		pads + stackFilter + "=inputs=" + strconv.Itoa(n)
+}
@@ -0,0 +1,105 @@
+package cinema
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+// WaveformPeaks returns the peaks of the audio of the output for drawing its
+// waveform, in the format of web waveform players like wavesurfer.js and
+// peaks.js: for each group of samplesPerPixel samples, the minimum and the
+// maximum, between -1 and 1. The result has two values per pixel, e.g. 2000
+// values for a waveform 1000 pixels wide. All channels are mixed into one.
+//
+// The samples are counted at the sample rate of the input, so for a
+// waveform of a fixed width, samplesPerPixel is the number of samples of the
+// trimmed video divided by the width. An error is returned if
+// samplesPerPixel is not positive, the video has no audio or ffmpeg fails.
+func (v *Video) WaveformPeaks(samplesPerPixel int) ([]float32, error) {
+	if samplesPerPixel <= 0 {
+		return nil, errors.New("cinema.Video.WaveformPeaks: samples per " +
+			"pixel must be positive")
+	}
+	if v.audioChannels == 0 {
+		return nil, errors.New("cinema.Video.WaveformPeaks: the video has " +
+			"no audio")
+	}
+	if err := v.checkTrim("cinema.Video.WaveformPeaks"); err != nil {
+		return nil, err
+	}
+	var (
+		peaks    []float32
+		min, max float32
+		n        int
+	)
+	var args []string
+	if v.sampleRate > 0 {
+		args = []string{"-ar", strconv.Itoa(v.sampleRate)}
+	}
+	err := v.decodeAudio(append(v.audioArgs(), args...), func(s float32) {
+		if n == 0 || s < min {
+			min = s
+		}
+		if n == 0 || s > max {
+			max = s
+		}
+		n++
+		if n == samplesPerPixel {
+			peaks = append(peaks, clampSample(min), clampSample(max))
+			n = 0
+		}
+	})
+	if err != nil {
+		return nil, ffmpegFailed("cinema.Video.WaveformPeaks", err)
+	}
+	if n > 0 {
+		peaks = append(peaks, clampSample(min), clampSample(max))
+	}
+	return peaks, nil
+}
+
+// clampSample limits a float sample to the range from -1 to 1, which decoders
+// may exceed slightly.
+func clampSample(s float32) float32 {
+	if s > 1 {
+		return 1
+	}
+	if s < -1 {
+		return -1
+	}
+	return s
+}
+
+// decodeAudio decodes the audio of the output, mixed into one channel, and
+// calls sample for each sample. args are output options, e.g. the audio
+// filters or the sample rate.
+func (v *Video) decodeAudio(args []string, sample func(s float32)) error {
+	line := []string{"ffmpeg"}
+	line = append(line, v.input()...)
+	line = append(line, v.trimArgs(v.outputTime(v.start), v.outputTime(v.end))...)
+	line = append(line, args...)
+	line = append(line, "-vn", "-ac", "1", "-f", "f32le", "pipe:1")
+
+	cmd := v.command(line)
+	cmd.Stdout = nil
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.New("unable to read samples: " + err.Error())
+	}
+	if err := startProcess(cmd, v.memoryLimit); err != nil {
+		return errors.New("unable to start ffmpeg: " + err.Error())
+	}
+	r := bufio.NewReader(stdout)
+	var buf [4]byte
+	for {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			break
+		}
+		sample(math.Float32frombits(binary.LittleEndian.Uint32(buf[:])))
+	}
+	return waitProcess(cmd)
+}